@@ -0,0 +1,218 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AlertRule fires when more than Threshold entries of Severity or above are
+// logged within Window, giving basic alerting to services without a metrics
+// stack:
+//
+//	flog.AddAlertRule(flog.AlertRule{
+//		Name:      "criticals",
+//		Severity:  flog.CriticalLog,
+//		Threshold: 10,
+//		Window:    5 * time.Minute,
+//		Webhook:   "https://alerts.example.com/hook",
+//	})
+//
+// A rule fires once, then again only after the number of entries within the
+// window went back to Threshold or less.
+type AlertRule struct {
+	// Name identifies the rule in Alert and AlertStates.
+	Name      string
+	Severity  Severity
+	Threshold int
+	Window    time.Duration
+	// Callback, if not nil, is called when the rule fires, in a goroutine of
+	// its own, so it may log.
+	Callback func(Alert)
+	// Webhook, if not empty, is a URL the Alert is posted to as a JSON
+	// object when the rule fires. Failures are logged as Warning entries.
+	Webhook string
+}
+
+// Alert describes a rule firing.
+type Alert struct {
+	Rule string `json:"rule"`
+	// Count is the number of entries within the window of the rule.
+	Count  int           `json:"count"`
+	Window time.Duration `json:"window_ns"`
+	Time   time.Time     `json:"time"`
+	// Message is the message of the entry that made the rule fire.
+	Message string `json:"message"`
+}
+
+// AlertState is the state of a rule, as returned by AlertStates.
+type AlertState struct {
+	Name string `json:"name"`
+	// Count is the number of entries within the window of the rule.
+	Count int `json:"count"`
+	// Firing is true from the time the rule fired to the time the entries
+	// within its window went back to its threshold or less.
+	Firing bool  `json:"firing"`
+	Fired  int64 `json:"fired"`
+}
+
+// alerts holds the rules added with AddAlertRule.
+type alerts struct {
+	// list holds the []*alertRule to check. It is replaced as a whole under
+	// mu.
+	list atomic.Value
+	mu   sync.Mutex
+}
+
+// alertRule is an AlertRule in effect.
+type alertRule struct {
+	AlertRule
+
+	mu     sync.Mutex
+	times  []time.Time // the times of the last Threshold+1 entries, a ring
+	next   int         // the index of the oldest time in times once full
+	firing bool
+	fired  int64
+}
+
+// alertClient posts alerts to webhooks.
+var alertClient = &http.Client{Timeout: 10 * time.Second}
+
+// AddAlertRule adds r to the rules checked against every entry written,
+// including the entries of Loggers. It returns a function removing r.
+// This function is safe to use concurrently.
+func AddAlertRule(r AlertRule) (remove func(), err error) {
+	ensureInit()
+	switch {
+	case r.Name == "":
+		return nil, errors.New("alert rule without a name")
+	case r.Threshold < 0 || r.Window <= 0:
+		return nil, fmt.Errorf("alert rule %s: negative threshold or window", r.Name)
+	case r.Callback == nil && r.Webhook == "":
+		return nil, fmt.Errorf("alert rule %s: no callback or webhook", r.Name)
+	}
+	a := &logging.alerts
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	old, _ := a.list.Load().([]*alertRule)
+	for _, o := range old {
+		if o.Name == r.Name {
+			return nil, fmt.Errorf("alert rule %s already added", r.Name)
+		}
+	}
+	rule := &alertRule{AlertRule: r, times: make([]time.Time, 0, r.Threshold+1)}
+	a.list.Store(append(old[:len(old):len(old)], rule))
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		old, _ := a.list.Load().([]*alertRule)
+		for i, o := range old {
+			if o == rule {
+				list := make([]*alertRule, 0, len(old)-1)
+				list = append(list, old[:i]...)
+				a.list.Store(append(list, old[i+1:]...))
+				return
+			}
+		}
+	}, nil
+}
+
+// AlertStates returns the state of the rules added with AddAlertRule, in the
+// order they were added.
+func AlertStates() []AlertState {
+	list, _ := logging.alerts.list.Load().([]*alertRule)
+	var states []AlertState
+	now := timeNow()
+	for _, r := range list {
+		r.mu.Lock()
+		n := r.count(now)
+		if n <= r.Threshold {
+			r.firing = false
+		}
+		states = append(states, AlertState{r.Name, n, r.firing, r.fired})
+		r.mu.Unlock()
+	}
+	return states
+}
+
+// observe counts e against the rules, firing those it makes go over their
+// threshold.
+func (a *alerts) observe(e *Entry) {
+	list, _ := a.list.Load().([]*alertRule)
+	for _, r := range list {
+		if e.Severity < r.Severity {
+			continue
+		}
+		r.mu.Lock()
+		if len(r.times) < cap(r.times) {
+			r.times = append(r.times, e.Time)
+		} else {
+			r.times[r.next] = e.Time
+			r.next = (r.next + 1) % len(r.times)
+		}
+		n := r.count(e.Time)
+		fire := n > r.Threshold && !r.firing
+		if fire {
+			r.fired++
+		}
+		r.firing = n > r.Threshold
+		r.mu.Unlock()
+		if fire {
+			atomic.AddInt64(&Stats.AlertsFired, 1)
+			go r.fire(Alert{r.Name, n, r.Window, e.Time, e.Message})
+		}
+	}
+}
+
+// count returns the number of entries within the window ending at now, up to
+// Threshold+1.
+// r.mu is held.
+func (r *alertRule) count(now time.Time) int {
+	n := 0
+	for _, t := range r.times {
+		if now.Sub(t) < r.Window {
+			n++
+		}
+	}
+	return n
+}
+
+// fire calls the callback of r and posts to its webhook.
+func (r *alertRule) fire(a Alert) {
+	if r.Callback != nil {
+		r.Callback(a)
+	}
+	if r.Webhook == "" {
+		return
+	}
+	body, _ := json.Marshal(a)
+	resp, err := alertClient.Post(r.Webhook, "application/json", bytes.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			err = errors.New(resp.Status)
+		}
+	}
+	if err != nil {
+		logging.notice("alert webhook failed", Field{"rule", r.Name}, Field{"error", err.Error()})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAlertRule(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.Local)
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	timeNow = func() time.Time { return now }
+	fired := make(chan Alert, 10)
+	remove, err := AddAlertRule(AlertRule{
+		Name:      "errors",
+		Severity:  ErrorLog,
+		Threshold: 2,
+		Window:    time.Minute,
+		Callback:  func(a Alert) { fired <- a },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	if _, err := AddAlertRule(AlertRule{Name: "errors", Window: time.Minute, Callback: func(Alert) {}}); err == nil {
+		t.Error("added a rule twice")
+	}
+	want := func(count int, firing bool, n int64) {
+		t.Helper()
+		states := AlertStates()
+		if len(states) != 1 || states[0] != (AlertState{"errors", count, firing, n}) {
+			t.Errorf("got states %+v, want count %d, firing %v and fired %d", states, count, firing, n)
+		}
+	}
+
+	Info("ignored")
+	Error("first")
+	Critical("second")
+	want(2, false, 0)
+	Error("third")
+	Error("fourth")
+	a := <-fired
+	if a.Rule != "errors" || a.Count != 3 || a.Message != "third" || !a.Time.Equal(now) {
+		t.Errorf("wrong alert: %+v", a)
+	}
+	want(3, true, 1)
+
+	now = now.Add(2 * time.Minute)
+	want(0, false, 1)
+	Error("again")
+	Error("again")
+	Error("again")
+	<-fired
+	want(3, true, 2)
+	select {
+	case a := <-fired:
+		t.Errorf("rule fired twice: %+v", a)
+	default:
+	}
+}
+
+func TestAlertWebhook(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	posted := make(chan Alert, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Error(err)
+		}
+		posted <- a
+	}))
+	defer srv.Close()
+	remove, err := AddAlertRule(AlertRule{Name: "criticals", Severity: CriticalLog, Window: time.Hour, Webhook: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	fired := atomic.LoadInt64(&Stats.AlertsFired)
+	Critical("disk full")
+	if a := <-posted; a.Rule != "criticals" || a.Count != 1 || a.Message != "disk full" {
+		t.Errorf("wrong alert posted: %+v", a)
+	}
+	if GetStats().AlertsFired != fired+1 {
+		t.Error("alert not counted")
+	}
+	remove()
+	if len(AlertStates()) != 0 {
+		t.Error("rule not removed")
+	}
+	if _, err := AddAlertRule(AlertRule{Name: "none", Window: time.Hour}); err == nil {
+		t.Error("added a rule without callback or webhook")
+	}
+}
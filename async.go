@@ -0,0 +1,187 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AsyncPolicy is what logging does when the queue of the asynchronous mode
+// is full.
+type AsyncPolicy int
+
+const (
+	// AsyncBlock makes logging wait for room in the queue.
+	AsyncBlock AsyncPolicy = iota
+	// AsyncDrop drops the entry and counts it in Stats.AsyncDropped.
+	AsyncDrop
+)
+
+// asyncItem is an encoded entry waiting in the queue.
+type asyncItem struct {
+	s    Severity
+	buf  *buffer
+	held int // memory reserved for buf
+}
+
+// asyncQueue holds the entries waiting to be written by the background
+// writer. All of its elements are protected by logging.mu.
+type asyncQueue struct {
+	depth  int // zero if the asynchronous mode is off
+	policy AsyncPolicy
+	items  []asyncItem
+	// busy is set while the writer writes the items it took from the queue.
+	busy bool
+	// gen identifies the writer that is to run, so the writer of a previous
+	// SetAsync call knows to exit.
+	gen int
+	// notEmpty, notFull and idle are signaled when items are queued, when
+	// they are taken and when the writer is done with them.
+	notEmpty, notFull, idle *sync.Cond
+}
+
+// SetAsync turns the asynchronous mode on, with a queue of depth entries, or
+// off if depth is zero. In asynchronous mode, entries are encoded by the
+// logging goroutine but written to the output and the outputs added with
+// AddOutput by a background goroutine, so a slow output, such as a stderr
+// pipe that isn't read fast enough, doesn't stall the callers. When the
+// queue is full, logging waits or drops the entry, according to policy.
+//
+// Critical and Fatal entries are written synchronously, once the queued
+// entries are, so that they are never lost when the process exits. Entries
+// still queued when the process exits otherwise are lost; turning the mode
+// off writes them out. The outputs of Loggers are always written
+// synchronously.
+// This function is safe to use concurrently.
+func SetAsync(depth int, policy AsyncPolicy) {
+	ensureInit()
+	if depth < 0 {
+		depth = 0
+	}
+	l := &logging
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	q := &l.async
+	if q.idle == nil {
+		q.notEmpty, q.notFull, q.idle = sync.NewCond(&l.mu), sync.NewCond(&l.mu), sync.NewCond(&l.mu)
+	}
+	running := q.depth > 0
+	q.depth, q.policy = depth, policy
+	if running && depth > 0 {
+		q.notFull.Broadcast()
+		return
+	}
+	if running {
+		q.notFull.Broadcast()
+		q.drain()
+		q.gen++
+		q.notEmpty.Broadcast()
+		return
+	}
+	if depth > 0 {
+		q.gen++
+		go l.writeAsync(q.gen)
+	}
+}
+
+// enqueue adds it to the queue, waiting for room or dropping it as the
+// policy says.
+// logging.mu is held.
+func (q *asyncQueue) enqueue(it asyncItem) {
+	for len(q.items) >= q.depth {
+		if q.depth == 0 {
+			// Turned off while waiting.
+			q.drain()
+			q.writeSync(it)
+			return
+		}
+		if q.policy == AsyncDrop {
+			atomic.AddInt64(&Stats.AsyncDropped, 1)
+			logging.mem.release(it.held)
+			logging.putBuffer(it.buf)
+			return
+		}
+		q.notFull.Wait()
+	}
+	q.items = append(q.items, it)
+	q.notEmpty.Signal()
+}
+
+// writeSync writes it in the logging goroutine.
+// logging.mu is held.
+func (q *asyncQueue) writeSync(it asyncItem) {
+	l := &logging
+	data := it.buf.Bytes()
+	l.write(nil, it.s, data, 0)
+	countOutput(it.s, len(data))
+	l.mem.release(it.held)
+	l.putBuffer(it.buf)
+}
+
+// active reports whether entries are queued or being written, in which case
+// entries written synchronously must wait for them with drain.
+// logging.mu is held.
+func (q *asyncQueue) active() bool {
+	return q.depth > 0 || q.busy || len(q.items) > 0
+}
+
+// drain waits until the queued entries are written.
+// logging.mu is held.
+func (q *asyncQueue) drain() {
+	for len(q.items) > 0 || q.busy {
+		q.idle.Wait()
+	}
+}
+
+// writeAsync writes the queued entries until the writer of generation gen
+// is no longer the one to run.
+func (l *loggingT) writeAsync(gen int) {
+	q := &l.async
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for {
+		for len(q.items) == 0 && q.gen == gen {
+			q.notEmpty.Wait()
+		}
+		if len(q.items) == 0 {
+			return
+		}
+		items := q.items
+		q.items = nil
+		q.busy = true
+		out, outLat, sevOut, m, outputs := l.out, l.outLatency, l.severityOut, l.loadFieldMap(), l.outputs
+		atomicWrites := atomic.LoadInt32(&l.atomicWrites) != 0
+		threshold := time.Duration(atomic.LoadInt64(&l.slowThreshold))
+		q.notFull.Broadcast()
+		l.mu.Unlock()
+		for _, it := range items {
+			data := it.buf.Bytes()
+			w, lat := sevOut.pick(out, outLat, it.s)
+			slow := writeOutputs(w, lat, m, outputs, it.s, data, atomicWrites, threshold)
+			countOutput(it.s, len(data))
+			l.mem.release(it.held)
+			l.putBuffer(it.buf)
+			// Not from this goroutine, which the warnings may be queued for.
+			for _, so := range slow {
+				go l.warnSlowOutput(so.name, so.p99, threshold)
+			}
+		}
+		l.mu.Lock()
+		q.busy = false
+		q.idle.Broadcast()
+	}
+}
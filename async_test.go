@@ -0,0 +1,95 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// gateWriter blocks writes until open is closed.
+type gateWriter struct {
+	open chan struct{}
+	mu   sync.Mutex
+	sb   strings.Builder
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.open
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sb.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sb.String()
+}
+
+func TestAsyncDrop(t *testing.T) {
+	w := &gateWriter{open: make(chan struct{})}
+	SetOutput(w)
+	defer SetOutput(os.Stderr)
+	SetAsync(2, AsyncDrop)
+	before := atomic.LoadInt64(&Stats.AsyncDropped)
+
+	// The writer takes the first entry and blocks on it, two more fill the
+	// queue and the rest are dropped, without blocking this goroutine.
+	for i := 0; i < 10; i++ {
+		Infof("entry %d", i)
+	}
+	close(w.open)
+	SetAsync(0, AsyncDrop)
+
+	got := strings.Count(w.String(), "] entry ")
+	dropped := atomic.LoadInt64(&Stats.AsyncDropped) - before
+	if got+int(dropped) != 10 || dropped == 0 {
+		t.Errorf("%d entries written and %d dropped, want 10 in total with some dropped", got, dropped)
+	}
+}
+
+func TestAsyncOrder(t *testing.T) {
+	w := &gateWriter{open: make(chan struct{})}
+	close(w.open)
+	SetOutput(w)
+	defer SetOutput(os.Stderr)
+	SetAsync(4, AsyncBlock)
+	for i := 0; i < 100; i++ {
+		Infof("entry %d", i)
+	}
+	SetExitPolicy(ExitPolicy{Critical: ActionLog})
+	defer SetExitPolicy(ExitPolicy{})
+	Critical("last")
+	out := w.String()
+	SetAsync(0, AsyncBlock)
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 101 {
+		t.Fatalf("%d lines written before the Critical entry returned, want 101", len(lines))
+	}
+	for i, line := range lines[:100] {
+		if want := "] entry " + strconv.Itoa(i); !strings.HasSuffix(line, want) {
+			t.Fatalf("line %d = %q, want suffix %q", i, line, want)
+		}
+	}
+	if !strings.HasSuffix(lines[100], "] last") {
+		t.Errorf("last line %q", lines[100])
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+)
+
+// SetAtomicWrites turns atomic writes on or off. When several processes
+// share the same stderr, such as the children of a supervisor, writes of at
+// most PIPE_BUF bytes to a pipe or to a file opened with O_APPEND are not
+// interleaved with the writes of the others, but longer ones may be. With
+// atomic writes on, entries longer than PIPE_BUF are written as several
+// writes of whole lines, each under PIPE_BUF, breaking lines that don't fit
+// in one into several lines, so that the lines of concurrent processes may
+// alternate but never mix. Entries that fit in PIPE_BUF are always written
+// in a single write.
+// This function is safe to use concurrently.
+func SetAtomicWrites(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.atomicWrites, v)
+}
+
+// writeAtomic writes data to w in chunks of whole lines that fit in pipeBuf
+// bytes, breaking longer lines.
+func writeAtomic(w io.Writer, data []byte) {
+	for len(data) > pipeBuf {
+		n := bytes.LastIndexByte(data[:pipeBuf], '\n') + 1
+		if n == 0 {
+			// Break the line, leaving room for the newline ending the part.
+			n = pipeBuf - 1
+			chunk := make([]byte, n+1)
+			copy(chunk, data[:n])
+			chunk[n] = '\n'
+			w.Write(chunk)
+		} else {
+			w.Write(data[:n])
+		}
+		data = data[n:]
+	}
+	if len(data) > 0 {
+		w.Write(data)
+	}
+}
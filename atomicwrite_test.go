@@ -0,0 +1,62 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// writeRecorder records the writes made to it.
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestWriteAtomic(t *testing.T) {
+	short := strings.Repeat("s", pipeBuf/2-1) + "\n"
+	long := strings.Repeat("l", pipeBuf*2) + "\n"
+	var w writeRecorder
+	writeAtomic(&w, []byte(short+short+short+long))
+	for _, write := range w.writes {
+		if len(write) > pipeBuf || !strings.HasSuffix(write, "\n") {
+			t.Errorf("write of %d bytes not atomic or not ending a line", len(write))
+		}
+	}
+	if len(w.writes) != 5 || w.writes[0] != short+short || w.writes[1] != short {
+		t.Errorf("got %d writes, want whole lines grouped in 5", len(w.writes))
+	}
+	if got := strings.Replace(strings.Join(w.writes[2:], ""), "\n", "", -1); got != strings.TrimSuffix(long, "\n") {
+		t.Error("long line not written in full")
+	}
+}
+
+func TestAtomicWrites(t *testing.T) {
+	var w writeRecorder
+	SetOutput(&w)
+	defer logging.revertBuffer()
+	SetAtomicWrites(true)
+	defer SetAtomicWrites(false)
+	Info(strings.Repeat("x", pipeBuf))
+	Info("short")
+	if len(w.writes) != 3 || !bytes.HasSuffix([]byte(w.writes[2]), []byte("] short\n")) {
+		t.Errorf("got %d writes, want the long entry in 2 and the short one in 1", len(w.writes))
+	}
+}
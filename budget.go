@@ -0,0 +1,170 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// budgetKey is the key of the line budget in a context.
+type budgetKey struct{}
+
+// lineBudget is the line budget of a context, see LimitContext.
+type lineBudget struct {
+	// used and dropped count the entries logged and dropped. They are read
+	// and written using sync/atomic.
+	used    int64
+	dropped int64
+	max     int64
+}
+
+// maxExhausted is the maximum number of exhausted budgets whose contexts
+// are watched for the summary entry. Beyond it, budgets get none.
+const maxExhausted = 256
+
+// exhausted holds the budgets that were exhausted, with their context and
+// logger, until their context is done and their summary entry is logged.
+// Rather than by a goroutine per context, which would leak for contexts
+// never cancelled, the contexts are checked by sweepBudgets, which a single
+// goroutine calls every budgetSweepInterval while m isn't empty.
+var exhausted struct {
+	mu sync.Mutex
+	m  map[*lineBudget]exhaustedBudget
+	// n is the length of m, read using sync/atomic so that sweepBudgets
+	// needn't lock while there is none.
+	n int32
+	// sweeping is true while the goroutine calling sweepBudgets runs.
+	sweeping bool
+}
+
+// budgetSweepInterval is the time between the checks of the contexts of
+// the exhausted budgets.
+var budgetSweepInterval = time.Second
+
+// exhaustedBudget is the context and logger of an exhausted budget.
+type exhaustedBudget struct {
+	ctx context.Context
+	lg  *Logger
+}
+
+// LimitContext returns a copy of ctx allowing at most maxLines entries to be
+// logged through InfoCtx and the other Ctx functions, including by the
+// contexts derived from it, so a single pathological request can't flood
+// the shared logs. Further entries are dropped and counted in
+// Stats.ContextDropped; the first of them is replaced by a Warning entry
+// saying so, and once ctx is done, another Warning entry with a dropped
+// field gives their number. Both carry the fields of the logger of ctx,
+// such as its request ID. The second one is logged within about a second,
+// or by Flush, and never if ctx can't be cancelled. Critical and Fatal
+// entries are never dropped, and neither they nor entries below the minimum
+// severity count. A new limit replaces the one ctx may already have.
+func LimitContext(ctx context.Context, maxLines int) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &lineBudget{max: int64(maxLines)})
+}
+
+// ContextDropped returns the number of entries dropped because the line
+// budget of ctx, as set with LimitContext, was exhausted.
+func ContextDropped(ctx context.Context) int64 {
+	b, _ := ctx.Value(budgetKey{}).(*lineBudget)
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&b.dropped)
+}
+
+// withinBudget reports whether an entry logged through lg, the logger of
+// ctx, is within the line budget of ctx, and counts it.
+func withinBudget(ctx context.Context, lg *Logger) bool {
+	b, _ := ctx.Value(budgetKey{}).(*lineBudget)
+	if b == nil {
+		return true
+	}
+	sweepBudgets()
+	if atomic.AddInt64(&b.used, 1) <= b.max {
+		return true
+	}
+	atomic.AddInt64(&Stats.ContextDropped, 1)
+	if atomic.AddInt64(&b.dropped, 1) == 1 {
+		lg.notice("context line budget exhausted, dropping its further entries", Field{"line_budget", b.max})
+		if ctx.Done() != nil {
+			exhausted.mu.Lock()
+			if len(exhausted.m) < maxExhausted {
+				if exhausted.m == nil {
+					exhausted.m = make(map[*lineBudget]exhaustedBudget)
+				}
+				exhausted.m[b] = exhaustedBudget{ctx, lg}
+				atomic.StoreInt32(&exhausted.n, int32(len(exhausted.m)))
+				if !exhausted.sweeping {
+					exhausted.sweeping = true
+					go sweepBudgetsEvery(budgetSweepInterval)
+				}
+			}
+			exhausted.mu.Unlock()
+		}
+	}
+	return false
+}
+
+// sweepBudgets logs the summary entry of the exhausted budgets whose context
+// is done, and forgets them.
+func sweepBudgets() {
+	if atomic.LoadInt32(&exhausted.n) == 0 {
+		return
+	}
+	var done []*lineBudget
+	var lgs []*Logger
+	exhausted.mu.Lock()
+	for b, x := range exhausted.m {
+		if x.ctx.Err() != nil {
+			done = append(done, b)
+			lgs = append(lgs, x.lg)
+			delete(exhausted.m, b)
+		}
+	}
+	atomic.StoreInt32(&exhausted.n, int32(len(exhausted.m)))
+	exhausted.mu.Unlock()
+	for i, b := range done {
+		lgs[i].notice("context line budget exceeded", Field{"line_budget", b.max}, Field{"dropped", atomic.LoadInt64(&b.dropped)})
+	}
+}
+
+// sweepBudgetsEvery calls sweepBudgets every interval until no exhausted
+// budget is left.
+func sweepBudgetsEvery(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		sweepBudgets()
+		exhausted.mu.Lock()
+		if len(exhausted.m) == 0 {
+			exhausted.sweeping = false
+			exhausted.mu.Unlock()
+			return
+		}
+		exhausted.mu.Unlock()
+	}
+}
+
+// notice logs a Warning entry about flog itself through lg, like
+// loggingT.notice.
+func (lg *Logger) notice(msg string, fields ...Field) {
+	e := newEntry(WarningLog, 0, "flog", 0, msg)
+	e.Fields = append(append([]Field(nil), lg.fields...), fields...)
+	e.output = lg.output
+	e.hooks = lg.hooks
+	logging.output(e)
+}
@@ -0,0 +1,96 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimitContext(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetMinSeverity(InfoLog)
+	defer SetMinSeverity(DebugLog)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = LimitContext(NewContext(ctx, With(F("req", "r1"))), 2)
+	before := atomic.LoadInt64(&Stats.ContextDropped)
+	DebugCtx(ctx, "below min severity")
+	for i := 0; i < 5; i++ {
+		InfoCtxf(ctx, "line %d", i)
+	}
+	if n := strings.Count(contents(), "] line "); n != 2 {
+		t.Errorf("%d entries written, want 2: %q", n, contents())
+	}
+	if !contains("] context line budget exhausted, dropping its further entries req=r1 line_budget=2\n") {
+		t.Errorf("exhaustion not logged: %q", contents())
+	}
+	if ContextDropped(ctx) != 3 || atomic.LoadInt64(&Stats.ContextDropped)-before != 3 {
+		t.Errorf("dropped %d entries, want 3", ContextDropped(ctx))
+	}
+	if ContextDropped(context.Background()) != 0 {
+		t.Error("entries dropped without budget")
+	}
+
+	cancel()
+	Flush()
+	if !contains("] context line budget exceeded req=r1 line_budget=2 dropped=3\n") {
+		t.Errorf("summary not logged: %q", contents())
+	}
+}
+
+func TestLimitContextSweep(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer func(d time.Duration) { budgetSweepInterval = d }(budgetSweepInterval)
+	budgetSweepInterval = time.Millisecond
+	before := runtime.NumGoroutine()
+	var cancels []context.CancelFunc
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+		ctx = LimitContext(ctx, 1)
+		for j := 0; j < 3; j++ {
+			InfoCtx(ctx, "line")
+		}
+		CriticalCtx(ctx, "critical")
+	}
+	if n := runtime.NumGoroutine() - before; n > 1 {
+		t.Errorf("%d goroutines started for the summaries, want at most one", n)
+	}
+	if n := strings.Count(contents(), "] critical\n"); n != 10 {
+		t.Errorf("%d critical entries over the budget written, want 10", n)
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	want := "] context line budget exceeded line_budget=1 dropped=2\n"
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		logging.mu.Lock()
+		n := strings.Count(contents(), want)
+		logging.mu.Unlock()
+		if n == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d summaries logged, want 10: %q", n, contents())
+		}
+	}
+}
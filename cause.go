@@ -0,0 +1,83 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// The keys of the fields linking entries causally.
+const (
+	entryIDKey = "entry_id"
+	causeIDKey = "cause_id"
+)
+
+// causeKey is the key of the cause ID in a context.
+type causeKey struct{}
+
+// NewEntryID returns a random ID for an entry, to pass to WithID and then to
+// WithCause or CauseContext for the entries it caused, so log backends can
+// follow chains such as an error causing a retry causing an alert:
+//
+//	id := flog.NewEntryID()
+//	flog.WithID(id).Error("connection reset")
+//	ctx = flog.CauseContext(ctx, id)
+//	...
+//	flog.WarningCtx(ctx, "retrying") // cause_id=<id>
+func NewEntryID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithID returns a logger writing to the package's output that attaches an
+// entry_id field with id to its entries.
+func WithID(id string) *Logger {
+	return defaultLogger.WithID(id)
+}
+
+// WithID returns a logger sharing the output of lg that attaches an entry_id
+// field with id to its entries.
+func (lg *Logger) WithID(id string) *Logger {
+	return lg.With(Field{entryIDKey, id})
+}
+
+// WithCause returns a logger writing to the package's output that attaches
+// a cause_id field with id, the ID of the entry that caused them, to its
+// entries.
+func WithCause(id string) *Logger {
+	return defaultLogger.WithCause(id)
+}
+
+// WithCause returns a logger sharing the output of lg that attaches a
+// cause_id field with id to its entries.
+func (lg *Logger) WithCause(id string) *Logger {
+	return lg.With(Field{causeIDKey, id})
+}
+
+// CauseContext returns a copy of ctx carrying id as the cause of the entries
+// logged through InfoCtx and the other Ctx functions, which add it as their
+// cause_id field. An empty id removes the cause of ctx.
+func CauseContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, causeKey{}, id)
+}
+
+// CauseFromContext returns the cause ID carried by ctx, empty if none.
+func CauseFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(causeKey{}).(string)
+	return id
+}
@@ -0,0 +1,48 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCause(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	id := NewEntryID()
+	if len(id) != 16 || id == NewEntryID() {
+		t.Fatalf("bad entry ID %q", id)
+	}
+	WithID(id).Error("connection reset")
+	ctx := CauseContext(context.Background(), id)
+	retry := NewEntryID()
+	FromContext(ctx).WithID(retry).Warning("no context")
+	WarningCtx(NewContext(ctx, WithID(retry)), "retrying")
+	WithCause(retry).Critical("alert")
+	for _, want := range []string{
+		"] connection reset entry_id=" + id + "\n",
+		"] no context entry_id=" + retry + "\n",
+		"] retrying entry_id=" + retry + " cause_id=" + id + "\n",
+		"] alert cause_id=" + retry + "\n",
+	} {
+		if !contains(want) {
+			t.Errorf("%q not logged: %q", want, contents())
+		}
+	}
+	if CauseFromContext(context.Background()) != "" || CauseFromContext(CauseContext(ctx, "")) != "" {
+		t.Error("cause found in context without one")
+	}
+}
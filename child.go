@@ -0,0 +1,150 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// maxChildLine is the length past which an unterminated line written by a
+// child process is logged anyway.
+const maxChildLine = 64 << 10
+
+// ForwardChild wires the standard output and error of cmd into flog, before
+// cmd is started. Every line the child writes is logged as an entry of its
+// own, at the stdout or stderr severity depending on the stream, with a
+// child=name field and the file and line of the call to ForwardChild. Lines
+// written in several parts are reassembled first.
+//
+// Call the returned function once cmd.Wait has returned, to log the last
+// line of each stream if the child didn't terminate it:
+//
+//	cmd := exec.Command("helper")
+//	flush := flog.ForwardChild(cmd, "helper", flog.InfoLog, flog.WarningLog)
+//	err := cmd.Run()
+//	flush()
+func ForwardChild(cmd *exec.Cmd, name string, stdout, stderr Severity) (flush func()) {
+	_, file, line := caller(-1)
+	out := &childWriter{name: name, severity: stdout, file: file, line: line}
+	errOut := &childWriter{name: name, severity: stderr, file: file, line: line}
+	cmd.Stdout, cmd.Stderr = out, errOut
+	return func() {
+		out.flush()
+		errOut.flush()
+	}
+}
+
+// childWriter logs the lines written to it by a child process.
+type childWriter struct {
+	name     string
+	severity Severity
+	file     string
+	line     int
+
+	mu      sync.Mutex
+	partial []byte // the start of an unterminated line
+}
+
+// Write is part of the io.Writer interface.
+func (w *childWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			if len(w.partial) >= maxChildLine {
+				w.log(w.partial)
+				w.partial = w.partial[:0]
+			}
+			break
+		}
+		if len(w.partial) > 0 {
+			w.log(append(w.partial, p[:i]...))
+			w.partial = w.partial[:0]
+		} else {
+			w.log(p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// flush logs the unterminated line, if any.
+func (w *childWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.log(w.partial)
+		w.partial = w.partial[:0]
+	}
+}
+
+// log logs a line without its newline.
+// w.mu is held.
+func (w *childWriter) log(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	logging.printWithFileLine(w.severity, w.file, w.line, []Field{{"child", w.name}}, string(line))
+}
+
+// ConfigEnv returns the current logging configuration as FLOG_* env vars,
+// in the KEY=value form of exec.Cmd.Env, so that a child process using flog
+// starts with the verbosity, vmodule, vlogger, backtrace location, error
+// stack cooldown, stack trace level, minimum severity, format, time format
+// and time zone the parent has at the time of the call, including changes
+// made at runtime. Every var is included, empty or not, so it overrides the
+// one the parent inherited.
+// The output settings, such as LogFile, are left out, as a child writing to
+// the files of its parent would interfere with their rotation.
+func ConfigEnv() []string {
+	ensureInit()
+	logging.mu.Lock()
+	traceSet := logging.traceLocation.isSet()
+	logging.mu.Unlock()
+	var backtrace string
+	if traceSet {
+		backtrace = logging.traceLocation.String()
+	}
+	utc := atomic.LoadInt32(&logging.utc) != 0
+	return []string{
+		"FLOG_VERBOSITY=" + strconv.Itoa(int(logging.verbosity.get())),
+		"FLOG_VMODULE=" + logging.vmodule.String(),
+		"FLOG_VLOGGER=" + logging.vlogger.String(),
+		"FLOG_LOG_BACKTRACE_AT=" + backtrace,
+		"FLOG_ERROR_STACK_COOLDOWN=" + logging.errorStacks.String(),
+		"FLOG_STACK_TRACE_LEVEL=" + logging.stackTraceLevel.String(),
+		"FLOG_MIN_SEVERITY=" + logging.minSeverity.String(),
+		"FLOG_FORMAT=" + logging.format.String(),
+		"FLOG_TIME_FORMAT=" + logging.timeFormat.String(),
+		"FLOG_UTC=" + strconv.FormatBool(utc),
+	}
+}
+
+// InheritConfig makes cmd, before it is started, pass the env vars returned
+// by ConfigEnv to the child process, in addition to cmd.Env or, if it is
+// nil, the environment of the parent.
+func InheritConfig(cmd *exec.Cmd) {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env[:len(env):len(env)], ConfigEnv()...)
+}
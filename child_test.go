@@ -0,0 +1,97 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestChildHelper is the child process of TestForwardChild.
+func TestChildHelper(t *testing.T) {
+	if os.Getenv("FLOG_TEST_CHILD") != "1" {
+		return
+	}
+	fmt.Fprint(os.Stdout, "first ")
+	fmt.Fprint(os.Stdout, "line\r\nsecond line\n")
+	fmt.Fprint(os.Stderr, "oops\nunterminated")
+	os.Exit(0)
+}
+
+func TestForwardChild(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	cmd := exec.Command(os.Args[0], "-test.run=TestChildHelper")
+	cmd.Env = append(os.Environ(), "FLOG_TEST_CHILD=1")
+	flush := ForwardChild(cmd, "helper", InfoLog, WarningLog)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	flush()
+	for _, want := range []string{
+		"] first line child=helper\n",
+		"] second line child=helper\n",
+		"] oops child=helper\n",
+		"] unterminated child=helper\n",
+	} {
+		if !contains(want) {
+			t.Errorf("missing %q in %q", want, contents())
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(contents(), "\n"), "\n") {
+		if !strings.Contains(line, " child_test.go:") {
+			t.Errorf("wrong call site: %q", line)
+		}
+		fromStderr := strings.Contains(line, "oops") || strings.Contains(line, "unterminated")
+		if fromStderr != (line[0] == 'W') {
+			t.Errorf("wrong severity: %q", line)
+		}
+	}
+}
+
+// TestInheritConfigHelper is the child process of TestInheritConfig.
+func TestInheritConfigHelper(t *testing.T) {
+	if os.Getenv("FLOG_TEST_CHILD") != "2" {
+		return
+	}
+	Init()
+	fmt.Printf("%d %s %s\n", logging.verbosity.get(), logging.vmodule.String(), logging.format.String())
+	os.Exit(0)
+}
+
+func TestInheritConfig(t *testing.T) {
+	defer logging.verbosity.Set("0")
+	logging.verbosity.Set("3")
+	if err := logging.vmodule.Set("child_test=2"); err != nil {
+		t.Fatal(err)
+	}
+	defer logging.vmodule.Set("")
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestInheritConfigHelper")
+	cmd.Env = append(os.Environ(), "FLOG_TEST_CHILD=2", "FLOG_VERBOSITY=1")
+	InheritConfig(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "3 child_test=2 json\n"; got != want {
+		t.Errorf("child has config %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// processStart is the time the package was initialized. Monotonic
+// timestamps are measured from it.
+var processStart = time.Now()
+
+// clockJumpTolerance is how far back the wall clock may step, compared to the
+// monotonic clock, before entries are flagged.
+const clockJumpTolerance = time.Millisecond
+
+// clockNoticeThreshold is how far the wall clock may step, compared to the
+// monotonic clock, before a notice is logged. Forward steps are flagged
+// from there too, smaller ones being indistinguishable from clock slewing.
+const clockNoticeThreshold = time.Second
+
+// Keys of the fields added to entries by stampClock.
+const (
+	monoKey        = "mono"
+	clockJumpKey   = "clock_jump"
+	previousPidKey = "previous_pid"
+	newPidKey      = "new_pid"
+)
+
+// SetMonotonicTimestamps turns the mono field on or off. When on, every
+// entry carries the monotonic time elapsed since the process started, its
+// Mono member, in a mono field, which orders entries correctly even across
+// steps of the wall clock.
+// This function is safe to use concurrently.
+func SetMonotonicTimestamps(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.monoTimestamps, v)
+}
+
+// clockState tracks the offset between the wall and monotonic clocks to
+// detect steps of the wall clock.
+type clockState struct {
+	// offset is the wall clock time minus the monotonic time of the latest
+	// entry, in nanoseconds. It is read and written using sync/atomic.
+	offset int64
+	// pidChecked is the Unix time, in seconds, the process ID was last
+	// checked at. It is read and written using sync/atomic.
+	pidChecked int64
+}
+
+// getPid returns the process ID written with the entries.
+func getPid() int {
+	p := atomic.LoadInt64(&pid)
+	if p == 0 {
+		p = int64(os.Getpid())
+		atomic.StoreInt64(&pid, p)
+	}
+	return int(p)
+}
+
+// stampClock sets the monotonic timestamp of e and, if the wall clock
+// stepped back since the previous entry, as with an NTP step, or forward by
+// clockNoticeThreshold or more, flags e with a clock_jump field holding the
+// size of the step. Such jumps are counted in Stats.ClockJumps, and the
+// large ones are reported by a Warning entry before e. Once per second, it
+// also checks the process ID, which changes when a process forks to
+// daemonize, and reports changes the same way.
+func (l *loggingT) stampClock(e *Entry) {
+	if atomic.LoadInt32(&l.utc) != 0 {
+		e.Time = e.Time.UTC()
+	}
+	e.Mono = e.Time.Sub(processStart)
+	offset := e.Time.UnixNano() - int64(e.Mono)
+	prev := atomic.SwapInt64(&l.clock.offset, offset)
+	if jump := time.Duration(offset - prev); prev != 0 && (jump < -clockJumpTolerance || jump >= clockNoticeThreshold) {
+		atomic.AddInt64(&Stats.ClockJumps, 1)
+		e.setField(clockJumpKey, jump)
+		if jump <= -clockNoticeThreshold || jump >= clockNoticeThreshold {
+			l.notice("wall clock stepped", Field{clockJumpKey, jump})
+		}
+	}
+	sec := e.Time.Unix()
+	if checked := atomic.LoadInt64(&l.clock.pidChecked); checked != sec && atomic.CompareAndSwapInt64(&l.clock.pidChecked, checked, sec) {
+		if now, old := int64(os.Getpid()), int64(getPid()); now != old {
+			atomic.StoreInt64(&pid, now)
+			atomic.AddInt64(&Stats.PidChanges, 1)
+			l.notice("process ID changed", Field{previousPidKey, old}, Field{newPidKey, now})
+		}
+	}
+	if atomic.LoadInt32(&l.monoTimestamps) != 0 {
+		e.setField(monoKey, e.Mono)
+	}
+}
+
+// notice logs a Warning entry from flog itself about a change of the
+// environment.
+func (l *loggingT) notice(msg string, fields ...Field) {
+	e := newEntry(WarningLog, 0, "flog", 0, msg)
+	e.Fields = fields
+	l.output(e)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClockJump(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Info("first")
+	if contains(clockJumpKey) || contains(monoKey) {
+		t.Errorf("unexpected clock fields: %q", contents())
+	}
+
+	// Pretend the wall clock was 2s ahead at the previous entry.
+	jumps := atomic.LoadInt64(&Stats.ClockJumps)
+	atomic.AddInt64(&logging.clock.offset, int64(2*time.Second))
+	Info("second")
+	if !contains("] second clock_jump=-") {
+		t.Errorf("backward step not flagged: %q", contents())
+	}
+	if n := atomic.LoadInt64(&Stats.ClockJumps) - jumps; n != 1 {
+		t.Errorf("Stats.ClockJumps increased by %d, want 1", n)
+	}
+}
+
+func TestMonotonicTimestamps(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetMonotonicTimestamps(true)
+	defer SetMonotonicTimestamps(false)
+	var mono time.Duration
+	AddProcessor(PhaseEnrich, "mono", func(e *Entry) bool {
+		mono = e.Mono
+		return true
+	})
+	defer resetPipeline()
+	Info("stamped")
+	if mono <= 0 || !contains("] stamped mono="+mono.String()+"\n") {
+		t.Errorf("mono field missing or wrong for %v: %q", mono, contents())
+	}
+}
+
+func TestPidChange(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	changes := atomic.LoadInt64(&Stats.PidChanges)
+	real := atomic.LoadInt64(&pid)
+	// Pretend the process forked since the pid was last checked.
+	atomic.StoreInt64(&pid, real+1)
+	atomic.StoreInt64(&logging.clock.pidChecked, 0)
+	Info("after fork")
+	want := fmt.Sprintf("] process ID changed previous_pid=%d new_pid=%d\n", real+1, real)
+	if !contains(want) || !contains(fmt.Sprintf(" %7d clock_test.go:", real)) {
+		t.Errorf("got %q, want a notice %q and the new pid", contents(), want)
+	}
+	if getPid() != int(real) {
+		t.Errorf("pid is %d, want %d", getPid(), real)
+	}
+	if n := atomic.LoadInt64(&Stats.PidChanges) - changes; n != 1 {
+		t.Errorf("Stats.PidChanges increased by %d, want 1", n)
+	}
+}
+
+func TestClockJumpNotice(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Info("first")
+	// Pretend the wall clock was 1h behind at the previous entry.
+	atomic.AddInt64(&logging.clock.offset, -int64(time.Hour))
+	Info("second")
+	// The offsets of the two entries may differ by a few nanoseconds.
+	for _, prefix := range []string{"] wall clock stepped clock_jump=", "] second clock_jump="} {
+		i := strings.Index(contents(), prefix)
+		if i < 0 {
+			t.Errorf("forward step not reported: %q", contents())
+			continue
+		}
+		v := contents()[i+len(prefix):]
+		v = v[:strings.IndexAny(v, " \n")]
+		if jump, err := time.ParseDuration(v); err != nil || jump < time.Hour-time.Second || jump > time.Hour+time.Second {
+			t.Errorf("clock_jump=%s, want about 1h", v)
+		}
+	}
+	if i, j := strings.Index(contents(), "wall clock stepped"), strings.Index(contents(), "] second"); i > j {
+		t.Errorf("notice after the entry: %q", contents())
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ColorMode tells when the headers of the text format are colored by
+// severity. *ColorMode implements flag.Value; the -log_color flag is of type
+// ColorMode.
+type ColorMode int32
+
+const (
+	// ColorAuto colors the output when it is a terminal, the default,
+	// unless the NO_COLOR env var is set or TERM is dumb.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors the output whatever it is.
+	ColorAlways
+	// ColorNever never colors the output.
+	ColorNever
+	numColorMode
+)
+
+var colorModeName = [numColorMode]string{
+	ColorAuto:   "auto",
+	ColorAlways: "always",
+	ColorNever:  "never",
+}
+
+// severityColor holds the ANSI escape sequences coloring each severity.
+var severityColor = [numSeverity]string{
+	DebugLog:    "\x1b[90m",   // gray
+	InfoLog:     "\x1b[32m",   // green
+	WarningLog:  "\x1b[33m",   // yellow
+	ErrorLog:    "\x1b[31m",   // red
+	CriticalLog: "\x1b[1;31m", // bold red
+	FatalLog:    "\x1b[1;35m", // bold magenta
+}
+
+const colorReset = "\x1b[0m"
+
+// SetColor sets when the severity letter and the rest of the header of the
+// entries written in the text format are colored by severity, so errors
+// stand out when developers run services in a terminal. Only the output set
+// by SetOutput and SetSeverityOutput is colored, never the outputs added
+// with AddOutput nor those of Loggers, and the JSON format never is.
+// This function is safe to use concurrently.
+func SetColor(m ColorMode) {
+	ensureInit()
+	logging.color.set(m)
+}
+
+// get returns the value of the ColorMode.
+func (m *ColorMode) get() ColorMode {
+	return ColorMode(atomic.LoadInt32((*int32)(m)))
+}
+
+// set sets the value of the ColorMode.
+func (m *ColorMode) set(val ColorMode) {
+	atomic.StoreInt32((*int32)(m), int32(val))
+}
+
+// String is part of the flag.Value interface.
+func (m *ColorMode) String() string {
+	if v := m.get(); v >= 0 && v < numColorMode {
+		return colorModeName[v]
+	}
+	return "ColorMode(" + strconv.Itoa(int(*m)) + ")"
+}
+
+// Get is part of the flag.Value interface.
+func (m *ColorMode) Get() interface{} {
+	return m.get()
+}
+
+// Set is part of the flag.Value interface. The value is "auto", "always"
+// or "never", or a boolean for always or never; an empty value selects
+// auto.
+func (m *ColorMode) Set(value string) error {
+	ensureInit()
+	if value == "" {
+		m.set(ColorAuto)
+		return nil
+	}
+	for i, name := range colorModeName {
+		if strings.EqualFold(value, name) {
+			m.set(ColorMode(i))
+			return nil
+		}
+	}
+	if on, err := strconv.ParseBool(value); err == nil {
+		if on {
+			m.set(ColorAlways)
+		} else {
+			m.set(ColorNever)
+		}
+		return nil
+	}
+	return errors.New("unknown color mode: expect auto, always or never")
+}
+
+// terminal is the last file checked by isTerminal, with the result.
+type terminal struct {
+	f   *os.File
+	tty bool
+}
+
+// lastTerminal holds the terminal last checked by isTerminal.
+var lastTerminal atomic.Value
+
+// colored reports whether the entries written to out are to be colored.
+func (l *loggingT) colored(out io.Writer) bool {
+	if l.loadEncoder() != nil {
+		return false
+	}
+	switch l.color.get() {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// isTerminal reports whether f is a terminal fit for colors. The result for
+// the last file checked is cached, as it is nearly always the same.
+func isTerminal(f *os.File) bool {
+	if t, ok := lastTerminal.Load().(terminal); ok && t.f == f {
+		return t.tty
+	}
+	tty := os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	if tty {
+		info, err := f.Stat()
+		tty = err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	lastTerminal.Store(terminal{f, tty})
+	return tty
+}
+
+// colorize returns data, an entry of severity s, with its header colored.
+// Entries that don't have a glog-like header ending in "] ", such as JSON,
+// logfmt and raw entries, are returned as is.
+func colorize(data []byte, s Severity) []byte {
+	if len(data) == 0 || data[0] == '{' || bytes.HasPrefix(data, []byte("ts=")) {
+		return data
+	}
+	line := data
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	end := bytes.Index(line, []byte("] "))
+	if end < 0 {
+		return data
+	}
+	if s < DebugLog || s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	c := make([]byte, 0, len(data)+len(severityColor[s])+len(colorReset))
+	c = append(c, severityColor[s]...)
+	c = append(c, data[:end+1]...)
+	c = append(c, colorReset...)
+	return append(c, data[end+1:]...)
+}
@@ -0,0 +1,78 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Error("auto")
+	if strings.Contains(contents(), "\x1b[") {
+		t.Errorf("buffer colored in auto mode: %q", contents())
+	}
+	SetColor(ColorAlways)
+	defer SetColor(ColorAuto)
+	logging.newBuffers()
+	Error("oops\nsecond line")
+	if !strings.HasPrefix(contents(), "\x1b[31mE") || !contains("color_test.go:") || !contains("]\x1b[0m oops\nsecond line\n") {
+		t.Errorf("wrong colors: %q", contents())
+	}
+	logging.newBuffers()
+	SetFormat(FormatJSON)
+	Warning("json")
+	SetFormat(FormatLogfmt)
+	Warning("[x] logfmt")
+	SetFormat(FormatText)
+	if strings.Contains(contents(), "\x1b[") {
+		t.Errorf("JSON or logfmt colored: %q", contents())
+	}
+	SetColor(ColorNever)
+	logging.newBuffers()
+	Warning("never")
+	if strings.Contains(contents(), "\x1b[") {
+		t.Errorf("colored in never mode: %q", contents())
+	}
+}
+
+func TestColorModeSet(t *testing.T) {
+	var m ColorMode
+	for value, want := range map[string]ColorMode{"": ColorAuto, "ALWAYS": ColorAlways, "never": ColorNever, "1": ColorAlways, "false": ColorNever} {
+		if err := m.Set(value); err != nil || m != want {
+			t.Errorf("Set(%q) = %v, %v", value, m.String(), err)
+		}
+	}
+	if err := m.Set("rainbow"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// /dev/null passes for a terminal, but NO_COLOR must win.
+	defer os.Setenv("NO_COLOR", os.Getenv("NO_COLOR"))
+	os.Setenv("NO_COLOR", "1")
+	if isTerminal(f) {
+		t.Error("NO_COLOR ignored")
+	}
+}
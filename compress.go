@@ -0,0 +1,114 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"compress/flate"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CompressedWriter compresses the log entries written to it with DEFLATE
+// and a preset dictionary. Highly repetitive service logs compress much
+// better with a dictionary built from a sample of their messages, since the
+// dictionary primes the compressor with the text the entries repeat:
+//
+//	dict := flog.BuildDictionary(samples, 32<<10)
+//	cw, err := flog.NewCompressedWriter(f, dict)
+//	...
+//	flog.SetOutput(cw)
+//
+// The reader needs the same dictionary, see flate.NewReaderDict. Compressed
+// data is written out when the internal buffers fill up and on Flush, which
+// ActionFlushExit calls, and Close.
+type CompressedWriter struct {
+	zw *flate.Writer
+}
+
+// NewCompressedWriter returns a writer compressing to w with the preset
+// dictionary dict, which may be nil.
+func NewCompressedWriter(w io.Writer, dict []byte) (*CompressedWriter, error) {
+	// Lower levels skip matching on small blocks, which short streams and
+	// frequent flushes produce, so the dictionary would go unused.
+	zw, err := flate.NewWriterDict(w, flate.BestCompression, dict)
+	if err != nil {
+		return nil, err
+	}
+	return &CompressedWriter{zw: zw}, nil
+}
+
+// Write is part of the io.Writer interface.
+func (cw *CompressedWriter) Write(p []byte) (int, error) {
+	return cw.zw.Write(p)
+}
+
+// Flush writes out the data compressed so far.
+func (cw *CompressedWriter) Flush() error {
+	return cw.zw.Flush()
+}
+
+// Close flushes the data and ends the compressed stream. It doesn't close the
+// underlying writer.
+func (cw *CompressedWriter) Close() error {
+	return cw.zw.Close()
+}
+
+// BuildDictionary builds a preset dictionary of at most size bytes from
+// samples of log messages, for NewCompressedWriter. It keeps the words that
+// would save the most bytes, that is the long ones that appear often, and
+// puts the most valuable ones last, where DEFLATE references them most
+// cheaply. Use a few thousand messages representative of the logs.
+func BuildDictionary(samples []string, size int) []byte {
+	counts := make(map[string]int)
+	for _, s := range samples {
+		for _, word := range strings.Fields(s) {
+			if len(word) > 3 {
+				counts[word]++
+			}
+		}
+	}
+	type candidate struct {
+		word  string
+		value int
+	}
+	var candidates []candidate
+	for word, n := range counts {
+		if n > 1 {
+			candidates = append(candidates, candidate{word, n * len(word)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].value != candidates[j].value {
+			return candidates[i].value > candidates[j].value
+		}
+		return candidates[i].word < candidates[j].word
+	})
+	var words []string
+	n := 0
+	for _, c := range candidates {
+		if n+len(c.word)+1 > size {
+			continue
+		}
+		words = append(words, c.word)
+		n += len(c.word) + 1
+	}
+	dict := make([]byte, 0, n)
+	for i := len(words) - 1; i >= 0; i-- {
+		dict = append(dict, words[i]...)
+		dict = append(dict, ' ')
+	}
+	return dict
+}
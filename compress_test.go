@@ -0,0 +1,55 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressedWriter(t *testing.T) {
+	var samples []string
+	for i := 0; i < 100; i++ {
+		samples = append(samples, fmt.Sprintf("request completed handler=checkout status=200 latency=%dms", i))
+	}
+	dict := BuildDictionary(samples, 256)
+	if len(dict) == 0 || len(dict) > 256 {
+		t.Fatalf("dictionary of %d bytes", len(dict))
+	}
+
+	compress := func(dict []byte) []byte {
+		var out bytes.Buffer
+		cw, err := NewCompressedWriter(&out, dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(cw, samples[42])
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return out.Bytes()
+	}
+	plain, primed := compress(nil), compress(dict)
+	if len(primed) >= len(plain) {
+		t.Errorf("dictionary didn't help: %d bytes with it, %d without", len(primed), len(plain))
+	}
+	got, err := ioutil.ReadAll(flate.NewReaderDict(bytes.NewReader(primed), dict))
+	if err != nil || string(got) != samples[42]+"\n" {
+		t.Errorf("round trip = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentReconfigure logs from many goroutines while others change
+// the configuration, for the race detector to check the shared state is
+// only accessed through locks or atomics.
+func TestConcurrentReconfigure(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.verbosity.Set("0")
+	defer logging.vmodule.Set("")
+	defer SetFormat(FormatText)
+	defer SetMinSeverity(DebugLog)
+	defer SetLinePrefix("")
+	defer SetSequenceNumbers(false)
+	defer logging.vlogger.Set("")
+
+	out := logging.out
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lg := Named("race" + strconv.Itoa(i%2)).With(F("n", i))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				Info("info")
+				V(2).Infof("v%d", i)
+				lg.Warning("named")
+				if VDepth(0, 1) {
+					Debug("debug")
+				}
+			}
+		}(i)
+	}
+	reconfigure := []func(i int){
+		func(i int) { logging.verbosity.Set(strconv.Itoa(i % 3)) },
+		func(i int) { logging.vmodule.Set("concurrency_test=" + strconv.Itoa(i%3)) },
+		func(i int) { SetFormat(Format(i % 2)) },
+		func(i int) { SetMinSeverity(Severity(i % 3)) },
+		func(i int) {
+			if i%2 == 0 {
+				SetOutput(ioutil.Discard)
+			} else {
+				SetOutput(out)
+			}
+		},
+		func(i int) { SetLinePrefix(strconv.Itoa(i % 2)) },
+		func(i int) { SetSequenceNumbers(i%2 == 0) },
+		func(i int) { logging.vlogger.Set("race0=" + strconv.Itoa(i%3)) },
+	}
+	for _, f := range reconfigure {
+		wg.Add(1)
+		go func(f func(int)) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				f(i)
+				time.Sleep(100 * time.Microsecond)
+			}
+		}(f)
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
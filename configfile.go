@@ -0,0 +1,231 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadConfigFile reads a Config from the file at path and sets it, so the
+// logging policy can be managed by configuration management tools rather than
+// through env vars. The format follows the file extension: .json, .yaml or
+// .yml, and .toml. Keys name the members of Config in any case, with or
+// without underscores or dashes, so "vmodule", "Vmodule" and "max_size_mb"
+// are all accepted:
+//
+//	verbosity: 2
+//	vmodule: gfs*=3,recordio=1
+//	format: json
+//
+// Only flat documents are supported: YAML and TOML files may hold key/value
+// pairs and comments, but no nesting, tables or lists. Members missing from
+// the file take their zero values, except Verbosity which defaults to 0, so
+// the file describes the whole configuration. Invalid files and values are
+// reported as described for ConfigErrorPrefix.
+func LoadConfigFile(path string) error {
+	c, err := readConfigFile(path)
+	if err != nil {
+		return checkConfig("ConfigFile", path, err)
+	}
+	return c.Set()
+}
+
+// WatchConfigFile checks the file at path every interval and calls
+// LoadConfigFile when its modification time or size changed, so changes to
+// the verbosity, vmodule, format and the rest of the configuration take effect
+// without a restart. Call LoadConfigFile first for the initial configuration.
+// Every reload is logged as an Info entry and every failure as an Error
+// entry, in which case the settings set before the failing one stay applied.
+// A file missing for a while, as when it is being replaced, is not an error.
+// The returned function stops the watching and waits for a reload in
+// progress.
+func WatchConfigFile(path string, interval time.Duration) (stop func()) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	last, _ := os.Stat(path)
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				fi, err := os.Stat(path)
+				if err != nil || (last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size()) {
+					continue
+				}
+				last = fi
+				if err := LoadConfigFile(path); err != nil {
+					Errorf("config file %s not applied: %v", path, err)
+					continue
+				}
+				Infof("config reloaded from %s", path)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// readConfigFile parses the file at path in the format of its extension.
+func readConfigFile(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		values, err = parseJSONConfig(data)
+	case ".yaml", ".yml":
+		values, err = parseFlatConfig(data, ':')
+	case ".toml":
+		values, err = parseFlatConfig(data, '=')
+	default:
+		return nil, fmt.Errorf("unknown config file format %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{Verbosity: "0"}
+	for k, v := range values {
+		if err := setConfigMember(c, k, v); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// parseJSONConfig parses a JSON object whose members are strings, numbers or
+// booleans.
+func parseJSONConfig(data []byte) (map[string]string, error) {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			values[k] = v
+		case json.Number:
+			values[k] = v.String()
+		case bool:
+			values[k] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("%s: value is not a string, number or boolean", k)
+		}
+	}
+	return values, nil
+}
+
+// parseFlatConfig parses lines of key, sep and value, as a flat YAML or TOML
+// document. Values may be quoted, and # starts a comment outside quotes.
+func parseFlatConfig(data []byte, sep byte) (map[string]string, error) {
+	values := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line == "---" {
+			continue
+		}
+		i := strings.IndexByte(line, sep)
+		if i <= 0 || strings.ContainsAny(line[:1], "[-{") {
+			return nil, fmt.Errorf("line %d: expected key %c value", n, sep)
+		}
+		k := strings.TrimSpace(line[:i])
+		v, err := flatConfigValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if _, ok := values[k]; ok {
+			return nil, fmt.Errorf("line %d: %s set twice", n, k)
+		}
+		values[k] = v
+	}
+	return values, s.Err()
+}
+
+// flatConfigValue returns the value v stands for, unquoted and without a
+// trailing comment.
+func flatConfigValue(v string) (string, error) {
+	if v == "" || (v[0] != '"' && v[0] != '\'') {
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		return v, nil
+	}
+	end := 1
+	for ; end < len(v) && v[end] != v[0]; end++ {
+		if v[0] == '"' && v[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(v) {
+		return "", fmt.Errorf("unterminated string %s", v)
+	}
+	rest := strings.TrimSpace(v[end+1:])
+	if rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected %q after string", rest)
+	}
+	if v[0] == '\'' {
+		return v[1:end], nil
+	}
+	return strconv.Unquote(v[:end+1])
+}
+
+// setConfigMember sets the member of c named by key to value.
+func setConfigMember(c *Config, key, value string) error {
+	name := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	rv := reflect.ValueOf(c).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if strings.ToLower(rv.Type().Field(i).Name) != name {
+			continue
+		}
+		switch f := rv.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(value)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			f.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			f.SetBool(b)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
@@ -0,0 +1,124 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer logging.verbosity.Set("0")
+	defer logging.vmodule.Set("")
+	defer logging.format.Set("")
+	files := map[string]string{
+		"flog.json": `{"verbosity": 2, "vmodule": "gfs*=3", "Format": "json", "sample_rate": 0}`,
+		"flog.yaml": "# logging policy\n---\nverbosity: 2\nvmodule: 'gfs*=3' # storage\nformat: \"json\"\n",
+		"flog.toml": "verbosity = 2\nVmodule = \"gfs*=3\"\n\nformat = json\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		logging.verbosity.Set("0")
+		logging.vmodule.Set("")
+		logging.format.Set("")
+		if err := LoadConfigFile(path); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := logging.verbosity.get(); got != 2 {
+			t.Errorf("%s: verbosity is %d", name, got)
+		}
+		if got := logging.vmodule.String(); got != "gfs*=3" {
+			t.Errorf("%s: vmodule is %q", name, got)
+		}
+		if got := logging.format.String(); got != "json" {
+			t.Errorf("%s: format is %q", name, got)
+		}
+	}
+}
+
+func TestLoadConfigFileError(t *testing.T) {
+	var out bytes.Buffer
+	configErrorOutput = &out
+	defer func() { configErrorOutput = os.Stderr }()
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"unknown.yaml": "verbosty: 2\n",
+		"nested.yaml":  "flog:\n  verbosity: 2\n",
+		"number.toml":  "max_size_mb = big\n",
+		"quote.toml":   "vmodule = \"gfs*=3\n",
+		"object.json":  `{"verbosity": [2]}`,
+		"flog.ini":     "verbosity=2\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := LoadConfigFile(path); err == nil {
+			t.Errorf("%s was accepted", name)
+		}
+		if !strings.Contains(out.String(), `"setting":"ConfigFile"`) {
+			t.Errorf("%s: config error not reported: %q", name, out.String())
+		}
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer logging.verbosity.Set("0")
+	path := filepath.Join(dir, "flog.yaml")
+	if err := ioutil.WriteFile(path, []byte("verbosity: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	stop := WatchConfigFile(path, time.Millisecond)
+	defer stop()
+	if err := ioutil.WriteFile(path, []byte("verbosity: 3 # debugging\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; logging.verbosity.get() != 3; i++ {
+		if i == 1000 {
+			t.Fatal("config change not applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+)
+
+// contextKey is the key of the logger in a context.
+type contextKey struct{}
+
+// defaultLogger is returned by FromContext for contexts without a logger.
+var defaultLogger = &Logger{}
+
+// NewContext returns a copy of ctx carrying lg, so request-scoped loggers,
+// with the trace ID of the request and such as fields, can be threaded
+// through handlers:
+//
+//	ctx = flog.NewContext(ctx, flog.With(flog.F("trace_id", id)))
+//	...
+//	flog.FromContext(ctx).Info("cache miss")
+func NewContext(ctx context.Context, lg *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, lg)
+}
+
+// FromContext returns the logger carried by ctx, or a logger writing to the
+// package's output without fields if there is none. It never returns nil.
+func FromContext(ctx context.Context) *Logger {
+	if lg, ok := ctx.Value(contextKey{}).(*Logger); ok && lg != nil {
+		return lg
+	}
+	return defaultLogger
+}
@@ -0,0 +1,36 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	ctx := context.Background()
+	FromContext(ctx).Info("no logger")
+	if !contains("] no logger\n") {
+		t.Errorf("default logger didn't log: %q", contents())
+	}
+
+	ctx = NewContext(ctx, With(F("trace_id", "abc")))
+	FromContext(ctx).With(F("step", 2)).Info("request")
+	if !contains("context_test.go:") || !contains("] request trace_id=abc step=2\n") {
+		t.Errorf("logger from context didn't log its fields: %q", contents())
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// savedCounters is the content of the counter file, see SetCounterFile.
+type savedCounters struct {
+	Lines map[string]int64 `json:"lines"`
+	Bytes map[string]int64 `json:"bytes"`
+}
+
+// counterFile is the file set by SetCounterFile.
+var counterFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// SetCounterFile makes the line and byte counts of Stats survive restarts:
+// the counts saved in the file at path, if it exists, are loaded as the
+// counts of the previous runs, returned by the Lifetime methods of
+// OutputStats, then the lifetime counts are saved to the file on Flush and
+// before flog exits the process. This answers questions such as the number
+// of errors since the last deployment from the process itself. Call it once
+// at startup, and Flush before exiting; an empty path stops saving the
+// counts. Invalid files and failures to save the counts are reported as
+// described for ConfigErrorPrefix.
+// This function is safe to use concurrently.
+func SetCounterFile(path string) error {
+	ensureInit()
+	counterFile.mu.Lock()
+	defer counterFile.mu.Unlock()
+	var saved savedCounters
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &saved)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return checkConfig("CounterFile", path, err)
+		}
+	}
+	for sev, stats := range severityStats {
+		if stats != nil {
+			atomic.StoreInt64(&stats.savedLines, saved.Lines[severityName[sev]])
+			atomic.StoreInt64(&stats.savedBytes, saved.Bytes[severityName[sev]])
+		}
+	}
+	counterFile.path = path
+	return nil
+}
+
+// saveCounters writes the lifetime counts to the counter file, if any. The
+// file is replaced as a whole, so a crash never leaves it half written.
+func saveCounters() {
+	counterFile.mu.Lock()
+	defer counterFile.mu.Unlock()
+	if counterFile.path == "" {
+		return
+	}
+	saved := savedCounters{make(map[string]int64), make(map[string]int64)}
+	for sev, stats := range severityStats {
+		if stats != nil {
+			saved.Lines[severityName[sev]] = stats.LifetimeLines()
+			saved.Bytes[severityName[sev]] = stats.LifetimeBytes()
+		}
+	}
+	data, _ := json.Marshal(saved)
+	tmp := counterFile.path + ".tmp"
+	err := ioutil.WriteFile(tmp, append(data, '\n'), 0644)
+	if err == nil {
+		err = os.Rename(tmp, counterFile.path)
+	}
+	checkConfig("CounterFile", counterFile.path, err)
+}
+
+// LifetimeLines returns the number of lines written, including by the
+// previous runs counted in the file set by SetCounterFile.
+func (s *OutputStats) LifetimeLines() int64 {
+	return atomic.LoadInt64(&s.savedLines) + atomic.LoadInt64(&s.lines)
+}
+
+// LifetimeBytes returns the number of bytes written, including by the
+// previous runs counted in the file set by SetCounterFile.
+func (s *OutputStats) LifetimeBytes() int64 {
+	return atomic.LoadInt64(&s.savedBytes) + atomic.LoadInt64(&s.bytes)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCounterFile(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "flog.counters")
+	if err := ioutil.WriteFile(path, []byte(`{"lines":{"ERROR":40},"bytes":{"ERROR":4000}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetCounterFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer SetCounterFile("")
+	Error("since deploy")
+	if got, want := Stats.Error.LifetimeLines(), 40+Stats.Error.Lines(); got != want {
+		t.Errorf("lifetime lines %d, want %d", got, want)
+	}
+	if got := GetStats().LifetimeBytes["ERROR"]; got != 4000+Stats.Error.Bytes() {
+		t.Errorf("lifetime bytes %d in snapshot", got)
+	}
+
+	Flush()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved savedCounters
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Lines["ERROR"] != Stats.Error.LifetimeLines() || saved.Lines["INFO"] != Stats.Info.Lines() {
+		t.Errorf("saved counts %s", data)
+	}
+
+	var out strings.Builder
+	configErrorOutput = &out
+	defer func() { configErrorOutput = os.Stderr }()
+	ioutil.WriteFile(path, []byte("garbage"), 0644)
+	if err := SetCounterFile(path); err == nil || !strings.Contains(out.String(), `"setting":"CounterFile"`) {
+		t.Errorf("invalid file accepted: %v %q", err, out.String())
+	}
+	SetCounterFile("")
+	if Stats.Error.LifetimeLines() != Stats.Error.Lines() {
+		t.Error("previous counts kept without a counter file")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// MirrorCrashOutput makes the runtime also write the output of fatal errors,
+// such as an unrecovered panic in any goroutine, to f, in addition to
+// stderr, so crashes are reflected in log files and not only on a stderr
+// nobody may capture. Passing nil stops the mirroring. It requires Go 1.23
+// or later and returns an error otherwise; LogPanics works with any version,
+// and reaches the hooks and the other outputs too.
+//
+// The file output set through Config.LogFile is mirrored to automatically,
+// including after rotations, where supported.
+func MirrorCrashOutput(f *os.File) error {
+	ensureInit()
+	return setCrashOutput(f)
+}
+
+// LogPanics, deferred at the top of a goroutine, logs a panic unwinding the
+// goroutine as a Critical entry with a panic field and the stack trace of
+// the panic, and flushes the outputs, before panicking again with the same
+// value. The process thus still crashes as it would have, but the panic is
+// reflected in every output, hook and exporter, such as syslog, journald or
+// OTLP, and not only on stderr:
+//
+//	go func() {
+//		defer flog.LogPanics()
+//		...
+//	}()
+//
+// The entry is attributed to the function that panicked.
+func LogPanics() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	pc, file, line := panicSite()
+	e := newEntry(CriticalLog, pc, file, line, "goroutine panicked")
+	e.Fields = []Field{{"panic", fmt.Sprint(r)}}
+	e.Stack = debug.Stack()
+	logging.output(e)
+	Flush()
+	panic(r)
+}
+
+// panicSite returns the call site of the function that panicked, from
+// within a function deferred by it.
+func panicSite() (uintptr, string, int) {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			file := f.File
+			if slash := strings.LastIndex(file, "/"); slash >= 0 {
+				file = file[slash+1:]
+			}
+			return f.PC, file, f.Line
+		}
+		if !more {
+			return 0, "???", 1
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCrashHelper is not a real test. It panics in a goroutine with the
+// crash output mirrored to FLOG_TEST_CRASH_FILE when run by TestMirrorCrashOutput.
+func TestCrashHelper(t *testing.T) {
+	path := os.Getenv("FLOG_TEST_CRASH_FILE")
+	if path == "" {
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		os.Exit(3)
+	}
+	if err := MirrorCrashOutput(f); err != nil {
+		os.Exit(3)
+	}
+	done := make(chan bool)
+	go func() {
+		panic("crash helper")
+	}()
+	<-done
+}
+
+func TestMirrorCrashOutput(t *testing.T) {
+	if err := setCrashOutput(nil); err != nil {
+		t.Skip(err)
+	}
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "crash")
+	cmd := exec.Command(os.Args[0], "-test.run=TestCrashHelper")
+	cmd.Env = append(os.Environ(), "FLOG_TEST_CRASH_FILE="+path)
+	if err := cmd.Run(); err == nil {
+		t.Fatal("helper exited successfully, want a crash")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "panic: crash helper") {
+		t.Errorf("crash output is %q, want the panic", data)
+	}
+}
+
+func TestLogPanics(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var hooked []Entry
+	defer AddHook(func(e Entry) { hooked = append(hooked, e) })()
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic again", r)
+			}
+		}()
+		defer LogPanics()
+		panic("boom")
+	}()
+	if !contains("crash_test.go:") || !contains("] goroutine panicked panic=boom\n") || !contains("TestLogPanics") || contents()[0] != 'C' {
+		t.Errorf("panic not logged: %q", contents())
+	}
+	if len(hooked) != 1 || hooked[0].Severity != CriticalLog || len(hooked[0].Stack) == 0 {
+		t.Errorf("hooks got %v, want the panic with its stack", hooked)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ctxErrKey is the key of the field telling why the context of an entry
+// was done when it was logged.
+const ctxErrKey = "ctx_err"
+
+// SetContextStatus makes InfoCtx and the other Ctx functions add a ctx_err
+// field to their entries when their context is already done, with the value
+// canceled or deadline_exceeded. As the field is set when the entry is
+// logged, outputs, hooks and exporters writing entries later still tell the
+// operations that failed from those their caller abandoned.
+// This function is safe to use concurrently.
+func SetContextStatus(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.contextStatus, v)
+}
+
+// ctxStatus returns the ctx_err field for ctx, and whether it applies.
+func ctxStatus(ctx context.Context) (Field, bool) {
+	if atomic.LoadInt32(&logging.contextStatus) == 0 {
+		return Field{}, false
+	}
+	switch err := ctx.Err(); err {
+	case nil:
+		return Field{}, false
+	case context.Canceled:
+		return Field{ctxErrKey, "canceled"}, true
+	case context.DeadlineExceeded:
+		return Field{ctxErrKey, "deadline_exceeded"}, true
+	default:
+		return Field{ctxErrKey, err.Error()}, true
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContextStatus(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	InfoCtx(ctx, "off")
+	if !contains("] off\n") {
+		t.Errorf("status added while off: %q", contents())
+	}
+
+	SetContextStatus(true)
+	defer SetContextStatus(false)
+	logging.newBuffers()
+	InfoCtx(context.Background(), "live")
+	ErrorCtxf(NewContext(ctx, With(F("user", "ann"))), "query %s", "failed")
+	if !contains("] live\n") || !contains("] query failed user=ann ctx_err=canceled\n") {
+		t.Errorf("wrong status: %q", contents())
+	}
+	logging.newBuffers()
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	WarningCtx(ctx, "too late")
+	if !contains("] too late ctx_err=deadline_exceeded\n") {
+		t.Errorf("wrong status: %q", contents())
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// repeatedKey is the field of the entries summarizing repeated messages.
+const repeatedKey = "repeated"
+
+// deduper collapses identical consecutive entries, see SetDedupWindow.
+type deduper struct {
+	// window is the time.Duration repeats are collapsed within, zero if
+	// they are not. It is read and written using sync/atomic.
+	window int64
+
+	mu sync.Mutex
+	// last is the entry repeats are compared with, nil if there is none.
+	last *Entry
+	// repeats counts the entries dropped as repeats of last.
+	repeats int64
+	// timer emits the summary of the repeats once the window is over.
+	timer *time.Timer
+	// run counts the resets, so that a timer firing late is ignored.
+	run int64
+}
+
+// SetDedupWindow collapses identical consecutive entries logged within d of
+// the first one, like syslogd does: the first entry is written, and the
+// repeats are dropped and counted in Stats.Deduplicated until a different
+// entry is logged or d is over. A "last message repeated N times" entry of
+// the same severity and call site, with a repeated=N field, is then written.
+// Entries are identical when they have the same severity, call site and
+// message; their fields are not compared. Critical and Fatal entries are
+// never dropped. Zero, the default, disables deduplication; a positive
+// window collapses the floods of crash loops logging the same error
+// thousands of times per second.
+// This function is safe to use concurrently.
+func SetDedupWindow(d time.Duration) {
+	ensureInit()
+	if d < 0 {
+		d = 0
+	}
+	s := &logging.dedup
+	s.mu.Lock()
+	summary := s.reset()
+	atomic.StoreInt64(&s.window, int64(d))
+	s.mu.Unlock()
+	if summary != nil {
+		logging.emit(summary)
+	}
+}
+
+// dedup reports whether e is to be logged and, if repeats of the previous
+// entry were dropped, returns an entry summarizing them, to be logged
+// first.
+func (s *deduper) dedup(e *Entry) (bool, *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	window := time.Duration(atomic.LoadInt64(&s.window))
+	if window <= 0 {
+		return true, nil
+	}
+	if l := s.last; l != nil && e.Severity < CriticalLog && l.Severity == e.Severity &&
+		l.File == e.File && l.Line == e.Line && l.Message == e.Message && l.output == e.output &&
+		!e.Time.Before(l.Time) && e.Time.Sub(l.Time) < window {
+		s.repeats++
+		if s.timer == nil {
+			run := s.run
+			s.timer = time.AfterFunc(window-e.Time.Sub(l.Time), func() { s.expire(run) })
+		}
+		return false, nil
+	}
+	summary := s.reset()
+	s.last = &Entry{
+		Severity: e.Severity,
+		Time:     e.Time,
+		File:     e.File,
+		Line:     e.Line,
+		Message:  e.Message,
+		pc:       e.pc,
+		output:   e.output,
+	}
+	return true, summary
+}
+
+// expire writes the summary of the repeats once the window of run is over.
+func (s *deduper) expire(run int64) {
+	s.mu.Lock()
+	var summary *Entry
+	if s.run == run {
+		summary = s.reset()
+	}
+	s.mu.Unlock()
+	if summary != nil {
+		logging.emit(summary)
+	}
+}
+
+// flush writes the summary of the repeats dropped so far, if any.
+func (s *deduper) flush() {
+	s.mu.Lock()
+	summary := s.reset()
+	s.mu.Unlock()
+	if summary != nil {
+		logging.emit(summary)
+	}
+}
+
+// reset forgets the last entry and returns the summary of its repeats, if
+// any.
+// s.mu is held.
+func (s *deduper) reset() *Entry {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	l, n := s.last, s.repeats
+	s.last, s.repeats = nil, 0
+	s.run++
+	if n == 0 {
+		return nil
+	}
+	now := timeNow()
+	if now.Before(l.Time) {
+		now = l.Time
+	}
+	return &Entry{
+		Severity: l.Severity,
+		Time:     now,
+		File:     l.File,
+		Line:     l.Line,
+		Message:  "last message repeated " + strconv.FormatInt(n, 10) + " times",
+		Fields:   []Field{{repeatedKey, n}},
+		pc:       l.pc,
+		output:   l.output,
+	}
+}
+
+// setDedupWindow parses value as a duration and calls SetDedupWindow with
+// it, or zero if value is empty.
+func setDedupWindow(value string) error {
+	var d time.Duration
+	if value != "" {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil {
+			return err
+		}
+		if d < 0 {
+			return errors.New("negative dedup window")
+		}
+	}
+	SetDedupWindow(d)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupWindow(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetDedupWindow(time.Hour)
+	defer SetDedupWindow(0)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	before := atomic.LoadInt64(&Stats.Deduplicated)
+	for i := 0; i < 1000; i++ {
+		Error("disk full")
+	}
+	if got := strings.Count(contents(), "] disk full"); got != 1 {
+		t.Errorf("%d entries logged, want 1:\n%s", got, contents())
+	}
+	if got := atomic.LoadInt64(&Stats.Deduplicated) - before; got != 999 {
+		t.Errorf("Stats.Deduplicated grew by %d, want 999", got)
+	}
+	Info("recovered")
+	if !contains("] last message repeated 999 times repeated=999\n") {
+		t.Errorf("no summary of the repeats:\n%s", contents())
+	}
+	if i, j := strings.Index(contents(), "repeated 999"), strings.Index(contents(), "recovered"); i < 0 || i > j {
+		t.Error("summary not logged before the next entry")
+	}
+	if !strings.Contains(contents(), "E0101 00:00:00.000000") {
+		t.Errorf("summary without the severity of the repeats:\n%s", contents())
+	}
+
+	logging.newBuffers()
+	Warning("retrying")
+	now = now.Add(2 * time.Hour)
+	Warning("retrying")
+	if got := strings.Count(contents(), "] retrying"); got != 2 {
+		t.Errorf("%d entries logged over two windows, want 2:\n%s", got, contents())
+	}
+	Critical("down")
+	Critical("down")
+	if got := strings.Count(contents(), "] down"); got != 2 {
+		t.Errorf("Critical entries collapsed:\n%s", contents())
+	}
+}
+
+func TestDedupWindowExpiry(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetDedupWindow(20 * time.Millisecond)
+	defer SetDedupWindow(0)
+	for i := 0; i < 3; i++ {
+		Info("flapping")
+	}
+	summarized := func() bool {
+		logging.mu.Lock()
+		defer logging.mu.Unlock()
+		return contains("last message repeated 2 times")
+	}
+	for i := 0; !summarized() && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !summarized() {
+		t.Errorf("no summary once the window is over:\n%s", contents())
+	}
+}
+
+func TestDedupWindowConfig(t *testing.T) {
+	if err := setDedupWindow("-1s"); err == nil {
+		t.Error("negative window accepted")
+	}
+	if err := setDedupWindow("soon"); err == nil {
+		t.Error("invalid window accepted")
+	}
+}
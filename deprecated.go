@@ -0,0 +1,72 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// deprecations tracks the uses of deprecated features reported with
+// Deprecated.
+var deprecations struct {
+	mu     sync.Mutex
+	logged map[uintptr]bool // the call sites already logged
+	counts map[string]int64 // uses per feature
+}
+
+// Deprecated reports a use of a deprecated feature, naming its replacement
+// and the owner to contact about it. The first use from each call site is
+// logged at Warning with feature, replacement and owner fields; every use
+// is counted, see DeprecationCounts. This standardizes deprecation telemetry:
+//
+//	func OldAPI() {
+//		flog.Deprecated("OldAPI", "NewAPI", "storage-team")
+//		...
+//	}
+//
+// As in the example, the call site is the caller of the function calling
+// Deprecated, so each user of a deprecated function is reported.
+func Deprecated(feature, replacement, owner string) {
+	pc, file, line := caller(0)
+	deprecations.mu.Lock()
+	if deprecations.counts == nil {
+		deprecations.counts = make(map[string]int64)
+		deprecations.logged = make(map[uintptr]bool)
+	}
+	deprecations.counts[feature]++
+	logged := deprecations.logged[pc]
+	deprecations.logged[pc] = true
+	deprecations.mu.Unlock()
+	atomic.AddInt64(&Stats.Deprecations, 1)
+	if logged {
+		return
+	}
+	e := newEntry(WarningLog, pc, file, line, "deprecated feature used")
+	e.Fields = []Field{{"feature", feature}, {"replacement", replacement}, {"owner", owner}}
+	logging.output(e)
+}
+
+// DeprecationCounts returns the number of uses of each feature reported with
+// Deprecated.
+func DeprecationCounts() map[string]int64 {
+	deprecations.mu.Lock()
+	defer deprecations.mu.Unlock()
+	counts := make(map[string]int64, len(deprecations.counts))
+	for feature, n := range deprecations.counts {
+		counts[feature] = n
+	}
+	return counts
+}
@@ -0,0 +1,58 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func oldAPI() {
+	Deprecated("oldAPI", "newAPI", "storage-team")
+}
+
+// resetDeprecations forgets the call sites already logged and the uses
+// counted by Deprecated.
+func resetDeprecations() {
+	deprecations.mu.Lock()
+	deprecations.logged = nil
+	deprecations.counts = nil
+	deprecations.mu.Unlock()
+}
+
+func TestDeprecated(t *testing.T) {
+	resetDeprecations()
+	defer resetDeprecations()
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	uses := atomic.LoadInt64(&Stats.Deprecations)
+	for i := 0; i < 3; i++ {
+		oldAPI()
+	}
+	oldAPI()
+	if n := strings.Count(contents(), "] deprecated feature used feature=oldAPI replacement=newAPI owner=storage-team\n"); n != 2 {
+		t.Errorf("got %d warnings, want one per call site: %q", n, contents())
+	}
+	if !contains("deprecated_test.go:") || contents()[0] != 'W' {
+		t.Errorf("wrong call site or severity: %q", contents())
+	}
+	if n := atomic.LoadInt64(&Stats.Deprecations) - uses; n != 4 {
+		t.Errorf("Stats.Deprecations increased by %d, want 4", n)
+	}
+	if n := DeprecationCounts()["oldAPI"]; n != 4 {
+		t.Errorf("DeprecationCounts()[oldAPI] = %d, want 4", n)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+)
+
+// Encoder serializes entries for the output, in place of the format set with
+// SetFormat, for serializations flog doesn't provide, such as protobuf, CBOR
+// or internal wire formats. Encode appends e to buf, including any record
+// separator, such as a newline or a length prefix, as flog adds none. It may
+// be called concurrently, and must not log.
+type Encoder interface {
+	Encode(e Entry, buf *bytes.Buffer)
+}
+
+// EncoderFunc is a function used as an Encoder.
+type EncoderFunc func(e Entry, buf *bytes.Buffer)
+
+// Encode calls f(e, buf).
+func (f EncoderFunc) Encode(e Entry, buf *bytes.Buffer) {
+	f(e, buf)
+}
+
+// encoderHolder holds an Encoder in logging.encoder, as an atomic.Value
+// can't hold nil.
+type encoderHolder struct {
+	enc Encoder
+}
+
+// SetEncoder makes enc serialize the entries written to the output and to
+// the added outputs, instead of the format set with SetFormat. Entries
+// written with RawWrite are still written as is, and custom encoded entries are
+// never colored. A nil enc restores the format.
+// This function is safe to use concurrently.
+func SetEncoder(enc Encoder) {
+	ensureInit()
+	logging.encoder.Store(encoderHolder{enc})
+}
+
+// loadEncoder returns the Encoder set with SetEncoder, nil if none.
+func (l *loggingT) loadEncoder() Encoder {
+	holder, _ := l.encoder.Load().(encoderHolder)
+	return holder.enc
+}
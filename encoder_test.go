@@ -0,0 +1,48 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSetEncoder(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetColor(ColorAlways)
+	defer SetColor(ColorAuto)
+	SetEncoder(EncoderFunc(func(e Entry, buf *bytes.Buffer) {
+		rec := fmt.Sprintf("%s|%s:%d|%s", e.Severity.Name(), e.File, e.Line, e.Message)
+		for _, f := range e.Fields {
+			rec += fmt.Sprintf("|%s=%v", f.Key, f.Value)
+		}
+		fmt.Fprintf(buf, "%d:%s", len(rec), rec)
+	}))
+	defer SetEncoder(nil)
+	logging.printWithFileLine(WarningLog, "f.go", 3, []Field{{"user", "ann"}}, "denied")
+	RawWrite(InfoLog, []byte("raw line\n"))
+	if got, want := contents(), "30:WARNING|f.go:3|denied|user=ann"+"raw line\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	SetEncoder(nil)
+	logging.newBuffers()
+	Info("text")
+	if !contains("encoder_test.go:") || !contains(" text\n") {
+		t.Errorf("format not restored: %q", contents())
+	}
+}
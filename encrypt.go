@@ -0,0 +1,105 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// EncryptedWriter encrypts the log entries written to it with AES-GCM, for
+// sinks that carry logs across shared infrastructure, such as a collector
+// or a queue, which must not see the diagnostic payloads in the clear:
+//
+//	ew, err := flog.NewEncryptedWriter(conn, key)
+//	...
+//	flog.AddOutput(ew, flog.InfoLog)
+//
+// Every write, which holds one entry or, with atomic writes, a run of its
+// whole lines, is sealed on its own with a random nonce and written as a
+// line of standard base64, so the encrypted stream is still made of lines
+// that line-oriented transports handle. DecryptLine returns the entries of
+// such a line.
+type EncryptedWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+}
+
+// NewEncryptedWriter returns a writer encrypting to w with key, which is
+// 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewEncryptedWriter(w io.Writer, key []byte) (*EncryptedWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedWriter{w: w, aead: aead}, nil
+}
+
+// Write is part of the io.Writer interface. It writes the line holding the
+// encrypted p to the underlying writer at once.
+func (ew *EncryptedWriter) Write(p []byte) (int, error) {
+	ns := ew.aead.NonceSize()
+	sealed := make([]byte, ns, ns+len(p)+ew.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, sealed); err != nil {
+		return 0, err
+	}
+	sealed = ew.aead.Seal(sealed, sealed, p, nil)
+	line := make([]byte, base64.StdEncoding.EncodedLen(len(sealed))+1)
+	base64.StdEncoding.Encode(line, sealed)
+	line[len(line)-1] = '\n'
+	if _, err := ew.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// errBadCiphertext is returned by DecryptLine for lines that are not made by
+// an EncryptedWriter with the key.
+var errBadCiphertext = errors.New("flog: line is not encrypted with this key")
+
+// DecryptLine returns the entries an EncryptedWriter with key encrypted into
+// line, as written to the writer, trailing newline included.
+func DecryptLine(key, line []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	line = bytes.TrimSpace(line)
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+	n, err := base64.StdEncoding.Decode(sealed, line)
+	ns := aead.NonceSize()
+	if err != nil || n < ns {
+		return nil, errBadCiphertext
+	}
+	data, err := aead.Open(nil, sealed[:ns], sealed[ns:n], nil)
+	if err != nil {
+		return nil, errBadCiphertext
+	}
+	return data, nil
+}
+
+// newAEAD returns AES-GCM with key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedWriter(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	key := bytes.Repeat([]byte{7}, 32)
+	var sink bytes.Buffer
+	ew, err := NewEncryptedWriter(&sink, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddOutput(ew, InfoLog)
+	defer RemoveOutput(ew)
+	Info("password reset for alice")
+	Info("password reset for bob")
+
+	if strings.Contains(sink.String(), "password") {
+		t.Fatalf("sink holds the clear text: %q", sink.String())
+	}
+	var got []string
+	s := bufio.NewScanner(&sink)
+	for s.Scan() {
+		data, err := DecryptLine(key, s.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(data))
+	}
+	if len(got) != 2 || !strings.HasSuffix(got[0], "] password reset for alice\n") || !strings.HasSuffix(got[1], "] password reset for bob\n") {
+		t.Errorf("decrypted %q", got)
+	}
+	if got[0]+got[1] != contents() {
+		t.Errorf("decrypted %q, want the clear output %q", got, contents())
+	}
+}
+
+func TestDecryptLineWrongKey(t *testing.T) {
+	var sink bytes.Buffer
+	ew, err := NewEncryptedWriter(&sink, bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew.Write([]byte("secret\n"))
+	if _, err := DecryptLine(bytes.Repeat([]byte{2}, 16), sink.Bytes()); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+	if _, err := DecryptLine(bytes.Repeat([]byte{1}, 16), []byte("not base64!")); err == nil {
+		t.Error("decrypted garbage")
+	}
+	if _, err := NewEncryptedWriter(&sink, []byte("short")); err == nil {
+		t.Error("accepted a 5-byte key")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Keys of the fields WithError attaches.
+const (
+	errorKey      = "error"
+	errorTypeKey  = "error_type"
+	errorCauseKey = "error_cause"
+)
+
+// WithError returns a logger writing to the package's output that attaches
+// err to its entries as structured fields, so error logs can be searched by
+// error rather than by the text of the message:
+//
+//	flog.WithError(err).Errorf("can't load %s", name)
+//
+// The error field holds the message of err. The error_type field holds the
+// type of the innermost error err wraps, as found by following the Unwrap
+// methods, and if err wraps another error, the error_cause field holds the
+// message of that innermost error. If an error of the chain carries a stack
+// trace, through a StackTrace method as in github.com/pkg/errors or a
+// Stack method returning []byte, the innermost one is written after the
+// entries, as for -log_backtrace_at. A nil err attaches nothing.
+func WithError(err error) *Logger {
+	return defaultLogger.WithError(err)
+}
+
+// ErrorIf logs msg to the ERROR log, with err attached as described for
+// WithError, if err is not nil, and reports whether it did. It replaces the
+// usual boilerplate:
+//
+//	if flog.ErrorIf(os.Remove(tmp), "can't remove the temporary file") {
+//		leaked++
+//	}
+//
+// The entry is logged from the caller of ErrorIf.
+func ErrorIf(err error, msg string) bool {
+	if err == nil {
+		return false
+	}
+	defaultLogger.WithError(err).printDepth(ErrorLog, 0, msg)
+	return true
+}
+
+// FatalIf acts as ErrorIf but logs to the FATAL log, which exits the process
+// unless the exit policy says otherwise, see SetExitPolicy.
+func FatalIf(err error, msg string) bool {
+	if err == nil {
+		return false
+	}
+	defaultLogger.WithError(err).printDepth(FatalLog, 0, msg)
+	return true
+}
+
+// WithError returns a logger sharing the output and fields of lg that
+// attaches err to its entries, after the fields of lg, as described for the
+// WithError function.
+func (lg *Logger) WithError(err error) *Logger {
+	if err == nil {
+		return lg.With()
+	}
+	fields := []Field{{errorKey, err.Error()}}
+	cause, stack := err, errorStack(err)
+	for {
+		u, ok := cause.(interface{ Unwrap() error })
+		if !ok || u.Unwrap() == nil {
+			break
+		}
+		cause = u.Unwrap()
+		if s := errorStack(cause); s != nil {
+			stack = s
+		}
+	}
+	fields = append(fields, Field{errorTypeKey, fmt.Sprintf("%T", cause)})
+	if cause != err {
+		fields = append(fields, Field{errorCauseKey, cause.Error()})
+	}
+	child := lg.With(fields...)
+	if stack != nil {
+		child.stack = stack
+	}
+	return child
+}
+
+// errorStack returns the stack trace err carries, ending with a newline, or
+// nil if it doesn't carry one.
+func errorStack(err error) []byte {
+	if s, ok := err.(interface{ Stack() []byte }); ok {
+		return withNewline(s.Stack())
+	}
+	// The result of StackTrace has a type of its own, which prints the
+	// frames with %+v, so it can only be called through reflection.
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	trace := strings.TrimLeft(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()), "\n")
+	return withNewline([]byte(trace))
+}
+
+// withNewline returns b ending with a newline, or nil if it is empty.
+func withNewline(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	if b[len(b)-1] != '\n' {
+		b = append(b[:len(b):len(b)], '\n')
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// tracedError carries a stack trace the way github.com/pkg/errors does.
+type tracedError struct{ msg string }
+
+func (e *tracedError) Error() string { return e.msg }
+
+func (e *tracedError) StackTrace() traceFrames { return traceFrames{"main.load", "main.main"} }
+
+// traceFrames prints its frames on lines of their own with %+v.
+type traceFrames []string
+
+func (f traceFrames) Format(s fmt.State, verb rune) {
+	for _, name := range f {
+		fmt.Fprintf(s, "\n%s", name)
+	}
+}
+
+func TestWithError(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	err := &os.PathError{Op: "open", Path: "/etc/app.conf", Err: os.ErrPermission}
+	WithError(fmt.Errorf("loading config: %w", err)).Errorf("can't start %s", "server")
+	want := `] can't start server error="loading config: open /etc/app.conf: permission denied" error_type=*errors.errorString error_cause="permission denied"` + "\n"
+	if !strings.HasSuffix(contents(), want) {
+		t.Errorf("got %q, want suffix %q", contents(), want)
+	}
+}
+
+func TestWithErrorStack(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	err := fmt.Errorf("wrapped: %w", &tracedError{"boom"})
+	WithError(err).With(F("k", 1)).Error("failed")
+	if !contains(" error_type=*flog.tracedError error_cause=boom k=1\nmain.load\nmain.main\n") {
+		t.Errorf("got %q", contents())
+	}
+
+	logging.newBuffers()
+	WithError(errors.New("plain")).Error("failed")
+	if !strings.HasSuffix(contents(), "] failed error=plain error_type=*errors.errorString\n") {
+		t.Errorf("got %q", contents())
+	}
+	if len(WithError(nil).fields) != 0 {
+		t.Error("nil error attached fields")
+	}
+}
+
+func TestErrorIf(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if ErrorIf(nil, "nothing") {
+		t.Error("ErrorIf logged a nil error")
+	}
+	if !ErrorIf(errors.New("disk full"), "can't save") {
+		t.Error("ErrorIf didn't log")
+	}
+	if !contains("error_test.go:") || !contains(`] can't save error="disk full" error_type=*errors.errorString`+"\n") {
+		t.Errorf("wrong entry: %q", contents())
+	}
+
+	defer SetExitPolicy(GetExitPolicy())
+	SetExitPolicy(ExitPolicy{Fatal: ActionLog})
+	logging.newBuffers()
+	if FatalIf(nil, "nothing") || contents() != "" {
+		t.Error("FatalIf logged a nil error")
+	}
+	if !FatalIf(errors.New("corrupt"), "can't recover") || !contains("] can't recover error=corrupt") {
+		t.Errorf("wrong entry: %q", contents())
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FieldMap renames and drops the members of the entries written in the JSON
+// format, so that one binary can meet the schemas of several backends. It
+// maps member names, such as "message", "pid" or the keys of fields, to new
+// names, with an empty name dropping the member. Entries written in the text
+// format are left alone.
+type FieldMap map[string]string
+
+// ParseFieldMap parses a comma-separated list of from=to pairs, where an
+// empty to drops the member from:
+//
+//	message=msg,timestamp=ts,pid=
+func ParseFieldMap(spec string) (FieldMap, error) {
+	m := FieldMap{}
+	for _, pair := range strings.Split(spec, ",") {
+		if pair == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, errors.New("syntax error: expect comma-separated list of from=to")
+		}
+		m[pair[:i]] = pair[i+1:]
+	}
+	return m, nil
+}
+
+// apply returns data, an encoded entry, with its members mapped. Data that
+// isn't a JSON object is returned as is.
+func (m FieldMap) apply(data []byte) []byte {
+	if len(m) == 0 || len(data) == 0 || data[0] != '{' {
+		return data
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	if _, err := d.Token(); err != nil {
+		return data
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for d.More() {
+		t, err := d.Token()
+		key, ok := t.(string)
+		var value json.RawMessage
+		if err != nil || !ok || d.Decode(&value) != nil {
+			return data
+		}
+		if to, ok := m[key]; ok {
+			if to == "" {
+				continue
+			}
+			key = to
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Quote(key))
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// mappedWriter is a writer applying a FieldMap.
+type mappedWriter struct {
+	w io.Writer
+	m FieldMap
+}
+
+// mappedSeverityWriter is a mappedWriter for a SeverityWriter.
+type mappedSeverityWriter struct {
+	mappedWriter
+}
+
+// MapFields returns a writer applying m to the entries written to w, for
+// use with AddOutput, SetOutput or WithOutput.
+func MapFields(w io.Writer, m FieldMap) io.Writer {
+	if _, ok := w.(SeverityWriter); ok {
+		return &mappedSeverityWriter{mappedWriter{w, m}}
+	}
+	return &mappedWriter{w, m}
+}
+
+func (w *mappedWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write(w.m.apply(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteSeverity is part of the SeverityWriter interface, so that mapping
+// the fields of entries doesn't lose their severities.
+func (w *mappedSeverityWriter) WriteSeverity(s Severity, p []byte) (int, error) {
+	if _, err := writeSeverity(w.w, s, w.m.apply(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// setFieldMap sets the FieldMap applied to the output, parsed from spec.
+func setFieldMap(spec string) error {
+	m, err := ParseFieldMap(spec)
+	if err != nil {
+		return err
+	}
+	if len(m) == 0 {
+		m = nil
+	}
+	logging.fieldMap.Store(m)
+	return nil
+}
+
+// loadFieldMap returns the FieldMap applied to the output, nil if none.
+func (l *loggingT) loadFieldMap() FieldMap {
+	m, _ := l.fieldMap.Load().(FieldMap)
+	return m
+}
@@ -0,0 +1,61 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFieldMap(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	if err := setFieldMap("message=msg,pid="); err != nil {
+		t.Fatal(err)
+	}
+	defer setFieldMap("")
+	var other bytes.Buffer
+	m, err := ParseFieldMap("user=uid,line=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := MapFields(&other, m)
+	AddOutput(w, InfoLog)
+	defer RemoveOutput(w)
+
+	With(F("user", "ann")).Info("hi")
+	if got := contents(); !strings.Contains(got, `"msg":"hi"`) || strings.Contains(got, `"pid"`) || !strings.Contains(got, `"user":"ann"}`) {
+		t.Errorf("output %s, want msg and no pid", got)
+	}
+	if got := other.String(); !strings.Contains(got, `"message":"hi"`) || strings.Contains(got, `"line"`) || !strings.Contains(got, `"uid":"ann"}`) || !strings.HasSuffix(got, "}\n") {
+		t.Errorf("added output %s, want uid and no line", got)
+	}
+}
+
+func TestParseFieldMapError(t *testing.T) {
+	var out bytes.Buffer
+	configErrorOutput = &out
+	defer func() { configErrorOutput = os.Stderr }()
+	if err := (&Config{FieldMap: "=msg"}).Set(); err == nil {
+		t.Error("Config.Set accepted a bad field map")
+	}
+	if _, err := ParseFieldMap("message"); err == nil {
+		t.Error("ParseFieldMap accepted a pair without =")
+	}
+}
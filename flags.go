@@ -18,9 +18,15 @@
 package flog
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // getEnvDefString returns the value of the env var key or defVal if that var
@@ -32,30 +38,255 @@ func getEnvDefString(key, defVal string) string {
 	return defVal
 }
 
-// We initiliaze the lib here using whatever values we got in our env vars.
+// init only sets up what needs no environment, the env vars are read on
+// first use.
 func init() {
 	logging.out = os.Stderr
+	logging.outLatency = new(latencyTracker)
 	logging.freeList = &sync.Pool{
 		New: func() interface{} {
 			return new(buffer)
 		},
 	}
+}
 
+// initConfig applies the env vars. It runs once, on first use, see Init.
+func initConfig() {
 	// Pick values from env vars or set sane defaults
 	logBacktrace := getEnvDefString("FLOG_LOG_BACKTRACE_AT", "")
-	logging.traceLocation.Set(logBacktrace)
+	checkConfig("FLOG_LOG_BACKTRACE_AT", logBacktrace, logging.traceLocation.Set(logBacktrace))
+
+	errorStackCooldown := getEnvDefString("FLOG_ERROR_STACK_COOLDOWN", "")
+	checkConfig("FLOG_ERROR_STACK_COOLDOWN", errorStackCooldown, logging.errorStacks.Set(errorStackCooldown))
 
+	stackTraceLevel := getEnvDefString("FLOG_STACK_TRACE_LEVEL", "")
+	checkConfig("FLOG_STACK_TRACE_LEVEL", stackTraceLevel, logging.stackTraceLevel.Set(stackTraceLevel))
+
+	// Level names can't have been registered yet, so unknown ones are kept
+	// for RegisterLevelName rather than reported.
 	vmoduleSpec := getEnvDefString("FLOG_VMODULE", "")
-	logging.vmodule.Set(vmoduleSpec)
+	if err := logging.vmodule.Set(vmoduleSpec); err == errUnknownLevel {
+		levelNames.pendingVmodule = vmoduleSpec
+	} else {
+		checkConfig("FLOG_VMODULE", vmoduleSpec, err)
+	}
+
+	vloggerSpec := getEnvDefString("FLOG_VLOGGER", "")
+	checkConfig("FLOG_VLOGGER", vloggerSpec, logging.vlogger.Set(vloggerSpec))
 
 	v := getEnvDefString("FLOG_VERBOSITY", "0")
-	logging.verbosity.Set(v)
+	if err := logging.verbosity.Set(v); err == errUnknownLevel {
+		levelNames.pendingV = v
+	} else {
+		checkConfig("FLOG_VERBOSITY", v, err)
+	}
+
+	if threshold := getEnvDefString("FLOG_SLOW_OUTPUT_THRESHOLD", ""); threshold != "" {
+		d, err := time.ParseDuration(threshold)
+		if err == nil {
+			SetSlowOutputThreshold(d)
+		}
+		checkConfig("FLOG_SLOW_OUTPUT_THRESHOLD", threshold, err)
+	}
+
+	if budget := getEnvDefString("FLOG_MEMORY_BUDGET", ""); budget != "" {
+		limit, err := parseByteSize(budget)
+		if err == nil {
+			SetMemoryBudget(limit)
+		}
+		checkConfig("FLOG_MEMORY_BUDGET", budget, err)
+	}
+
+	// Plugins can't have been registered yet either.
+	pluginList := getEnvDefString("FLOG_PLUGINS", "")
+	if err := enabledPlugins.Set(pluginList); err != nil {
+		if _, ok := err.(unknownPluginError); ok {
+			plugins.pending = pluginList
+		} else {
+			checkConfig("FLOG_PLUGINS", pluginList, err)
+		}
+	}
+
+	fieldMap := getEnvDefString("FLOG_FIELD_MAP", "")
+	checkConfig("FLOG_FIELD_MAP", fieldMap, setFieldMap(fieldMap))
+
+	output := getEnvDefString("FLOG_OUTPUT", "")
+	checkConfig("FLOG_OUTPUT", output, setOutputName(output))
+
+	minSeverity := getEnvDefString("FLOG_MIN_SEVERITY", "")
+	checkConfig("FLOG_MIN_SEVERITY", minSeverity, logging.minSeverity.Set(minSeverity))
+
+	format := getEnvDefString("FLOG_FORMAT", "")
+	checkConfig("FLOG_FORMAT", format, logging.format.Set(format))
+
+	if mono := getEnvDefString("FLOG_MONOTONIC", ""); mono != "" {
+		on, err := strconv.ParseBool(mono)
+		if err == nil {
+			SetMonotonicTimestamps(on)
+		}
+		checkConfig("FLOG_MONOTONIC", mono, err)
+	}
+
+	if atomicWrites := getEnvDefString("FLOG_ATOMIC_WRITES", ""); atomicWrites != "" {
+		on, err := strconv.ParseBool(atomicWrites)
+		if err == nil {
+			SetAtomicWrites(on)
+		}
+		checkConfig("FLOG_ATOMIC_WRITES", atomicWrites, err)
+	}
+
+	timeFormat := getEnvDefString("FLOG_TIME_FORMAT", "")
+	checkConfig("FLOG_TIME_FORMAT", timeFormat, logging.timeFormat.Set(timeFormat))
+
+	color := getEnvDefString("FLOG_COLOR", "")
+	checkConfig("FLOG_COLOR", color, logging.color.Set(color))
+
+	if utc := getEnvDefString("FLOG_UTC", ""); utc != "" {
+		on, err := strconv.ParseBool(utc)
+		if err == nil {
+			SetUTC(on)
+		}
+		checkConfig("FLOG_UTC", utc, err)
+	}
+
+	if status := getEnvDefString("FLOG_CONTEXT_STATUS", ""); status != "" {
+		on, err := strconv.ParseBool(status)
+		if err == nil {
+			SetContextStatus(on)
+		}
+		checkConfig("FLOG_CONTEXT_STATUS", status, err)
+	}
+
+	if ids := getEnvDefString("FLOG_GOROUTINE_IDS", ""); ids != "" {
+		on, err := strconv.ParseBool(ids)
+		if err == nil {
+			SetGoroutineIDs(on)
+		}
+		checkConfig("FLOG_GOROUTINE_IDS", ids, err)
+	}
+
+	if prefix := getEnvDefString("FLOG_SEVERITY_PREFIX", ""); prefix != "" {
+		on, err := strconv.ParseBool(prefix)
+		if err == nil {
+			SetSeverityPrefix(on)
+		}
+		checkConfig("FLOG_SEVERITY_PREFIX", prefix, err)
+	}
+
+	if prefix := getEnvDefString("FLOG_LINE_PREFIX", ""); prefix != "" {
+		checkConfig("FLOG_LINE_PREFIX", prefix, SetLinePrefix(prefix))
+	}
+
+	if cache := getEnvDefString("FLOG_TIMESTAMP_CACHE", ""); cache != "" {
+		on, err := strconv.ParseBool(cache)
+		if err == nil {
+			SetTimestampCache(on)
+		}
+		checkConfig("FLOG_TIMESTAMP_CACHE", cache, err)
+	}
+
+	if tags := getEnvDefString("FLOG_JSON_TYPE_TAGS", ""); tags != "" {
+		on, err := strconv.ParseBool(tags)
+		if err == nil {
+			SetJSONTypeTags(on)
+		}
+		checkConfig("FLOG_JSON_TYPE_TAGS", tags, err)
+	}
+
+	if levels := getEnvDefString("FLOG_JSON_LEVELS", ""); levels != "" {
+		m, err := parseJSONLevels(levels)
+		if err == nil {
+			SetJSONLevels(m)
+		}
+		checkConfig("FLOG_JSON_LEVELS", levels, err)
+	}
+
+	if decimals := getEnvDefString("FLOG_FLOAT_DECIMALS", ""); decimals != "" {
+		n, err := strconv.Atoi(decimals)
+		if err == nil {
+			SetFloatDecimals(n)
+		}
+		checkConfig("FLOG_FLOAT_DECIMALS", decimals, err)
+	}
+
+	if rate := getEnvDefString("FLOG_SAMPLE_RATE", ""); rate != "" {
+		n, err := strconv.Atoi(rate)
+		if err == nil && n < 0 {
+			err = errors.New("negative sample rate")
+		}
+		if err == nil {
+			SetSampleRate(n)
+		}
+		checkConfig("FLOG_SAMPLE_RATE", rate, err)
+	}
+
+	if window := getEnvDefString("FLOG_DEDUP_WINDOW", ""); window != "" {
+		checkConfig("FLOG_DEDUP_WINDOW", window, setDedupWindow(window))
+	}
+
+	if threshold := getEnvDefString("FLOG_SUMMARY_THRESHOLD", ""); threshold != "" {
+		n, err := parseByteSize(threshold)
+		if err == nil {
+			SetSummaryThreshold(int(n))
+		}
+		checkConfig("FLOG_SUMMARY_THRESHOLD", threshold, err)
+	}
+
+	if endpoint := getEnvDefString("FLOG_OTLP_ENDPOINT", ""); endpoint != "" {
+		checkConfig("FLOG_OTLP_ENDPOINT", endpoint, setOTLPEndpoint(endpoint))
+	}
+
+	if patterns := getEnvDefString("FLOG_REDACT_FIELDS", ""); patterns != "" {
+		checkConfig("FLOG_REDACT_FIELDS", patterns, setRedactFields(patterns))
+	}
+
+	if expr := getEnvDefString("FLOG_REDACT_PATTERN", ""); expr != "" {
+		checkConfig("FLOG_REDACT_PATTERN", expr, RedactPattern("redact_pattern", expr))
+	}
+
+	if path := getEnvDefString("FLOG_COUNTER_FILE", ""); path != "" {
+		SetCounterFile(path)
+	}
+
+	// Last, so that the saved settings override those of the env vars.
+	if path := getEnvDefString("FLOG_STATE_FILE", ""); path != "" {
+		SetStateFile(path)
+	}
+}
+
+// ConfigErrorPrefix starts the lines written when a setting is invalid. The
+// prefix is followed by a space and a JSON object naming the setting, its
+// value and the error:
+//
+//	FLOG_CONFIG_ERROR {"setting":"FLOG_VMODULE","value":"gfs*=x","error":"..."}
+//
+// These lines are always written to stderr, whatever the output and the
+// verbosity, so that tooling can detect broken logging configurations at
+// startup instead of discovering missing logs later.
+const ConfigErrorPrefix = "FLOG_CONFIG_ERROR"
+
+var configErrorOutput io.Writer = os.Stderr // Stubbed out for testing.
+
+// checkConfig reports err, if not nil, as an error in the value of setting.
+func checkConfig(setting, value string, err error) error {
+	if err == nil {
+		return nil
+	}
+	detail, _ := json.Marshal(struct {
+		Setting string `json:"setting"`
+		Value   string `json:"value"`
+		Error   string `json:"error"`
+	}{setting, value, err.Error()})
+	fmt.Fprintf(configErrorOutput, "%s %s\n", ConfigErrorPrefix, detail)
+	return err
 }
 
 // AddFlags allows the caller to add the flags for configuring this module
 // to the specified FlagSet which can then be used to arbitrary flag libs.
 // For the Go flag lib use flag.CommandLine.
 // If defaults is not nil this function will first call its Set() method.
+// Only registering the flags doesn't apply the env vars, see Init: that is
+// done once a flag is set.
 func AddFlags(fs *flag.FlagSet, defaults *Config) error {
 	if defaults != nil {
 		if err := defaults.Set(); err != nil {
@@ -64,7 +295,15 @@ func AddFlags(fs *flag.FlagSet, defaults *Config) error {
 	}
 	fs.Var(&logging.verbosity, "v", "log level for V logs")
 	fs.Var(&logging.vmodule, "vmodule", "comma-separated list of pattern=N settings for file-filtered logging")
-	fs.Var(&logging.traceLocation, "log_backtrace_at", "when logging hits line file:N, emit a stack trace")
+	fs.Var(&logging.vlogger, "vlogger", "comma-separated list of name=N settings for named loggers")
+	fs.Var(&logging.traceLocation, "log_backtrace_at", "when logging hits line file:N or function pkg.Func, emit a stack trace")
+	fs.Var(&logging.minSeverity, "log_min_severity", "severity below which entries are dropped, such as WARNING")
+	fs.Var(&logging.format, "log_format", "format of the log entries: text, json or logfmt")
+	fs.Var(&logging.timeFormat, "log_time_format", "format of the timestamps of the text format: glog or rfc3339")
+	fs.Var(&logging.color, "log_color", "when to color the headers of the text format: auto, always or never")
+	fs.Var(&enabledPlugins, "log_plugins", "comma-separated list of the registered plugins to enable, in order")
+	fs.Var(&logging.errorStacks, "error_stack_cooldown", "emit a stack trace with the first error from each call site, then again once this duration has passed")
+	fs.Var(&logging.stackTraceLevel, "stack_trace_level", "emit a stack trace with every log of this severity or above, such as CRITICAL")
 
 	return nil
 }
@@ -91,19 +330,116 @@ func FlagsWithDefaults(defaults *Config) (*flag.FlagSet, error) {
 // Config struct provides an alternative way to configure this lib.
 // Callers must call the Set() method once defining the values.
 type Config struct {
-	Verbosity     string
-	Vmodule       string
-	TraceLocation string
+	Verbosity          string
+	Vmodule            string
+	Vlogger            string
+	TraceLocation      string
+	ErrorStackCooldown string
+	StackTraceLevel    string
+	MinSeverity        string
+	Format             string
+	TimeFormat         string
+	Plugins            string
+	// Color tells when the headers of the text format are colored, as for
+	// -log_color: auto, the default, always or never.
+	Color string
+	// LinePrefix is written at the start of every line of the text format,
+	// see SetLinePrefix.
+	LinePrefix string
+	// LogFile, if not empty, makes entries go to this file instead of
+	// stderr. The file is rotated once it would exceed MaxSizeMB megabytes,
+	// if positive, keeping MaxBackups previous files, see RotatingFile.
+	LogFile    string
+	MaxSizeMB  int
+	MaxBackups int
+	// Syslog makes entries go to the local syslog daemon instead of stderr,
+	// see SyslogWriter. It can't be combined with LogFile.
+	Syslog bool
+	// UTC makes entries carry their time in UTC, see SetUTC.
+	UTC bool
+	// FieldMap renames and drops the members of the entries written to the
+	// output in the JSON format, as parsed by ParseFieldMap.
+	FieldMap string
+	// SampleRate, if positive, is the number of entries each call site may
+	// log per second, see SetSampleRate.
+	SampleRate int
+	// DedupWindow, if not empty, is the duration identical consecutive
+	// entries are collapsed within, such as "10s", see SetDedupWindow.
+	DedupWindow string
+	// OTLPEndpoint, if not empty, is the URL of an OpenTelemetry collector
+	// entries are exported to, see OTLPExporter.
+	OTLPEndpoint string
 }
 
 // Set sets the configuration for the lib using the values of the struct.
 // This function is safe to use concurrently.
+// Invalid values are also reported as described for ConfigErrorPrefix.
 func (c *Config) Set() error {
+	ensureInit()
 	if err := logging.vmodule.Set(c.Vmodule); err != nil {
-		return err
+		return checkConfig("Config.Vmodule", c.Vmodule, err)
+	}
+	if err := logging.vlogger.Set(c.Vlogger); err != nil {
+		return checkConfig("Config.Vlogger", c.Vlogger, err)
 	}
 	if err := logging.traceLocation.Set(c.TraceLocation); err != nil {
-		return err
+		return checkConfig("Config.TraceLocation", c.TraceLocation, err)
+	}
+	if err := logging.errorStacks.Set(c.ErrorStackCooldown); err != nil {
+		return checkConfig("Config.ErrorStackCooldown", c.ErrorStackCooldown, err)
+	}
+	if err := logging.stackTraceLevel.Set(c.StackTraceLevel); err != nil {
+		return checkConfig("Config.StackTraceLevel", c.StackTraceLevel, err)
+	}
+	if err := logging.minSeverity.Set(c.MinSeverity); err != nil {
+		return checkConfig("Config.MinSeverity", c.MinSeverity, err)
+	}
+	if err := logging.format.Set(c.Format); err != nil {
+		return checkConfig("Config.Format", c.Format, err)
+	}
+	if err := logging.timeFormat.Set(c.TimeFormat); err != nil {
+		return checkConfig("Config.TimeFormat", c.TimeFormat, err)
+	}
+	if err := logging.color.Set(c.Color); err != nil {
+		return checkConfig("Config.Color", c.Color, err)
+	}
+	if err := SetLinePrefix(c.LinePrefix); err != nil {
+		return checkConfig("Config.LinePrefix", c.LinePrefix, err)
+	}
+	SetUTC(c.UTC)
+	if err := enabledPlugins.Set(c.Plugins); err != nil {
+		return checkConfig("Config.Plugins", c.Plugins, err)
+	}
+	if err := setFieldMap(c.FieldMap); err != nil {
+		return checkConfig("Config.FieldMap", c.FieldMap, err)
+	}
+	if c.SampleRate < 0 {
+		return checkConfig("Config.SampleRate", strconv.Itoa(c.SampleRate), errors.New("negative sample rate"))
+	}
+	SetSampleRate(c.SampleRate)
+	if err := setDedupWindow(c.DedupWindow); err != nil {
+		return checkConfig("Config.DedupWindow", c.DedupWindow, err)
+	}
+	if err := setOTLPEndpoint(c.OTLPEndpoint); err != nil {
+		return checkConfig("Config.OTLPEndpoint", c.OTLPEndpoint, err)
+	}
+	if c.Syslog && c.LogFile != "" {
+		return checkConfig("Config.Syslog", "true", errors.New("Syslog and LogFile are exclusive"))
+	}
+	// Stop the output being replaced before starting the new one, so that
+	// stopping it doesn't restore stderr.
+	if !c.Syslog {
+		if err := setSyslog(false); err != nil {
+			return checkConfig("Config.Syslog", "false", err)
+		}
+	}
+	if err := setLogFile(c.LogFile, c.MaxSizeMB, c.MaxBackups); err != nil {
+		return checkConfig("Config.LogFile", c.LogFile, err)
+	}
+	if c.Syslog {
+		if err := setSyslog(true); err != nil {
+			return checkConfig("Config.Syslog", "true", err)
+		}
 	}
-	return logging.verbosity.Set(c.Verbosity)
+	return checkConfig("Config.Verbosity", c.Verbosity, logging.verbosity.Set(c.Verbosity))
 }
@@ -22,7 +22,21 @@
 //			-log_backtrace_at=gopherflakes.go:234
 //		a stack trace will be written to the Info log whenever execution
 //		hits that statement. (Unlike with -vmodule, the ".go" must be
-//		present.)
+//		present.) A function name qualified by its package, such as
+//			-log_backtrace_at=gopher.Flake
+//		may be given instead, in which case every logging statement
+//		inside that function emits a stack trace.
+//	-error_stack_cooldown=""
+//		When set to a duration, such as
+//			-error_stack_cooldown=10m
+//		the first Error or Critical log from each call site includes a
+//		stack trace. Later logs from the same call site omit it until the
+//		duration has passed since the last trace.
+//	-stack_trace_level=""
+//		When set to a severity, such as
+//			-stack_trace_level=CRITICAL
+//		every log of that severity or above, Fatal aside, includes a
+//		stack trace.
 //	-v=0
 //		Enable V-leveled logging at the specified level.
 //	-vmodule=""
@@ -31,6 +45,12 @@
 //		"glob" pattern and N is a V level. For instance,
 //			-vmodule=gopher*=3
 //		sets the V level to 3 in all Go files whose names begin "gopher".
+//		A pattern containing a slash is matched against the import path
+//		of the package followed by a slash and the file name instead, so
+//			-vmodule=github.com/gopher/flakes/*=3
+//		sets it in the files of that package only. A pattern ending in
+//		"/..." matches the packages below a literal import path prefix:
+//			-vmodule=github.com/gopher/...=2
 //
 package flog
 
@@ -50,59 +70,119 @@ import (
 	"time"
 )
 
-// severity identifies the sort of log: info, warning etc. It also implements
+// Severity identifies the sort of log: info, warning etc. It also implements
 // the flag.Value interface.  The values match the corresponding constants
 // in C++.
-type severity int32 // sync/atomic int32
+type Severity int32 // sync/atomic int32
 
 // These constants identify the log levels in order of increasing severity.
 // A message written to a high-severity log file is also written to each
 // lower-severity log file.
 const (
-	debugLog severity = iota
-	infoLog
-	warningLog
-	errorLog
-	criticalLog
-	fatalLog
+	DebugLog Severity = iota
+	InfoLog
+	WarningLog
+	ErrorLog
+	CriticalLog
+	FatalLog
 	numSeverity = 6
 )
 
 const severityChar = "VIWECF"
 
 var (
-	pid = os.Getpid()
+	// pid is the process ID written with the entries, zero until first
+	// used. It is read and written using sync/atomic, as stampClock
+	// refreshes it after a fork.
+	pid int64
 )
 
 var severityName = []string{
-	debugLog:    "DEBUG",
-	infoLog:     "INFO",
-	warningLog:  "WARNING",
-	errorLog:    "ERROR",
-	criticalLog: "CRITICAL",
-	fatalLog:    "FATAL",
+	DebugLog:    "DEBUG",
+	InfoLog:     "INFO",
+	WarningLog:  "WARNING",
+	ErrorLog:    "ERROR",
+	CriticalLog: "CRITICAL",
+	FatalLog:    "FATAL",
 }
 
 // get returns the value of the severity.
-func (s *severity) get() severity {
-	return severity(atomic.LoadInt32((*int32)(s)))
+func (s *Severity) get() Severity {
+	return Severity(atomic.LoadInt32((*int32)(s)))
 }
 
 // set sets the value of the severity.
-func (s *severity) set(val severity) {
+func (s *Severity) set(val Severity) {
 	atomic.StoreInt32((*int32)(s), int32(val))
 }
 
-// String is part of the flag.Value interface.
-func (s *severity) String() string {
-	return strconv.FormatInt(int64(*s), 10)
+// String is part of the flag.Value and fmt.Stringer interfaces. It returns
+// the name of the severity, such as WARNING.
+func (s Severity) String() string {
+	return s.Name()
+}
+
+// Get is part of the flag.Value interface.
+func (s *Severity) Get() interface{} {
+	return s.get()
 }
 
-func severityByName(s string) (severity, bool) {
+// Set is part of the flag.Value interface.
+// The value is as for ParseSeverity; an empty value selects DEBUG.
+func (s *Severity) Set(value string) error {
+	ensureInit()
+	if value == "" {
+		s.set(DebugLog)
+		return nil
+	}
+	v, err := ParseSeverity(value)
+	if err != nil {
+		return err
+	}
+	s.set(v)
+	return nil
+}
+
+// MarshalText is part of the encoding.TextMarshaler interface, so that
+// severities appear by name in JSON and other text formats.
+func (s Severity) MarshalText() ([]byte, error) {
+	if s < 0 || s >= numSeverity {
+		return nil, errUnknownSeverity
+	}
+	return []byte(severityName[s]), nil
+}
+
+// UnmarshalText is part of the encoding.TextUnmarshaler interface. The
+// text is as for ParseSeverity.
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+var errUnknownSeverity = errors.New("unknown severity: expect a name such as WARNING or a number")
+
+// ParseSeverity returns the severity named name, such as WARNING, in any
+// case, or numbered name, such as 2.
+func ParseSeverity(name string) (Severity, error) {
+	if v, ok := severityByName(name); ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(name)
+	if err != nil || v < 0 || v >= numSeverity {
+		return 0, errUnknownSeverity
+	}
+	return Severity(v), nil
+}
+
+func severityByName(s string) (Severity, bool) {
 	s = strings.ToUpper(s)
 	for i, name := range severityName {
 		if name == s {
-			return severity(i), true
+			return Severity(i), true
 		}
 	}
 	return 0, false
@@ -112,6 +192,10 @@ func severityByName(s string) (severity, bool) {
 type OutputStats struct {
 	lines int64
 	bytes int64
+	// savedLines and savedBytes are the counts of the previous runs, see
+	// SetCounterFile.
+	savedLines int64
+	savedBytes int64
 }
 
 // Lines returns the number of lines written.
@@ -128,14 +212,47 @@ func (s *OutputStats) Bytes() int64 {
 // per severity level. Values must be read with atomic.LoadInt64.
 var Stats struct {
 	Debug, Info, Warning, Error, Critical OutputStats
+	// SlowOutputWarnings counts the warnings about slow outputs, see
+	// SetSlowOutputThreshold.
+	SlowOutputWarnings int64
+	// Suppressed counts the entries dropped by Suppress.
+	Suppressed int64
+	// ClockJumps counts the steps of the wall clock detected between
+	// entries, see stampClock.
+	ClockJumps int64
+	// VChecks counts the V calls observed by SetVStats, and VPasses
+	// those that were enabled.
+	VChecks, VPasses int64
+	// PidChanges counts the changes of the process ID detected between
+	// entries, as in a child that kept running after a fork.
+	PidChanges int64
+	// Deprecations counts the uses of deprecated features reported with
+	// Deprecated.
+	Deprecations int64
+	// Sampled counts the entries dropped by the sampler, see SetSampleRate.
+	Sampled int64
+	// AsyncDropped counts the entries dropped because the queue of the
+	// asynchronous mode was full, see SetAsync.
+	AsyncDropped int64
+	// AlertsFired counts the times alert rules fired, see AddAlertRule.
+	AlertsFired int64
+	// SubscriberDropped counts the entries dropped because a subscriber
+	// fell behind, see Subscribe.
+	SubscriberDropped int64
+	// Deduplicated counts the entries dropped as repeats of the previous
+	// one, see SetDedupWindow.
+	Deduplicated int64
+	// ContextDropped counts the entries dropped because the line budget of
+	// their context was exhausted, see LimitContext.
+	ContextDropped int64
 }
 
 var severityStats = [numSeverity]*OutputStats{
-	debugLog:    &Stats.Debug,
-	infoLog:     &Stats.Info,
-	warningLog:  &Stats.Warning,
-	errorLog:    &Stats.Error,
-	criticalLog: &Stats.Critical,
+	DebugLog:    &Stats.Debug,
+	InfoLog:     &Stats.Info,
+	WarningLog:  &Stats.Warning,
+	ErrorLog:    &Stats.Error,
+	CriticalLog: &Stats.Critical,
 }
 
 // Level is exported because it appears in the arguments to V and is
@@ -173,14 +290,17 @@ func (l *Level) Get() interface{} {
 }
 
 // Set is part of the flag.Value interface.
+// The value is a number or a name registered with RegisterLevelName.
 func (l *Level) Set(value string) error {
-	v, err := strconv.Atoi(value)
+	ensureInit()
+	v, err := parseLevel(value)
 	if err != nil {
 		return err
 	}
 	logging.mu.Lock()
 	defer logging.mu.Unlock()
-	logging.setVState(Level(v), logging.vmodule.filter, false)
+	logging.setVState(v, logging.vmodule.filter, false)
+	clearPending(&levelNames.pendingV)
 	return nil
 }
 
@@ -197,9 +317,19 @@ type modulePat struct {
 	level   Level
 }
 
+// hasPath reports whether the pattern matches the import path of the
+// package along with the file name.
+func (m *modulePat) hasPath() bool {
+	return strings.Contains(m.pattern, "/")
+}
+
 // match reports whether the file matches the pattern. It uses a string
-// comparison if the pattern contains no metacharacters.
+// comparison if the pattern contains no metacharacters, and a prefix
+// comparison if the pattern ends in "/...".
 func (m *modulePat) match(file string) bool {
+	if strings.HasSuffix(m.pattern, "/...") {
+		return strings.HasPrefix(file, m.pattern[:len(m.pattern)-3])
+	}
 	if m.literal {
 		return file == m.pattern
 	}
@@ -211,8 +341,13 @@ func (m *moduleSpec) String() string {
 	// Lock because the type is not atomic. TODO: clean this up.
 	logging.mu.Lock()
 	defer logging.mu.Unlock()
+	return formatModulePats(m.filter)
+}
+
+// formatModulePats returns filter in the syntax of the -vmodule flag.
+func formatModulePats(filter []modulePat) string {
 	var b bytes.Buffer
-	for i, f := range m.filter {
+	for i, f := range filter {
 		if i > 0 {
 			b.WriteRune(',')
 		}
@@ -230,8 +365,30 @@ func (m *moduleSpec) Get() interface{} {
 var errVmoduleSyntax = errors.New("syntax error: expect comma-separated list of filename=N")
 
 // Syntax: -vmodule=recordio=2,file=1,gfs*=3
+// Levels may also be given by a name registered with RegisterLevelName.
 func (m *moduleSpec) Set(value string) error {
+	ensureInit()
+	pats, err := parseModulePats(value)
+	if err != nil {
+		return err
+	}
 	var filter []modulePat
+	for _, pat := range pats {
+		if pat.level == 0 {
+			continue // Ignore. It's harmless but no point in paying the overhead.
+		}
+		filter = append(filter, pat)
+	}
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.setVState(logging.verbosity, filter, true)
+	clearPending(&levelNames.pendingVmodule)
+	return nil
+}
+
+// parseModulePats parses a comma-separated list of pattern=N settings.
+func parseModulePats(value string) ([]modulePat, error) {
+	var pats []modulePat
 	for _, pat := range strings.Split(value, ",") {
 		if len(pat) == 0 {
 			// Empty strings such as from a trailing comma can be ignored.
@@ -239,26 +396,20 @@ func (m *moduleSpec) Set(value string) error {
 		}
 		patLev := strings.Split(pat, "=")
 		if len(patLev) != 2 || len(patLev[0]) == 0 || len(patLev[1]) == 0 {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		pattern := patLev[0]
-		v, err := strconv.Atoi(patLev[1])
+		v, err := parseLevel(patLev[1])
 		if err != nil {
-			return errors.New("syntax error: expect comma-separated list of filename=N")
+			return nil, err
 		}
 		if v < 0 {
-			return errors.New("negative value for vmodule level")
-		}
-		if v == 0 {
-			continue // Ignore. It's harmless but no point in paying the overhead.
+			return nil, errors.New("negative value for vmodule level")
 		}
 		// TODO: check syntax of filter?
-		filter = append(filter, modulePat{pattern, isLiteral(pattern), Level(v)})
+		pats = append(pats, modulePat{pattern, isLiteral(pattern), v})
 	}
-	logging.mu.Lock()
-	defer logging.mu.Unlock()
-	logging.setVState(logging.verbosity, filter, true)
-	return nil
+	return pats, nil
 }
 
 // isLiteral reports whether the pattern is a literal string, that is, has no metacharacters
@@ -268,21 +419,26 @@ func isLiteral(pattern string) bool {
 }
 
 // traceLocation represents the setting of the -log_backtrace_at flag.
+// Either file and line or function is set, never both.
 type traceLocation struct {
-	file string
-	line int
+	file     string
+	line     int
+	function string // package-qualified function name, e.g. "flog.Info"
 }
 
 // isSet reports whether the trace location has been specified.
 // logging.mu is held.
 func (t *traceLocation) isSet() bool {
-	return t.line > 0
+	return t.line > 0 || t.function != ""
 }
 
-// match reports whether the specified file and line matches the trace location.
+// match reports whether the specified pc or file and line matches the trace location.
 // The argument file name is the full path, not the basename specified in the flag.
 // logging.mu is held.
-func (t *traceLocation) match(file string, line int) bool {
+func (t *traceLocation) match(pc uintptr, file string, line int) bool {
+	if t.function != "" {
+		return pc != 0 && matchFuncName(t.function, pc)
+	}
 	if t.line != line {
 		return false
 	}
@@ -292,10 +448,26 @@ func (t *traceLocation) match(file string, line int) bool {
 	return t.file == file
 }
 
+// matchFuncName reports whether the function containing pc is name.
+// The full function name includes the import path, so name only has to match
+// from a path element boundary: "flog.Info" matches
+// "github.com/facebookincubator/flog.Info".
+func matchFuncName(name string, pc uintptr) bool {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return false
+	}
+	full := fn.Name()
+	return full == name || strings.HasSuffix(full, "/"+name)
+}
+
 func (t *traceLocation) String() string {
 	// Lock because the type is not atomic. TODO: clean this up.
 	logging.mu.Lock()
 	defer logging.mu.Unlock()
+	if t.function != "" {
+		return t.function
+	}
 	return fmt.Sprintf("%s:%d", t.file, t.line)
 }
 
@@ -305,17 +477,35 @@ func (t *traceLocation) Get() interface{} {
 	return nil
 }
 
-var errTraceSyntax = errors.New("syntax error: expect file.go:234")
+var errTraceSyntax = errors.New("syntax error: expect file.go:234 or package.Function")
 
-// Syntax: -log_backtrace_at=gopherflakes.go:234
+// Syntax: -log_backtrace_at=gopherflakes.go:234 or -log_backtrace_at=gopher.Flake
 // Note that unlike vmodule the file extension is included here.
 func (t *traceLocation) Set(value string) error {
+	ensureInit()
 	if value == "" {
 		// Unset.
 		logging.mu.Lock()
 		defer logging.mu.Unlock()
 		t.line = 0
 		t.file = ""
+		t.function = ""
+		logging.updateStackTriggers()
+		return nil
+	}
+	if !strings.Contains(value, ":") {
+		// A function name needs at least a package and a name, and must not
+		// be mistaken for a file with a missing line number.
+		i := strings.LastIndex(value, ".")
+		if i <= 0 || i == len(value)-1 || strings.HasSuffix(value, ".go") {
+			return errTraceSyntax
+		}
+		logging.mu.Lock()
+		defer logging.mu.Unlock()
+		t.line = 0
+		t.file = ""
+		t.function = value
+		logging.updateStackTriggers()
 		return nil
 	}
 	fields := strings.Split(value, ":")
@@ -337,35 +527,218 @@ func (t *traceLocation) Set(value string) error {
 	defer logging.mu.Unlock()
 	t.line = v
 	t.file = file
+	t.function = ""
+	logging.updateStackTriggers()
+	return nil
+}
+
+// errorStacks represents the setting of the -error_stack_cooldown flag.
+type errorStacks struct {
+	cooldown time.Duration // zero means disabled
+	// last records when each call site, identified by PC, last had a
+	// stack trace attached.
+	last map[uintptr]time.Time
+}
+
+// due reports whether an error logged at pc should carry a stack trace and
+// if so records that it got one.
+// logging.mu is held.
+func (e *errorStacks) due(pc uintptr, now time.Time) bool {
+	if e.cooldown <= 0 || pc == 0 {
+		return false
+	}
+	if last, ok := e.last[pc]; ok && now.Sub(last) < e.cooldown {
+		return false
+	}
+	e.last[pc] = now
+	return true
+}
+
+func (e *errorStacks) String() string {
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	if e.cooldown <= 0 {
+		return ""
+	}
+	return e.cooldown.String()
+}
+
+// Get is part of the (Go 1.2) flag.Getter interface. It always returns nil for this flag type since the
+// struct is not exported
+func (e *errorStacks) Get() interface{} {
+	return nil
+}
+
+// Syntax: -error_stack_cooldown=10m
+// An empty value or a zero duration disables the stack traces.
+func (e *errorStacks) Set(value string) error {
+	ensureInit()
+	var d time.Duration
+	if value != "" {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil {
+			return err
+		}
+		if d < 0 {
+			return errors.New("negative value for error stack cooldown")
+		}
+	}
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	e.cooldown = d
+	e.last = make(map[uintptr]time.Time)
+	logging.updateStackTriggers()
 	return nil
 }
 
 // loggingT collects all the global state of the logging setup.
 type loggingT struct {
+	// The fields read and written with 64-bit atomic operations come first,
+	// as only they are 64-bit aligned on 32-bit platforms, see the bugs
+	// section of sync/atomic. The structures among them start with theirs.
+
+	// slowThreshold is the p99 write latency above which the output is
+	// reported as slow, zero if latencies are not measured. It is read and
+	// written using sync/atomic.
+	slowThreshold int64
+	// summaryThreshold is the size above which arguments are summarized,
+	// zero if they are not. It is read and written using sync/atomic.
+	summaryThreshold int64
+	// clock detects steps of the wall clock.
+	clock clockState
+	// dedup collapses identical consecutive entries, see SetDedupWindow.
+	dedup deduper
+
 	// freeList is a pool of byte buffers
 	freeList *sync.Pool
 
+	// pipeline holds the current *pipeline. It is read without locking and
+	// replaced as a whole under pipelineMu.
+	pipeline   atomic.Value
+	pipelineMu sync.Mutex
+	// dryRun is nonzero if processors only annotate entries. It is read and
+	// written using sync/atomic.
+	dryRun int32
+	// mem accounts for the memory held by internal buffering.
+	mem memoryBudget
+	// outLatency tracks the write latencies of out. It is replaced along
+	// with out.
+	outLatency *latencyTracker
+	// suppressions holds the call sites silenced with Suppress.
+	suppressions suppressions
+	// atomicWrites is nonzero if entries are written in chunks of at most
+	// PIPE_BUF bytes. It is read and written using sync/atomic.
+	atomicWrites int32
+	// monoTimestamps is nonzero if entries carry their monotonic timestamp
+	// in a field. It is read and written using sync/atomic.
+	monoTimestamps int32
+	// jsonTypeTags is nonzero if durations, times and byte sizes are tagged
+	// with their type in JSON. It is read and written using sync/atomic.
+	jsonTypeTags int32
+	// floatDecimals is one more than the number of decimals float fields are
+	// written with, zero for the shortest representation. It is read and
+	// written using sync/atomic.
+	floatDecimals int32
+	// jsonLevels holds the *[numSeverity]int set by SetJSONLevels.
+	jsonLevels atomic.Value
+	// profile holds the *profileState set by SetProfileTrigger.
+	profile atomic.Value
+	// format is the format entries are written in. It is read and written
+	// using sync/atomic.
+	format Format
+	// sampler drops entries of call sites over the rate set by
+	// SetSampleRate.
+	sampler sampler
+	// hooks holds the hooks added with AddHook.
+	hooks hooks
+	// alerts holds the rules added with AddAlertRule.
+	alerts alerts
+	// statePath is the file verbosity and vmodule changes are saved to, see
+	// SetStateFile. l.mu protects it.
+	statePath string
+	// recent holds the last entries written, see KeepRecent.
+	recent recent
+	// sequenced is nonzero if entries carry sequence numbers. It is read and
+	// written using sync/atomic.
+	sequenced int32
+	// contextStatus is nonzero if the Ctx functions add the status of their
+	// context to entries. It is read and written using sync/atomic.
+	contextStatus int32
+
 	// mu protects the remaining elements of this structure and is
 	// used to synchronize logging.
 	mu sync.Mutex
-	// pcs is used in V to avoid an allocation when computing the caller's PC.
-	pcs [1]uintptr
-	// vmap is a cache of the V Level for each V() call site, identified by PC.
-	// It is wiped whenever the vmodule flag changes state.
-	vmap map[uintptr]Level
-	// filterLength stores the length of the vmodule filter chain. If greater
-	// than zero, it means vmodule is enabled. It may be read safely
-	// using sync.LoadInt32, but is only modified under mu.
-	filterLength int32
+	// vstate holds the *vState V reads, replaced as a whole under mu
+	// whenever the verbosity or vmodule changes.
+	vstate atomic.Value
+	// levelHook holds the levelHookHolder set by SetLevelHook.
+	levelHook atomic.Value
+	// hookMinSeverity is one more than the minimum severity decided by the
+	// level hook, zero if it decides none. It is read and written using
+	// sync/atomic.
+	hookMinSeverity int32
 	// traceLocation is the state of the -log_backtrace_at flag.
 	traceLocation traceLocation
+	// exitPolicy controls what Critical and Fatal logs do once written.
+	exitPolicy ExitPolicy
+	// errorStacks is the state of the -error_stack_cooldown flag.
+	errorStacks errorStacks
+	// stackTraceLevel is the state of the -stack_trace_level flag.
+	stackTraceLevel stackTraceLevel
+	// stackTriggers is nonzero if traceLocation, errorStacks or
+	// stackTraceLevel is set. It may
+	// be read safely using sync.LoadInt32, but is only modified under mu.
+	stackTriggers int32
+	// seq is the sequence number of the last entry written, see
+	// SetSequenceNumbers.
+	seq uint64
+	// async is the queue of the asynchronous mode.
+	async asyncQueue
+	// fieldMap holds the FieldMap applied to out, set by Config.FieldMap.
+	fieldMap atomic.Value
+	// severityOut holds the outputs set by SetSeverityOutput.
+	severityOut severityOutputs
+	// minSeverity is the severity below which entries are dropped, set by
+	// SetMinSeverity. It is read and written using sync/atomic.
+	minSeverity Severity
+	// severityPrefix is nonzero if the lines of the text format start with
+	// the severity token. It is read and written using sync/atomic.
+	severityPrefix int32
+	// color tells when the output is colored, see SetColor.
+	color ColorMode
+	// linePrefix holds the string set by SetLinePrefix.
+	linePrefix atomic.Value
+	// tags holds the tags of goroutines set with SetTag.
+	tags tagSet
+	// goroutineIDs is nonzero if entries are tagged with the ID of their
+	// goroutine. It is read and written using sync/atomic.
+	goroutineIDs int32
+	// timeFormat is the format of the timestamps of the text format. It is
+	// read and written using sync/atomic.
+	timeFormat TimeFormat
+	// utc is nonzero if entries carry their time in UTC. It is read and
+	// written using sync/atomic.
+	utc int32
+	// headerFormatter holds the HeaderFormatter set by SetHeaderFormatter.
+	headerFormatter atomic.Value
+	// encoder holds the encoderHolder set by SetEncoder.
+	encoder atomic.Value
+	// tsCache holds the *tsCache of the timestamp cache, nil if it is off.
+	tsCache atomic.Value
+	// buffered is the buffer in front of the output, set by SetBuffer. When
+	// not nil, it is out.
+	buffered *bufferedOutput
 	// These flags are modified only under lock, although verbosity may be fetched
 	// safely using atomic.LoadInt32.
 	vmodule   moduleSpec // The state of the -vmodule flag.
+	vlogger   loggerSpec // The state of the -vlogger flag.
 	verbosity Level      // V logging level, the value of the -v flag/
 	// This is where we output. Used to facilitate testing.
 	// Should be set to Stderr for most applications
 	out io.Writer
+	// outputs are the outputs added with AddOutput. The slice is replaced,
+	// never modified.
+	outputs []extraOutput
 }
 
 // buffer holds a byte Buffer for reuse. The zero value is ready for use.
@@ -379,21 +752,12 @@ var logging loggingT
 // setVState sets a consistent state for V logging.
 // l.mu is held.
 func (l *loggingT) setVState(verbosity Level, filter []modulePat, setFilter bool) {
-	// Turn verbosity off so V will not fire while we are in transition.
-	logging.verbosity.set(0)
-	// Ditto for filter length.
-	atomic.StoreInt32(&logging.filterLength, 0)
-
-	// Set the new filters and wipe the pc->Level map if the filter has changed.
 	if setFilter {
-		logging.vmodule.filter = filter
-		logging.vmap = make(map[uintptr]Level)
+		l.vmodule.filter = filter
 	}
-
-	// Things are consistent now, so enable filtering and verbosity.
-	// They are enabled in order opposite to that in V.
-	atomic.StoreInt32(&logging.filterLength, int32(len(filter)))
-	logging.verbosity.set(verbosity)
+	l.verbosity.set(verbosity)
+	l.publishV()
+	l.saveState(verbosity, l.vmodule.filter)
 }
 
 // getBuffer returns a new, ready-to-use buffer.
@@ -414,75 +778,78 @@ func (l *loggingT) putBuffer(b *buffer) {
 
 var timeNow = time.Now // Stubbed out for testing.
 
+// caller returns the pc, file base name and line number of the user's call site.
+// The depth specifies how many stack frames above lives the source line to be
+// identified in the log message.
+func caller(depth int) (uintptr, string, int) {
+	pc, file, line, ok := runtime.Caller(3 + depth)
+	if !ok {
+		return 0, "???", 1
+	}
+	if slash := strings.LastIndex(file, "/"); slash >= 0 {
+		file = file[slash+1:]
+	}
+	return pc, file, line
+}
+
 /*
-header formats a log header as defined by the C++ implementation.
-It returns a buffer containing the formatted header and the user's file and line number.
-The depth specifies how many stack frames above lives the source line to be identified in the log message.
+formatHeader writes the log header of e to buf as defined by the C++ implementation.
 
 Log lines have this form:
-	Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg...
+	Lmmdd hh:mm:ss.uuuuuu threadid [tag ]file:line] msg...
 where the fields are defined as follows:
 	L                A single character, representing the log level (eg 'I' for INFO)
 	mm               The month (zero padded; ie May is '05')
 	dd               The day (zero padded)
 	hh:mm:ss.uuuuuu  Time in hours, minutes and fractional seconds
 	threadid         The space-padded thread ID as returned by GetTID()
+	tag              The tag of the entry, see SetTag, if any
 	file             The file name
 	line             The line number
 	msg              The user-supplied message
 */
-func (l *loggingT) header(s severity, depth int) (*buffer, string, int) {
-	_, file, line, ok := runtime.Caller(3 + depth)
-	if !ok {
-		file = "???"
-		line = 1
-	} else {
-		slash := strings.LastIndex(file, "/")
-		if slash >= 0 {
-			file = file[slash+1:]
-		}
-	}
-	return l.formatHeader(s, file, line), file, line
-}
-
-// formatHeader formats a log header using the provided file name and line number.
-func (l *loggingT) formatHeader(s severity, file string, line int) *buffer {
-	now := timeNow()
+func (l *loggingT) formatHeader(buf *buffer, e *Entry) {
+	s, file, line, now := e.Severity, e.File, e.Line, e.Time
 	if line < 0 {
 		line = 0 // not a real line number, but acceptable to someDigits
 	}
-	if s > fatalLog {
-		s = infoLog // for safety.
+	if s > FatalLog {
+		s = InfoLog // for safety.
 	}
-	buf := l.getBuffer()
 
 	// Avoid Fprintf, for speed. The format is so simple that we can do it quickly by hand.
 	// It's worth about 3X. Fprintf is hard.
-	_, month, day := now.Date()
-	hour, minute, second := now.Clock()
 	// Lmmdd hh:mm:ss.uuuuuu threadid file:line]
-	buf.tmp[0] = severityChar[s]
-	buf.twoDigits(1, int(month))
-	buf.twoDigits(3, day)
-	buf.tmp[5] = ' '
-	buf.twoDigits(6, hour)
-	buf.tmp[8] = ':'
-	buf.twoDigits(9, minute)
-	buf.tmp[11] = ':'
-	buf.twoDigits(12, second)
-	buf.tmp[14] = '.'
-	buf.nDigits(6, 15, now.Nanosecond()/1000, '0')
-	buf.tmp[21] = ' '
-	buf.nDigits(7, 22, pid, ' ') // TODO: should be TID
-	buf.tmp[29] = ' '
-	buf.Write(buf.tmp[:30])
+	if l.timeFormat.get() == TimeRFC3339 {
+		// Lyyyy-mm-ddThh:mm:ss.uuuuuu+hh:mm threadid file:line]
+		buf.WriteByte(severityChar[s])
+		buf.Write(now.AppendFormat(buf.tmp[:0], rfc3339Micro))
+		buf.tmp[0] = ' '
+		buf.nDigits(7, 1, getPid(), ' ')
+		buf.tmp[8] = ' '
+		buf.Write(buf.tmp[:9])
+	} else {
+		buf.tmp[0] = severityChar[s]
+		if !l.cachedTimestamp(buf, now) {
+			formatTimestamp(buf, now)
+		}
+		buf.tmp[14] = '.'
+		buf.nDigits(6, 15, now.Nanosecond()/1000, '0')
+		buf.tmp[21] = ' '
+		buf.nDigits(7, 22, getPid(), ' ') // TODO: should be TID
+		buf.tmp[29] = ' '
+		buf.Write(buf.tmp[:30])
+	}
+	if e.Tag != "" {
+		buf.WriteString(e.Tag)
+		buf.WriteByte(' ')
+	}
 	buf.WriteString(file)
 	buf.tmp[0] = ':'
 	n := buf.someDigits(1, line)
 	buf.tmp[n+1] = ']'
 	buf.tmp[n+2] = ' '
 	buf.Write(buf.tmp[:n+3])
-	return buf
 }
 
 // Some custom tiny helper functions to print the log header efficiently.
@@ -526,71 +893,328 @@ func (buf *buffer) someDigits(i, d int) int {
 	return copy(buf.tmp[i:], buf.tmp[j:])
 }
 
-func (l *loggingT) println(s severity, args ...interface{}) {
-	buf, file, line := l.header(s, 0)
-	fmt.Fprintln(buf, args...)
-	l.output(s, buf, file, line)
+func (l *loggingT) println(s Severity, args ...interface{}) {
+	if l.belowMinSeverity(s) {
+		return
+	}
+	pc, file, line := caller(0)
+	l.output(newEntry(s, pc, file, line, sprintln(args)))
 }
 
-func (l *loggingT) print(s severity, args ...interface{}) {
+func (l *loggingT) print(s Severity, args ...interface{}) {
 	l.printDepth(s, 1, args...)
 }
 
-func (l *loggingT) printDepth(s severity, depth int, args ...interface{}) {
-	buf, file, line := l.header(s, depth)
-	fmt.Fprint(buf, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
+func (l *loggingT) printDepth(s Severity, depth int, args ...interface{}) {
+	if l.belowMinSeverity(s) {
+		return
 	}
-	l.output(s, buf, file, line)
+	pc, file, line := caller(depth)
+	l.output(newEntry(s, pc, file, line, sprint(args)))
 }
 
-func (l *loggingT) printf(s severity, format string, args ...interface{}) {
-	buf, file, line := l.header(s, 0)
-	fmt.Fprintf(buf, format, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
-		buf.WriteByte('\n')
+func (l *loggingT) printf(s Severity, format string, args ...interface{}) {
+	if l.belowMinSeverity(s) {
+		return
 	}
-	l.output(s, buf, file, line)
+	pc, file, line := caller(0)
+	l.output(newEntry(s, pc, file, line, sprintf(format, args)))
+}
+
+// printWithFileLine behaves like print but uses the provided file and line
+// number and attaches the provided fields.
+func (l *loggingT) printWithFileLine(s Severity, file string, line int, fields []Field, args ...interface{}) {
+	e := newEntry(s, 0, file, line, sprint(args))
+	e.Fields = fields
+	l.output(e)
 }
 
-// printWithFileLine behaves like print but uses the provided file and line number.  If
-// alsoLogToStderr is true, the log message always appears on standard error
-func (l *loggingT) printWithFileLine(s severity, file string, line int, args ...interface{}) {
-	buf := l.formatHeader(s, file, line)
-	fmt.Fprint(buf, args...)
-	if buf.Bytes()[buf.Len()-1] != '\n' {
+// newEntry returns an entry for msg logged now at the given call site.
+func newEntry(s Severity, pc uintptr, file string, line int, msg string) *Entry {
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	return &Entry{
+		Severity: s,
+		Time:     timeNow(),
+		File:     file,
+		Line:     line,
+		Message:  msg,
+		pc:       pc,
+	}
+}
+
+// encode writes e to buf in the current format, or with the Encoder set by
+// SetEncoder. In the text format, that is the header, the message, the
+// fields and the stack trace, if any.
+func (l *loggingT) encode(buf *buffer, e *Entry) {
+	if enc := l.loadEncoder(); enc != nil && !e.raw {
+		enc.Encode(*e, &buf.Buffer)
+		return
+	}
+	if f := l.format.get(); f != FormatText && !e.raw {
+		if f == FormatLogfmt {
+			encodeLogfmt(buf, e)
+		} else {
+			encodeJSON(buf, e)
+		}
 		buf.WriteByte('\n')
+		return
+	}
+	start := buf.Len()
+	if e.raw {
+		buf.WriteString(e.Message)
+		buf.WriteByte('\n')
+		return
+	}
+	if !l.customHeader(buf, e) {
+		l.formatHeader(buf, e)
+	}
+	buf.WriteString(e.Message)
+	writeFields(buf, e.Fields)
+	buf.WriteByte('\n')
+	buf.Write(e.Stack)
+	severityPrefix, linePrefix := atomic.LoadInt32(&l.severityPrefix) != 0, l.loadLinePrefix()
+	if severityPrefix || linePrefix != "" {
+		var prefix []byte
+		if severityPrefix {
+			token := severityToken(e.Severity)
+			prefix = append(prefix, token[:]...)
+		}
+		prefixLines(buf, start, append(prefix, linePrefix...))
+	}
+}
+
+// output runs e through the pipeline, then writes it to the output.
+func (l *loggingT) output(e *Entry) {
+	if l.belowMinSeverity(e.Severity) {
+		return
+	}
+	l.stampClock(e)
+	l.stampTag(e)
+	if !l.process(e) {
+		return
+	}
+	if l.suppressions.match(e) {
+		atomic.AddInt64(&Stats.Suppressed, 1)
+		return
+	}
+	if atomic.LoadInt64(&l.dedup.window) > 0 {
+		keep, summary := l.dedup.dedup(e)
+		if !keep {
+			atomic.AddInt64(&Stats.Deduplicated, 1)
+			return
+		}
+		if summary != nil {
+			l.emit(summary)
+		}
 	}
-	l.output(s, buf, file, line)
+	if atomic.LoadInt32(&l.sampler.rate) > 0 {
+		keep, summary := l.sampler.sample(e)
+		if !keep {
+			atomic.AddInt64(&Stats.Sampled, 1)
+			return
+		}
+		if summary != nil {
+			l.emit(summary)
+		}
+	}
+	l.emit(e)
 }
 
-// output writes the data to the log files and releases the buffer.
-func (l *loggingT) output(s severity, buf *buffer, file string, line int) {
-	l.mu.Lock()
-	if l.traceLocation.isSet() {
-		if l.traceLocation.match(file, line) {
-			buf.Write(stacks(false))
+// emit writes e, which went through the pipeline, to the output, and exits
+// or panics afterwards if the exit policy says so.
+func (l *loggingT) emit(e *Entry) {
+	resolveLazy(e)
+	if e.Severity == CriticalLog {
+		l.captureProfile(e)
+	}
+	l.alerts.observe(e)
+	if atomic.LoadInt32(&l.stackTriggers) > 0 && !e.raw {
+		l.mu.Lock()
+		if l.traceLocation.isSet() && l.traceLocation.match(e.pc, e.File, e.Line) {
+			e.Stack = stacks(false)
+		} else if (e.Severity == ErrorLog || e.Severity == CriticalLog) && l.errorStacks.due(e.pc, e.Time) {
+			e.Stack = stacks(false)
+		} else if l.stackTraceLevel.match(e.Severity) && e.Stack == nil {
+			e.Stack = stacks(false)
 		}
+		l.mu.Unlock()
+	}
+	if e.Stack != nil {
+		e.Stack = l.redactStack(e.Stack)
+	}
+	l.hooks.call(e)
+	for _, h := range e.hooks {
+		h(*e)
+	}
+	// With sequence numbers, the entry is numbered and encoded under the
+	// lock it is written under, so that the numbers follow the output order.
+	sequenced := atomic.LoadInt32(&l.sequenced) != 0
+	if sequenced {
+		l.mu.Lock()
+		l.seq++
+		e.setField(seqKey, l.seq)
 	}
+	l.recent.add(e)
+	s := e.Severity
+	buf := l.getBuffer()
+	l.encode(buf, e)
 	data := buf.Bytes()
-	l.out.Write(data)
-	if s == fatalLog {
-		// If we got here via Exit rather than Fatal, print no stacks.
-		if atomic.LoadUint32(&fatalNoStacks) > 0 {
+	observeSite(e, len(data))
+	held := buf.Cap()
+	if !l.mem.reserve(s, held) {
+		if sequenced {
 			l.mu.Unlock()
-			os.Exit(1)
 		}
-		l.out.Write(stacks(true))
+		l.putBuffer(buf)
+		return
+	}
+	if !sequenced {
+		l.mu.Lock()
+	}
+	if e.output == nil && l.async.active() {
+		if s < CriticalLog && l.async.depth > 0 {
+			l.async.enqueue(asyncItem{s, buf, held})
+			l.mu.Unlock()
+			return
+		}
+		l.async.drain()
+	}
+	defer l.mem.release(held)
+	threshold := time.Duration(atomic.LoadInt64(&l.slowThreshold))
+	slow := l.write(e.output, s, data, threshold)
+	// If we got here via Exit rather than Fatal, print no stacks.
+	if s == FatalLog && !e.raw && atomic.SwapUint32(&fatalNoStacks, 0) > 0 {
+		l.mu.Unlock()
+		exitProcess(1, nil)
+		countOutput(s, len(data))
+		l.putBuffer(buf)
+		return
+	}
+	action := l.exitAction(s)
+	if e.raw {
+		action = ActionLog
+	}
+	switch action {
+	case ActionExit, ActionFlushExit:
+		if s == FatalLog {
+			trace := l.redactStack(stacks(true))
+			out, _ := l.severityOut.pick(l.out, nil, s)
+			out.Write(trace)
+			l.writeExtra(s, trace)
+		}
 		logExitFunc = func(error) {} // If we get a write error, we'll still exit below.
+		outs, timeout := []interface{}{l.out}, l.exitPolicy.FlushTimeout
+		if e.output != nil {
+			outs[0] = e.output.out // its buffer was flushed by write
+		} else {
+			outs = append(outs, l.severityOut.writers()...)
+			for _, o := range l.outputs {
+				outs = append(outs, o.w)
+			}
+		}
 		l.mu.Unlock()
-		os.Exit(255) // C++ uses -1, which is silly because it's anded with 255 anyway.
+		var flush func()
+		if action == ActionFlushExit {
+			flush = func() { flushOutputs(outs, timeout) }
+		}
+		exitProcess(255, flush) // C++ uses -1, which is silly because it's anded with 255 anyway.
+		countOutput(s, len(data))
+		l.putBuffer(buf)
+		return
+	case ActionPanic:
+		msg := strings.TrimSuffix(string(data), "\n")
+		l.putBuffer(buf)
+		l.mu.Unlock()
+		countOutput(s, len(msg)+1)
+		panic(msg)
 	}
-	l.putBuffer(buf)
 	l.mu.Unlock()
+	countOutput(s, len(data))
+	l.putBuffer(buf)
+	for _, so := range slow {
+		l.warnSlowOutput(so.name, so.p99, threshold)
+	}
+}
+
+// updateStackTriggers records whether any setting may attach a stack trace
+// to an entry, so output can skip taking the lock when none does.
+// l.mu is held.
+func (l *loggingT) updateStackTriggers() {
+	var n int32
+	if l.traceLocation.isSet() || l.errorStacks.cooldown > 0 || l.stackTraceLevel.on {
+		n = 1
+	}
+	atomic.StoreInt32(&l.stackTriggers, n)
+}
+
+// write writes data, an entry of severity s, to the logger output o, or to
+// the package's outputs if o is nil, and returns the outputs found slow, as
+// described for SetSlowOutputThreshold with threshold. Outputs used in tests
+// may panic on purpose, so l.mu is released before such a panic propagates.
+// l.mu is held.
+func (l *loggingT) write(o *loggerOutput, s Severity, data []byte, threshold time.Duration) []slowOutput {
+	defer func() {
+		if r := recover(); r != nil {
+			l.mu.Unlock()
+			panic(r)
+		}
+	}()
+	if o != nil {
+		var start time.Time
+		if threshold > 0 {
+			start = time.Now()
+		}
+		o.write(s, data)
+		return o.lat.observe(nil, o.out, threshold, start)
+	}
+	out, lat := l.severityOut.pick(l.out, l.outLatency, s)
+	return writeOutputs(out, lat, l.loadFieldMap(), l.outputs, s, data, atomic.LoadInt32(&l.atomicWrites) != 0, threshold)
+}
+
+// writeOutputs writes data, an entry of severity s, to out, with its fields
+// mapped by m, and to the added outputs that receive it. If atomicWrites,
+// long entries are written in chunks of at most pipeBuf bytes. If threshold
+// is positive, the latencies of the writes are recorded, by lat for out, and
+// the outputs found slow are returned.
+func writeOutputs(out io.Writer, lat *latencyTracker, m FieldMap, outputs []extraOutput, s Severity, data []byte, atomicWrites bool, threshold time.Duration) []slowOutput {
+	var start time.Time
+	if threshold > 0 {
+		start = time.Now()
+	}
+	mapped := m.apply(data)
+	if logging.colored(out) {
+		mapped = colorize(mapped, s)
+	}
+	if redraw := interruptLine(); redraw != nil {
+		defer redraw()
+	}
+	if sw, ok := out.(SeverityWriter); ok {
+		sw.WriteSeverity(s, mapped)
+	} else if len(mapped) > pipeBuf && atomicWrites {
+		writeAtomic(out, mapped)
+	} else {
+		out.Write(mapped)
+	}
+	slow := lat.observe(nil, out, threshold, start)
+	for _, o := range outputs {
+		if s >= o.minSeverity {
+			if threshold > 0 {
+				start = time.Now()
+			}
+			writeSeverity(o.w, s, data)
+			slow = o.lat.observe(slow, o.w, threshold, start)
+		}
+	}
+	return slow
+}
+
+// countOutput records a line of n bytes written at severity s in Stats.
+func countOutput(s Severity, n int) {
 	if stats := severityStats[s]; stats != nil {
 		atomic.AddInt64(&stats.lines, 1)
-		atomic.AddInt64(&stats.bytes, int64(len(data)))
+		atomic.AddInt64(&stats.bytes, int64(n))
 	}
 }
 
@@ -637,25 +1261,44 @@ func (l *loggingT) exit(err error) {
 // severities.  Subsequent changes to the standard log's default output location
 // or format may break this behavior.
 //
+// If more names are given, messages that start with one of them followed by a
+// colon or in brackets, as in "WARNING: disk almost full" or "[ERROR] request
+// failed", are logged at that severity instead, without the prefix. Either
+// way the entries carry a source=stdlog field, so they can be told apart from
+// the entries logged with flog directly.
+//
 // Valid names are "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL", and
-// "FATAL".  If the name is not recognized, CopyStandardLogTo panics.
-func CopyStandardLogTo(name string) {
-	sev, ok := severityByName(name)
-	if !ok {
-		panic(fmt.Sprintf("log.CopyStandardLogTo(%q): unrecognized severity name", name))
+// "FATAL".  If a name is not recognized, CopyStandardLogTo panics.
+func CopyStandardLogTo(name string, detect ...string) {
+	lb := logBridge{severity: mustSeverityByName(name)}
+	for _, name := range detect {
+		lb.detect = append(lb.detect, mustSeverityByName(name))
 	}
 	// Set a log format that captures the user's file and line:
 	//   d.go:23: message
 	stdLog.SetFlags(stdLog.Lshortfile)
-	stdLog.SetOutput(logBridge(sev))
+	stdLog.SetOutput(lb)
+}
+
+// mustSeverityByName returns the severity with the given name, or panics as
+// documented for CopyStandardLogTo.
+func mustSeverityByName(name string) Severity {
+	sev, ok := severityByName(name)
+	if !ok {
+		panic(fmt.Sprintf("log.CopyStandardLogTo(%q): unrecognized severity name", name))
+	}
+	return sev
 }
 
 // logBridge provides the Write method that enables CopyStandardLogTo to connect
 // Go's standard logs to the logs provided by this package.
-type logBridge severity
+type logBridge struct {
+	severity Severity   // the severity of messages without a known prefix
+	detect   []Severity // the severities recognized from message prefixes
+}
 
 // Write parses the standard logging line and passes its components to the
-// logger for severity(lb).
+// logger for lb.severity or the severity of the message prefix.
 func (lb logBridge) Write(b []byte) (n int, err error) {
 	var (
 		file = "???"
@@ -674,19 +1317,39 @@ func (lb logBridge) Write(b []byte) (n int, err error) {
 			line = 1
 		}
 	}
-	// printWithFileLine with alsoToStderr=true, so standard log messages
-	// always appear on standard error.
-	logging.printWithFileLine(severity(lb), file, line, text)
+	sev := lb.severity
+	for _, s := range lb.detect {
+		if rest, ok := trimSeverityPrefix(text, s); ok {
+			sev, text = s, rest
+			break
+		}
+	}
+	logging.printWithFileLine(sev, file, line, []Field{{"source", "stdlog"}}, text)
 	return len(b), nil
 }
 
-// setV computes and remembers the V level for a given PC
-// when vmodule is enabled.
+// trimSeverityPrefix removes a "NAME:" or "[NAME]" prefix naming s, in any
+// case, from text and reports whether there was one.
+func trimSeverityPrefix(text string, s Severity) (string, bool) {
+	name := severityName[s]
+	switch {
+	case len(text) > len(name) && strings.EqualFold(text[:len(name)], name) && text[len(name)] == ':':
+		text = text[len(name)+1:]
+	case len(text) > len(name)+1 && text[0] == '[' && strings.EqualFold(text[1:len(name)+1], name) && text[len(name)+1] == ']':
+		text = text[len(name)+2:]
+	default:
+		return text, false
+	}
+	return strings.TrimLeft(text, " "), true
+}
+
+// filterLevel returns the level of the first pattern of filter matching the
+// file of pc, zero if none does.
 // File pattern matching takes the basename of the file, stripped
 // of its .go suffix, and uses filepath.Match, which is a little more
-// general than the *? matching used in C++.
-// l.mu is held.
-func (l *loggingT) setV(pc uintptr) Level {
+// general than the *? matching used in C++. Patterns with a slash
+// take the import path of the package, a slash and that basename.
+func filterLevel(filter []modulePat, pc uintptr) Level {
 	fn := runtime.FuncForPC(pc)
 	file, _ := fn.FileLine(pc)
 	// The file is something like /a/b/c/d.go. We want just the d.
@@ -696,16 +1359,33 @@ func (l *loggingT) setV(pc uintptr) Level {
 	if slash := strings.LastIndex(file, "/"); slash >= 0 {
 		file = file[slash+1:]
 	}
-	for _, filter := range l.vmodule.filter {
-		if filter.match(file) {
-			l.vmap[pc] = filter.level
+	var pkgFile string // computed for the first pattern with a slash
+	for _, filter := range filter {
+		name := file
+		if filter.hasPath() {
+			if pkgFile == "" {
+				pkgFile = funcPackage(fn.Name()) + "/" + file
+			}
+			name = pkgFile
+		}
+		if filter.match(name) {
 			return filter.level
 		}
 	}
-	l.vmap[pc] = 0
 	return 0
 }
 
+// funcPackage returns the import path of the package of the function
+// named name, as returned by runtime.Func.Name, such as
+// github.com/a/b for github.com/a/b.(*T).F.
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
 // Verbose is a boolean type that implements Infof (like Printf) etc.
 // See the documentation of V for more information.
 type Verbose bool
@@ -726,38 +1406,41 @@ type Verbose bool
 // call, the V call will log.
 func V(level Level) Verbose {
 	// This function tries hard to be cheap unless there's work to do.
-	// The fast path is two atomic loads and compares.
+	// The fast path is a single atomic load and compares.
+	s := logging.loadV()
+	if s.verbosity >= level {
+		if s.observe {
+			observeV(0, true)
+		}
+		return true
+	}
 
-	// Here is a cheap but safe test to see if V logging is enabled globally.
-	if logging.verbosity.get() >= level {
-		return Verbose(true)
+	// It's off globally but vmodule may still enable it at the call site,
+	// whose level is cached.
+	v := level <= s.max && s.sites.level(2) >= level
+	if s.observe {
+		observeV(0, v)
 	}
+	return Verbose(v)
+}
 
-	// It's off globally but it vmodule may still be set.
-	// Here is another cheap but safe test to see if vmodule is enabled.
-	if atomic.LoadInt32(&logging.filterLength) > 0 {
-		// Now we need a proper lock to use the logging structure. The pcs field
-		// is shared so we must lock before accessing it. This is fairly expensive,
-		// but if V logging is enabled we're slow anyway.
-		logging.mu.Lock()
-		defer logging.mu.Unlock()
-		if runtime.Callers(2, logging.pcs[:]) == 0 {
-			return Verbose(false)
-		}
-		v, ok := logging.vmap[logging.pcs[0]]
-		if !ok {
-			v = logging.setV(logging.pcs[0])
-		}
-		return Verbose(v >= level)
+// VDepth acts as V but uses depth to determine which call frame's source file
+// is matched against -vmodule. VDepth(0, level) is the same as V(level).
+// It is meant for wrappers that forward to flog on behalf of their caller.
+func VDepth(depth int, level Level) Verbose {
+	s := logging.loadV()
+	v := s.verbosity >= level || level <= s.max && s.sites.level(2+depth) >= level
+	if s.observe {
+		observeV(depth, v)
 	}
-	return Verbose(false)
+	return Verbose(v)
 }
 
 // Info is equivalent to the global Info function, guarded by the value of v.
 // See the documentation of V for usage.
 func (v Verbose) Info(args ...interface{}) {
 	if v {
-		logging.print(infoLog, args...)
+		logging.print(InfoLog, args...)
 	}
 }
 
@@ -765,7 +1448,7 @@ func (v Verbose) Info(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbose) Infoln(args ...interface{}) {
 	if v {
-		logging.println(infoLog, args...)
+		logging.println(InfoLog, args...)
 	}
 }
 
@@ -773,155 +1456,155 @@ func (v Verbose) Infoln(args ...interface{}) {
 // See the documentation of V for usage.
 func (v Verbose) Infof(format string, args ...interface{}) {
 	if v {
-		logging.printf(infoLog, format, args...)
+		logging.printf(InfoLog, format, args...)
 	}
 }
 
 // Debug logs to the DEBUG log.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Debug(args ...interface{}) {
-	logging.print(debugLog, args...)
+	logging.print(DebugLog, args...)
 }
 
 // DebugDepth acts as Debug but uses depth to determine which call frame to log.
 // DebugDepth(0, "msg") is the same as Debug("msg").
 func DebugDepth(depth int, args ...interface{}) {
-	logging.printDepth(debugLog, depth, args...)
+	logging.printDepth(DebugLog, depth, args...)
 }
 
 // Debugln logs to the DEBUG log.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Debugln(args ...interface{}) {
-	logging.println(debugLog, args...)
+	logging.println(DebugLog, args...)
 }
 
 // Debugf logs to the DEBUG log.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Debugf(format string, args ...interface{}) {
-	logging.printf(debugLog, format, args...)
+	logging.printf(DebugLog, format, args...)
 }
 
 // Info logs to the INFO and DEBUG log.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Info(args ...interface{}) {
-	logging.print(infoLog, args...)
+	logging.print(InfoLog, args...)
 }
 
 // InfoDepth acts as Info but uses depth to determine which call frame to log.
 // InfoDepth(0, "msg") is the same as Info("msg").
 func InfoDepth(depth int, args ...interface{}) {
-	logging.printDepth(infoLog, depth, args...)
+	logging.printDepth(InfoLog, depth, args...)
 }
 
 // Infoln logs to the INFO and DEBUG log.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Infoln(args ...interface{}) {
-	logging.println(infoLog, args...)
+	logging.println(InfoLog, args...)
 }
 
 // Infof logs to the INFO and DEBUG log.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Infof(format string, args ...interface{}) {
-	logging.printf(infoLog, format, args...)
+	logging.printf(InfoLog, format, args...)
 }
 
 // Warning logs to the WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Warning(args ...interface{}) {
-	logging.print(warningLog, args...)
+	logging.print(WarningLog, args...)
 }
 
 // WarningDepth acts as Warning but uses depth to determine which call frame to log.
 // WarningDepth(0, "msg") is the same as Warning("msg").
 func WarningDepth(depth int, args ...interface{}) {
-	logging.printDepth(warningLog, depth, args...)
+	logging.printDepth(WarningLog, depth, args...)
 }
 
 // Warningln logs to the WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Warningln(args ...interface{}) {
-	logging.println(warningLog, args...)
+	logging.println(WarningLog, args...)
 }
 
 // Warningf logs to the WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Warningf(format string, args ...interface{}) {
-	logging.printf(warningLog, format, args...)
+	logging.printf(WarningLog, format, args...)
 }
 
 // Error logs to the ERROR, WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Error(args ...interface{}) {
-	logging.print(errorLog, args...)
+	logging.print(ErrorLog, args...)
 }
 
 // ErrorDepth acts as Error but uses depth to determine which call frame to log.
 // ErrorDepth(0, "msg") is the same as Error("msg").
 func ErrorDepth(depth int, args ...interface{}) {
-	logging.printDepth(errorLog, depth, args...)
+	logging.printDepth(ErrorLog, depth, args...)
 }
 
 // Errorln logs to the ERROR, WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Errorln(args ...interface{}) {
-	logging.println(errorLog, args...)
+	logging.println(ErrorLog, args...)
 }
 
 // Errorf logs to the ERROR, WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Errorf(format string, args ...interface{}) {
-	logging.printf(errorLog, format, args...)
+	logging.printf(ErrorLog, format, args...)
 }
 
 // Critical logs to the CRITICAL, ERROR, WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Critical(args ...interface{}) {
-	logging.print(criticalLog, args...)
+	logging.print(CriticalLog, args...)
 }
 
 // CriticalDepth acts as Critical but uses depth to determine which call frame to log.
 // CriticalDepth(0, "msg") is the same as Critical("msg").
 func CriticalDepth(depth int, args ...interface{}) {
-	logging.printDepth(criticalLog, depth, args...)
+	logging.printDepth(CriticalLog, depth, args...)
 }
 
 // Criticalln logs to the CRITICAL, ERROR, WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Criticalln(args ...interface{}) {
-	logging.println(criticalLog, args...)
+	logging.println(CriticalLog, args...)
 }
 
 // Criticalf logs to the CRITICAL, ERROR, WARNING, INFO and DEBUG logs.
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Criticalf(format string, args ...interface{}) {
-	logging.printf(criticalLog, format, args...)
+	logging.printf(CriticalLog, format, args...)
 }
 
 // Fatal logs to the FATAL, CRITICAL, ERROR, WARNING, INFO and DEBUG logs.
 // including a stack trace of all running goroutines, then calls os.Exit(255).
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Fatal(args ...interface{}) {
-	logging.print(fatalLog, args...)
+	logging.print(FatalLog, args...)
 }
 
 // FatalDepth acts as Fatal but uses depth to determine which call frame to log.
 // FatalDepth(0, "msg") is the same as Fatal("msg").
 func FatalDepth(depth int, args ...interface{}) {
-	logging.printDepth(fatalLog, depth, args...)
+	logging.printDepth(FatalLog, depth, args...)
 }
 
 // Fatalln logs to the FATAL, CRITICAL, ERROR, WARNING, INFO and DEBUG logs.
 // including a stack trace of all running goroutines, then calls os.Exit(255).
 // Arguments are handled in the manner of fmt.Println; a newline is appended if missing.
 func Fatalln(args ...interface{}) {
-	logging.println(fatalLog, args...)
+	logging.println(FatalLog, args...)
 }
 
 // Fatalf logs to the FATAL, CRITICAL, ERROR, WARNING, INFO and DEBUG logs.
 // including a stack trace of all running goroutines, then calls os.Exit(255).
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Fatalf(format string, args ...interface{}) {
-	logging.printf(fatalLog, format, args...)
+	logging.printf(FatalLog, format, args...)
 }
 
 // fatalNoStacks is non-zero if we are to exit without dumping goroutine stacks.
@@ -932,38 +1615,56 @@ var fatalNoStacks uint32
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 func Exit(args ...interface{}) {
 	atomic.StoreUint32(&fatalNoStacks, 1)
-	logging.print(fatalLog, args...)
+	logging.print(FatalLog, args...)
 }
 
 // ExitDepth acts as Exit but uses depth to determine which call frame to log.
 // ExitDepth(0, "msg") is the same as Exit("msg").
 func ExitDepth(depth int, args ...interface{}) {
 	atomic.StoreUint32(&fatalNoStacks, 1)
-	logging.printDepth(fatalLog, depth, args...)
+	logging.printDepth(FatalLog, depth, args...)
 }
 
 // Exitln logs to the FATAL, CRITICAL, ERROR, WARNING, INFO and DEBUG logs, then calls os.Exit(1).
 func Exitln(args ...interface{}) {
 	atomic.StoreUint32(&fatalNoStacks, 1)
-	logging.println(fatalLog, args...)
+	logging.println(FatalLog, args...)
 }
 
 // Exitf logs to the FATAL, CRITICAL, ERROR, WARNING, INFO and DEBUG logs, then calls os.Exit(1).
 // Arguments are handled in the manner of fmt.Printf; a newline is appended if missing.
 func Exitf(format string, args ...interface{}) {
 	atomic.StoreUint32(&fatalNoStacks, 1)
-	logging.printf(fatalLog, format, args...)
+	logging.printf(FatalLog, format, args...)
 }
 
 // SetOutput sets the output writer for the lib.
 func SetOutput(w io.Writer) {
+	ensureInit()
 	logging.mu.Lock()
+	if b := logging.buffered; b != nil {
+		b.setOut(w)
+		w = b
+	}
 	logging.out = w
+	logging.outLatency = new(latencyTracker)
 	logging.mu.Unlock()
 }
 
+// GetOutput gets the current output writer of the lib.
+func GetOutput() io.Writer {
+	ensureInit()
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	if b := logging.buffered; b != nil {
+		return b.out
+	}
+	return logging.out
+}
+
 // GetVerbosity gets the current verbosity level
 func GetVerbosity() Level {
+	ensureInit()
 	logging.mu.Lock()
 	defer logging.mu.Unlock()
 	return logging.verbosity
@@ -20,13 +20,16 @@ package flog
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	stdLog "log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -98,6 +101,40 @@ func TestStandardLog(t *testing.T) {
 	}
 }
 
+// Test that standard log messages with a severity prefix are logged at that
+// severity when it is one of the detected ones.
+func TestStandardLogSeverities(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	CopyStandardLogTo("INFO", "WARNING", "ERROR")
+	defer CopyStandardLogTo("INFO")
+	for _, tc := range []struct{ in, want string }{
+		{"[ERROR] request failed", "E"},
+		{"warning: disk almost full", "W"},
+		{"CRITICAL: not detected", "I"},
+		{"plain message", "I"},
+	} {
+		logging.newBuffers()
+		stdLog.Print(tc.in)
+		if !strings.HasPrefix(contents(), tc.want) {
+			t.Errorf("%q: got %q, want severity %s", tc.in, contents(), tc.want)
+		}
+		if !contains(" source=stdlog\n") {
+			t.Errorf("%q: missing source field: %q", tc.in, contents())
+		}
+	}
+	logging.newBuffers()
+	stdLog.Print("CRITICAL: not detected")
+	if !contains("] CRITICAL: not detected source=stdlog") {
+		t.Errorf("undetected prefix was modified: %q", contents())
+	}
+	logging.newBuffers()
+	stdLog.Print("[ERROR] request failed")
+	if !contains("] request failed source=stdlog") {
+		t.Errorf("detected prefix was not removed: %q", contents())
+	}
+}
+
 // Test that the header has the correct format.
 func TestHeader(t *testing.T) {
 	logging.newBuffers()
@@ -106,6 +143,9 @@ func TestHeader(t *testing.T) {
 	timeNow = func() time.Time {
 		return time.Date(2006, 1, 2, 15, 4, 5, .067890e9, time.Local)
 	}
+	// Keep stampClock from refreshing the pid.
+	defer atomic.StoreInt64(&pid, pid)
+	atomic.StoreInt64(&logging.clock.pidChecked, timeNow().Unix())
 	pid = 1234
 	Info("test")
 	var line int
@@ -284,6 +324,36 @@ func TestConfigV(t *testing.T) {
 	}
 }
 
+// Test that invalid settings are reported on the config error channel.
+func TestConfigError(t *testing.T) {
+	var out bytes.Buffer
+	configErrorOutput = &out
+	defer func() { configErrorOutput = os.Stderr }()
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	cfg := &Config{
+		Verbosity: "0",
+		Vmodule:   "gfs*",
+	}
+	if err := cfg.Set(); err == nil {
+		t.Fatal("invalid vmodule was accepted")
+	}
+	line := out.String()
+	if !strings.HasPrefix(line, ConfigErrorPrefix+" ") || !strings.HasSuffix(line, "\n") {
+		t.Fatalf("config error has wrong format: %q", line)
+	}
+	var detail map[string]string
+	if err := json.Unmarshal([]byte(line[len(ConfigErrorPrefix):]), &detail); err != nil {
+		t.Fatalf("config error detail is not JSON: %v", err)
+	}
+	if detail["setting"] != "Config.Vmodule" || detail["value"] != "gfs*" || detail["error"] != errVmoduleSyntax.Error() {
+		t.Errorf("wrong config error detail: %v", detail)
+	}
+	if contents() != "" {
+		t.Errorf("config error written to the output: %q", contents())
+	}
+}
+
 // Test that a vmodule enables a log in this file.
 func TestVmoduleOn(t *testing.T) {
 	logging.newBuffers()
@@ -332,6 +402,23 @@ func TestVmoduleOff(t *testing.T) {
 	}
 }
 
+// Test that VDepth matches vmodule against the requested call frame.
+func TestVDepth(t *testing.T) {
+	logging.vmodule.Set("flog_test=2")
+	defer logging.vmodule.Set("")
+	if !VDepth(0, 2) {
+		t.Error("VDepth(0) not enabled for 2 in this file")
+	}
+	// The frame above a test function is in the testing package.
+	if VDepth(1, 2) {
+		t.Error("VDepth(1) enabled for 2 in testing")
+	}
+	logging.vmodule.Set("testing=2")
+	if !VDepth(1, 2) {
+		t.Error("VDepth(1) not enabled for 2 in testing")
+	}
+}
+
 // vGlobs are patterns that match/don't match this file at V=2.
 var vGlobs = map[string]bool{
 	// Easy to test the numeric match here.
@@ -348,6 +435,14 @@ var vGlobs = map[string]bool{
 	"m*=2":         false,
 	"??_*=2":       false,
 	"?[abc]?_*t=2": false,
+	// These use import paths.
+	"github.com/facebookincubator/flog/flog_test=2": true,
+	"github.com/facebookincubator/flog/*=2":         true,
+	"github.com/facebookincubator/...=2":            true,
+	"github.com/facebookincubator/flog/...=2":       true,
+	"github.com/facebookincubator/flog/glog/*=2":    false,
+	"github.com/facebookincubator/fl/...=2":         false,
+	"*/flog_test=2":                                 false,
 }
 
 // Test that vmodule globbing works as advertised.
@@ -407,6 +502,130 @@ func TestLogBacktraceAt(t *testing.T) {
 	}
 }
 
+func TestLogBacktraceAtFunction(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.traceLocation.Set("")
+	// Strip the import path, which differs between GOPATH and module builds.
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	if err := logging.traceLocation.Set(name); err != nil {
+		t.Fatal("error setting log_backtrace_at: ", err)
+	}
+	Info("we want a stack trace here")
+	if !contains("goroutine ") {
+		logging.revertBuffer()
+		t.Fatal("got no trace back; log is ", contents())
+	}
+
+	logging.newBuffers()
+	if err := logging.traceLocation.Set("flog.NoSuchFunction"); err != nil {
+		t.Fatal("error setting log_backtrace_at: ", err)
+	}
+	Info("no stack trace here")
+	if contains("goroutine ") {
+		logging.revertBuffer()
+		t.Fatal("got unexpected trace back; log is ", contents())
+	}
+
+	for _, bad := range []string{"flog", "flog.", ".Info", "flog.go"} {
+		if err := logging.traceLocation.Set(bad); err == nil {
+			t.Errorf("expected error setting log_backtrace_at to %q", bad)
+		}
+	}
+}
+
+func TestErrorStackCooldown(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.errorStacks.Set("")
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local)
+	timeNow = func() time.Time { return now }
+	if err := logging.errorStacks.Set("1m"); err != nil {
+		t.Fatal("error setting error_stack_cooldown: ", err)
+	}
+	logErrors := func() {
+		for i := 0; i < 3; i++ {
+			Error("failed")
+		}
+	}
+	logErrors()
+	if n := strings.Count(contents(), "[running]"); n != 1 {
+		logging.revertBuffer()
+		t.Fatalf("got %d stack traces, want 1; log is %s", n, contents())
+	}
+	Warning("not an error")
+	if n := strings.Count(contents(), "[running]"); n != 1 {
+		logging.revertBuffer()
+		t.Fatalf("got %d stack traces after warning, want 1", n)
+	}
+
+	logging.newBuffers()
+	now = now.Add(time.Minute)
+	logErrors()
+	if n := strings.Count(contents(), "[running]"); n != 1 {
+		logging.revertBuffer()
+		t.Fatalf("got %d stack traces after cooldown, want 1", n)
+	}
+}
+
+func TestExitPolicy(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer SetExitPolicy(ExitPolicy{})
+
+	Critical("default critical")
+	if !contains("default critical") {
+		logging.revertBuffer()
+		t.Fatal("Critical failed")
+	}
+
+	SetExitPolicy(ExitPolicy{Critical: ActionPanic, Fatal: ActionLog})
+	Fatal("fatal without exit")
+	if !contains("fatal without exit") {
+		logging.revertBuffer()
+		t.Fatal("Fatal failed")
+	}
+	func() {
+		defer func() {
+			if s, ok := recover().(string); !ok || !strings.Contains(s, "critical panic") {
+				t.Errorf("Critical should have panicked with its message: %v", s)
+			}
+		}()
+		Critical("critical panic")
+	}()
+	if !contains("critical panic") {
+		logging.revertBuffer()
+		t.Fatal("Critical did not log before panicking")
+	}
+}
+
+func TestExitFunc(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var calls []string
+	SetExitFunc(func(code int) { calls = append(calls, fmt.Sprint("exit ", code)) })
+	defer SetExitFunc(nil)
+	OnFatal(func() { calls = append(calls, "first") })
+	OnFatal(func() { calls = append(calls, "second") })
+	defer func() { exitHooks.onFatal = nil }()
+
+	Fatal("fatal returns")
+	Exit("exit returns")
+	want := []string{"first", "second", "exit 255", "first", "second", "exit 1"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+	if !contains("fatal returns") || !contains("exit returns") {
+		t.Errorf("entries not logged: %q", contents())
+	}
+	if atomic.LoadUint32(&fatalNoStacks) != 0 {
+		t.Error("Exit left the next Fatal without stacks")
+	}
+}
+
 func TestGetVerbosity(t *testing.T) {
 	logging.verbosity.Set("5")
 	defer logging.verbosity.Set("0")
@@ -418,7 +637,8 @@ func TestGetVerbosity(t *testing.T) {
 
 func BenchmarkHeader(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		buf, _, _ := logging.header(infoLog, 0)
+		buf := logging.getBuffer()
+		logging.formatHeader(buf, newEntry(InfoLog, 0, "flog_test.go", 1, ""))
 		logging.putBuffer(buf)
 	}
 }
@@ -426,7 +646,8 @@ func BenchmarkHeader(b *testing.B) {
 func BenchmarkHeaderParallel(b *testing.B) {
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			buf, _, _ := logging.header(infoLog, 0)
+			buf := logging.getBuffer()
+			logging.formatHeader(buf, newEntry(InfoLog, 0, "flog_test.go", 1, ""))
 			logging.putBuffer(buf)
 		}
 	})
@@ -0,0 +1,153 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package flogclient reads and changes the logging settings of running
+// processes through the endpoints served by flog.AdminHandler, for tooling
+// and tests:
+//
+//	c := flogclient.New("http://localhost:8080/debug/flog/")
+//	if _, err := c.SetVerbosity(ctx, "2"); err != nil {
+//		...
+//	}
+//	entries, err := c.Recent(ctx, 50)
+package flogclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/facebookincubator/flog"
+)
+
+// Settings are the verbosity settings of a process, with the syntax of the
+// -v, -vmodule and -vlogger flags.
+type Settings struct {
+	Verbosity string `json:"verbosity"`
+	Vmodule   string `json:"vmodule"`
+	Vlogger   string `json:"vlogger"`
+}
+
+// Client talks to the endpoints of flog.AdminHandler.
+type Client struct {
+	base string
+	hc   *http.Client
+}
+
+// New returns a client for the flog.AdminHandler mounted at baseURL, using
+// http.DefaultClient.
+func New(baseURL string) *Client {
+	return NewWithClient(baseURL, http.DefaultClient)
+}
+
+// NewWithClient is like New but sends the requests with hc.
+func NewWithClient(baseURL string, hc *http.Client) *Client {
+	return &Client{strings.TrimSuffix(baseURL, "/") + "/", hc}
+}
+
+// Settings returns the verbosity settings of the process.
+func (c *Client) Settings(ctx context.Context) (Settings, error) {
+	var s Settings
+	err := c.do(ctx, http.MethodGet, "verbosity", nil, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&s)
+	})
+	return s, err
+}
+
+// SetVerbosity sets the verbosity of the process and returns its settings.
+func (c *Client) SetVerbosity(ctx context.Context, v string) (Settings, error) {
+	return c.set(ctx, "verbosity", v)
+}
+
+// SetVmodule sets the vmodule setting of the process and returns its
+// settings.
+func (c *Client) SetVmodule(ctx context.Context, spec string) (Settings, error) {
+	return c.set(ctx, "vmodule", spec)
+}
+
+// SetVlogger sets the vlogger setting of the process and returns its
+// settings.
+func (c *Client) SetVlogger(ctx context.Context, spec string) (Settings, error) {
+	return c.set(ctx, "vlogger", spec)
+}
+
+// set changes a single verbosity setting.
+func (c *Client) set(ctx context.Context, name, value string) (Settings, error) {
+	body, err := json.Marshal(map[string]string{name: value})
+	if err != nil {
+		return Settings{}, err
+	}
+	var s Settings
+	err = c.do(ctx, http.MethodPut, "verbosity", body, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&s)
+	})
+	return s, err
+}
+
+// Stats returns the output statistics of the process.
+func (c *Client) Stats(ctx context.Context) (flog.StatsSnapshot, error) {
+	var s flog.StatsSnapshot
+	err := c.do(ctx, http.MethodGet, "stats", nil, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&s)
+	})
+	return s, err
+}
+
+// Recent returns up to n of the last entries the process wrote, oldest
+// first, see flog.TailRecent.
+func (c *Client) Recent(ctx context.Context, n int) ([]flog.Entry, error) {
+	var entries []flog.Entry
+	err := c.do(ctx, http.MethodGet, "recent?n="+strconv.Itoa(n), nil, func(r io.Reader) error {
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, 1<<20)
+		for s.Scan() {
+			e, err := flog.ParseEntry(s.Bytes())
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return s.Err()
+	})
+	return entries, err
+}
+
+// do sends a request for the endpoint and passes the body of a successful
+// response to decode.
+func (c *Client) do(ctx context.Context, method, endpoint string, body []byte, decode func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("flogclient: %s %s: %s: %s", method, endpoint, resp.Status, bytes.TrimSpace(msg))
+	}
+	return decode(resp.Body)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flogclient
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(flog.AdminHandler())
+	defer srv.Close()
+	c := New(srv.URL + "/debug/flog")
+	ctx := context.Background()
+
+	s, err := c.SetVerbosity(ctx, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer (&flog.Config{}).Set()
+	if s.Verbosity != "2" || flog.GetVerbosity() != 2 {
+		t.Errorf("SetVerbosity: settings %+v, verbosity %d", s, flog.GetVerbosity())
+	}
+	if _, err := c.SetVmodule(ctx, "bad"); err == nil {
+		t.Error("SetVmodule accepted a bad spec")
+	}
+	if s, err := c.Settings(ctx); err != nil || s.Verbosity != "2" {
+		t.Errorf("Settings() = %+v, %v", s, err)
+	}
+
+	flog.KeepRecent(10)
+	defer flog.KeepRecent(0)
+	flog.With(flog.F("k", "v")).Warning("recent entry")
+	entries, err := c.Recent(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Message != "recent entry" || entries[0].Severity != flog.WarningLog || len(entries[0].Fields) != 1 {
+		t.Errorf("Recent(1) = %+v", entries)
+	}
+
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Lines["WARNING"] < 1 {
+		t.Errorf("Stats() = %+v, want a WARNING line", stats)
+	}
+}
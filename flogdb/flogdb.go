@@ -0,0 +1,114 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package flogdb adapts the logging interfaces of database and cache drivers
+// to flog, so their internal messages come out as leveled flog entries with
+// the driver's own file:line.
+//
+// The adapters satisfy the driver interfaces structurally, so this package
+// does not depend on any driver. Wire them up like this:
+//
+//	mysql.SetLogger(flogdb.MySQL{})
+//	redis.SetLogger(flogdb.Redis{})
+//	config.Logger = pgx.LoggerFunc(func(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+//		flogdb.Pgx(ctx, int(level), msg, data)
+//	})
+package flogdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/facebookincubator/flog"
+)
+
+// MySQL implements the Logger interface of github.com/go-sql-driver/mysql.
+// The driver only logs errors it cannot return to the caller, such as
+// broken connections, so every message is logged as an Error.
+type MySQL struct{}
+
+// Print is part of the mysql.Logger interface.
+func (MySQL) Print(v ...interface{}) {
+	flog.ErrorDepth(1, v...)
+}
+
+// Redis implements the Logging interface of github.com/go-redis/redis.
+// The client logs recoverable problems such as failed reconnects and pool
+// exhaustion, so every message is logged as a Warning.
+type Redis struct{}
+
+// Printf is part of the redis internal.Logging interface.
+func (Redis) Printf(ctx context.Context, format string, v ...interface{}) {
+	flog.WarningDepth(1, fmt.Sprintf(format, v...))
+}
+
+// Levels of github.com/jackc/pgx, as passed to Pgx.
+const (
+	PgxLogLevelTrace = 6
+	PgxLogLevelDebug = 5
+	PgxLogLevelInfo  = 4
+	PgxLogLevelWarn  = 3
+	PgxLogLevelError = 2
+	PgxLogLevelNone  = 1
+)
+
+// PgxVerbosity is the V level at which pgx's Debug messages are logged.
+// Trace messages are logged one level higher.
+const PgxVerbosity flog.Level = 2
+
+// Pgx logs a message of the github.com/jackc/pgx Logger interface. pgx's
+// level type can't be named without importing pgx, so callers convert it to
+// an int in a pgx.LoggerFunc as shown in the package documentation.
+// Error, Warn and Info messages go to the matching flog severity, Debug and
+// Trace messages are logged as Info if V(PgxVerbosity) and
+// V(PgxVerbosity+1) respectively are enabled. The data is appended to the
+// message as key=value pairs, sorted by key.
+func Pgx(ctx context.Context, level int, msg string, data map[string]interface{}) {
+	switch level {
+	case PgxLogLevelError:
+		flog.ErrorDepth(2, pgxMessage(msg, data))
+	case PgxLogLevelWarn:
+		flog.WarningDepth(2, pgxMessage(msg, data))
+	case PgxLogLevelInfo:
+		flog.InfoDepth(2, pgxMessage(msg, data))
+	case PgxLogLevelDebug:
+		if flog.VDepth(2, PgxVerbosity) {
+			flog.InfoDepth(2, pgxMessage(msg, data))
+		}
+	case PgxLogLevelTrace:
+		if flog.VDepth(2, PgxVerbosity+1) {
+			flog.InfoDepth(2, pgxMessage(msg, data))
+		}
+	}
+}
+
+// pgxMessage renders msg followed by data as sorted key=value pairs.
+func pgxMessage(msg string, data map[string]interface{}) string {
+	if len(data) == 0 {
+		return msg
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, data[k])
+	}
+	return b.String()
+}
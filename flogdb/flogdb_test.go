@@ -0,0 +1,66 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flogdb
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+func capture(f func()) string {
+	var b bytes.Buffer
+	flog.SetOutput(&b)
+	defer flog.SetOutput(os.Stderr)
+	f()
+	return b.String()
+}
+
+func TestMySQL(t *testing.T) {
+	out := capture(func() { MySQL{}.Print("packets.go:36: ", "unexpected EOF") })
+	if !strings.HasPrefix(out, "E") || !strings.Contains(out, "flogdb_test.go:") || !strings.Contains(out, "unexpected EOF") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+}
+
+func TestRedis(t *testing.T) {
+	out := capture(func() { Redis{}.Printf(context.Background(), "redis: %s", "pool timeout") })
+	if !strings.HasPrefix(out, "W") || !strings.Contains(out, "redis: pool timeout") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+}
+
+func TestPgx(t *testing.T) {
+	data := map[string]interface{}{"sql": "select 1", "args": []interface{}{}}
+	out := capture(func() { Pgx(context.Background(), PgxLogLevelError, "Query", data) })
+	if !strings.HasPrefix(out, "E") || !strings.Contains(out, "Query args=[] sql=select 1") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+	out = capture(func() { Pgx(context.Background(), PgxLogLevelDebug, "Query", data) })
+	if out != "" {
+		t.Errorf("debug message logged without verbosity: %q", out)
+	}
+	cfg := &flog.Config{Verbosity: "2"}
+	cfg.Set()
+	defer (&flog.Config{Verbosity: "0"}).Set()
+	out = capture(func() { Pgx(context.Background(), PgxLogLevelDebug, "Query", data) })
+	if !strings.HasPrefix(out, "I") {
+		t.Errorf("debug message not logged at verbosity 2: %q", out)
+	}
+}
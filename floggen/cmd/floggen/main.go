@@ -0,0 +1,44 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Command floggen generates strongly-typed logging functions from the event
+// definitions in a JSON file, as described in package floggen:
+//
+//	//go:generate go run github.com/facebookincubator/flog/floggen/cmd/floggen -in events.json -out events_flog.go
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/facebookincubator/flog"
+	"github.com/facebookincubator/flog/floggen"
+)
+
+func main() {
+	in := flag.String("in", "events.json", "file defining the events")
+	out := flag.String("out", "events_flog.go", "file to write the logging functions to")
+	flag.Parse()
+	src, err := ioutil.ReadFile(*in)
+	if err != nil {
+		flog.Exit(err)
+	}
+	code, err := floggen.Generate(src)
+	if err != nil {
+		flog.Exitf("%s: %v", *in, err)
+	}
+	if err := ioutil.WriteFile(*out, code, 0644); err != nil {
+		flog.Exit(err)
+	}
+}
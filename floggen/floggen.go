@@ -0,0 +1,224 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package floggen generates strongly-typed logging functions from event
+// definitions, for compile-time checked structured logging. Events are
+// defined in a JSON file:
+//
+//	{
+//		"package": "billing",
+//		"events": [{
+//			"name": "PaymentFailed",
+//			"severity": "error",
+//			"message": "payment failed",
+//			"fields": [
+//				{"name": "user_id", "type": "int64"},
+//				{"name": "latency", "type": "time.Duration"}
+//			]
+//		}]
+//	}
+//
+// and each becomes a function logging the event with its fields, plus an
+// event field holding its name:
+//
+//	func LogPaymentFailed(userID int64, latency time.Duration)
+//
+// The floggen command in cmd/floggen runs Generate, as from go:generate.
+package floggen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/facebookincubator/flog"
+)
+
+// Definitions are the events of a package.
+type Definitions struct {
+	Package string  `json:"package"`
+	Events  []Event `json:"events"`
+}
+
+// Event defines an event.
+type Event struct {
+	// Name is the name of the event, an exported Go identifier once
+	// prefixed with Log.
+	Name string `json:"name"`
+	// Severity is the name of the severity the event is logged at, as
+	// accepted by flog.ParseSeverity.
+	Severity string `json:"severity"`
+	// Message is the message of the entries, the name by default.
+	Message string `json:"message,omitempty"`
+	// Doc, if not empty, is added to the doc comment of the function.
+	Doc    string  `json:"doc,omitempty"`
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Field defines a field of an event.
+type Field struct {
+	// Name is the key of the field, such as user_id. The parameter of the
+	// function is named after it, as userID.
+	Name string `json:"name"`
+	// Type is the Go type of the field, one of those of Types.
+	Type string `json:"type"`
+}
+
+// types maps the supported field types to the package they need, if any.
+var types = map[string]string{
+	"string":        "",
+	"bool":          "",
+	"int":           "",
+	"int32":         "",
+	"int64":         "",
+	"uint":          "",
+	"uint32":        "",
+	"uint64":        "",
+	"float32":       "",
+	"float64":       "",
+	"error":         "",
+	"time.Duration": "time",
+	"time.Time":     "time",
+	"flog.ByteSize": "",
+}
+
+// Types returns the supported field types.
+func Types() []string {
+	list := make([]string, 0, len(types))
+	for t := range types {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Generate returns the Go source of the logging functions of the events
+// defined by src, in JSON.
+func Generate(src []byte) ([]byte, error) {
+	var defs Definitions
+	d := json.NewDecoder(bytes.NewReader(src))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&defs); err != nil {
+		return nil, err
+	}
+	return defs.Generate()
+}
+
+// Generate returns the Go source of the logging functions of defs.
+func (defs *Definitions) Generate() ([]byte, error) {
+	if !token.IsIdentifier(defs.Package) {
+		return nil, fmt.Errorf("invalid package name %q", defs.Package)
+	}
+	var body bytes.Buffer
+	needTime := false
+	names := make(map[string]bool)
+	for _, e := range defs.Events {
+		if !token.IsIdentifier(e.Name) || names[e.Name] {
+			return nil, fmt.Errorf("invalid or duplicate event name %q", e.Name)
+		}
+		names[e.Name] = true
+		s, err := flog.ParseSeverity(e.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %v", e.Name, err)
+		}
+		var params, fields []string
+		keys := map[string]bool{"event": true}
+		for _, f := range e.Fields {
+			pkg, ok := types[f.Type]
+			if !ok {
+				return nil, fmt.Errorf("event %s: field %s has unsupported type %q", e.Name, f.Name, f.Type)
+			}
+			param := paramName(f.Name)
+			if param == "" || keys[f.Name] {
+				return nil, fmt.Errorf("event %s: invalid or duplicate field name %q", e.Name, f.Name)
+			}
+			keys[f.Name] = true
+			needTime = needTime || pkg == "time"
+			params = append(params, param+" "+f.Type)
+			fields = append(fields, fmt.Sprintf("flog.F(%q, %s)", f.Name, param))
+		}
+		msg := e.Message
+		if msg == "" {
+			msg = e.Name
+		}
+		fmt.Fprintf(&body, "\n// Log%s logs a %s event at %v.\n", e.Name, e.Name, s)
+		if e.Doc != "" {
+			for _, line := range strings.Split(strings.TrimSpace(e.Doc), "\n") {
+				fmt.Fprintf(&body, "// %s\n", strings.TrimSpace(line))
+			}
+		}
+		fmt.Fprintf(&body, "func Log%s(%s) {\n", e.Name, strings.Join(params, ", "))
+		fmt.Fprintf(&body, "\tflog.With(\n\t\tflog.F(\"event\", %q),\n", e.Name)
+		for _, f := range fields {
+			fmt.Fprintf(&body, "\t\t%s,\n", f)
+		}
+		fmt.Fprintf(&body, "\t).LogDepth(flog.%s, 1, %q)\n}\n", severityConst[s], msg)
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by floggen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", defs.Package)
+	if needTime {
+		fmt.Fprintf(&out, "\t\"time\"\n\n")
+	}
+	fmt.Fprintf(&out, "\t\"github.com/facebookincubator/flog\"\n)\n")
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// severityConst are the names of the severity constants of flog.
+var severityConst = map[flog.Severity]string{
+	flog.DebugLog:    "DebugLog",
+	flog.InfoLog:     "InfoLog",
+	flog.WarningLog:  "WarningLog",
+	flog.ErrorLog:    "ErrorLog",
+	flog.CriticalLog: "CriticalLog",
+	flog.FatalLog:    "FatalLog",
+}
+
+// paramName returns the parameter name for the field key, in camel case,
+// or an empty string if key makes no valid parameter name.
+func paramName(key string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range key {
+		switch {
+		case r == '_' || r == '-' || r == '.':
+			upper = b.Len() > 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r) && b.Len() > 0:
+			if upper {
+				b.WriteString(strings.ToUpper(string(r)))
+			} else {
+				b.WriteRune(r)
+			}
+			upper = false
+		default:
+			return ""
+		}
+	}
+	name := b.String()
+	// Common initialisms read better in upper case, as in userID.
+	for _, suffix := range []string{"Id", "Url", "Ip"} {
+		if strings.HasSuffix(name, suffix) {
+			name = name[:len(name)-len(suffix)] + strings.ToUpper(suffix)
+		}
+	}
+	if token.IsKeyword(name) || name == "flog" || name == "time" {
+		name += "_"
+	}
+	return name
+}
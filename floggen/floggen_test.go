@@ -0,0 +1,79 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package floggen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const events = `{
+	"package": "billing",
+	"events": [{
+		"name": "PaymentFailed",
+		"severity": "error",
+		"message": "payment failed",
+		"doc": "It is logged once per attempt.",
+		"fields": [
+			{"name": "user_id", "type": "int64"},
+			{"name": "type", "type": "string"},
+			{"name": "latency", "type": "time.Duration"}
+		]
+	}, {
+		"name": "Started",
+		"severity": "INFO"
+	}]
+}`
+
+func TestGenerate(t *testing.T) {
+	code, err := Generate([]byte(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "events_flog.go", code, 0); err != nil {
+		t.Fatalf("generated code doesn't parse: %v\n%s", err, code)
+	}
+	for _, want := range []string{
+		"// Code generated by floggen. DO NOT EDIT.\n\npackage billing\n",
+		"\t\"time\"\n\n\t\"github.com/facebookincubator/flog\"\n",
+		"// LogPaymentFailed logs a PaymentFailed event at ERROR.\n// It is logged once per attempt.\n" +
+			"func LogPaymentFailed(userID int64, type_ string, latency time.Duration) {\n",
+		"\t\tflog.F(\"event\", \"PaymentFailed\"),\n\t\tflog.F(\"user_id\", userID),\n\t\tflog.F(\"type\", type_),\n",
+		"\t).LogDepth(flog.ErrorLog, 1, \"payment failed\")\n",
+		"func LogStarted() {\n",
+		"\t).LogDepth(flog.InfoLog, 1, \"Started\")\n",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("missing %q in:\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"package": "billing", "events": [{"name": "A", "severity": "loud"}]}`,
+		`{"package": "billing", "events": [{"name": "A", "severity": "info", "fields": [{"name": "n", "type": "complex128"}]}]}`,
+		`{"package": "billing", "events": [{"name": "A", "severity": "info", "fields": [{"name": "event", "type": "int"}]}]}`,
+		`{"package": "billing", "events": [{"name": "A", "severity": "info"}, {"name": "A", "severity": "info"}]}`,
+		`{"package": "bill-ing", "events": []}`,
+		`{"package": "billing", "evnts": []}`,
+	} {
+		if _, err := Generate([]byte(src)); err == nil {
+			t.Errorf("generated code for %s", src)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package floggrpc adapts the logging interface of gRPC to flog, so that the
+// logs of gRPC itself obey the flog verbosity and land in the same stream,
+// with the file:line of the gRPC code that logged them. Like flogkafka it
+// satisfies the interfaces structurally and does not depend on gRPC:
+//
+//	grpclog.SetLoggerV2(floggrpc.Logger{Verbosity: 1})
+package floggrpc
+
+import (
+	"fmt"
+
+	"github.com/facebookincubator/flog"
+)
+
+// Logger implements the LoggerV2 and DepthLoggerV2 interfaces of
+// google.golang.org/grpc/grpclog. gRPC logs much of its progress as Info, so
+// Info messages are guarded by V(Verbosity). Warning, Error and Fatal
+// messages are logged as such.
+type Logger struct {
+	Verbosity flog.Level
+}
+
+// Info is part of the grpclog.LoggerV2 interface.
+func (l Logger) Info(args ...interface{}) {
+	if flog.VDepth(1, l.Verbosity) {
+		flog.InfoDepth(1, fmt.Sprint(args...))
+	}
+}
+
+// Infoln is part of the grpclog.LoggerV2 interface.
+func (l Logger) Infoln(args ...interface{}) {
+	if flog.VDepth(1, l.Verbosity) {
+		flog.InfoDepth(1, fmt.Sprintln(args...))
+	}
+}
+
+// Infof is part of the grpclog.LoggerV2 interface.
+func (l Logger) Infof(format string, args ...interface{}) {
+	if flog.VDepth(1, l.Verbosity) {
+		flog.InfoDepth(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warning is part of the grpclog.LoggerV2 interface.
+func (Logger) Warning(args ...interface{}) {
+	flog.WarningDepth(1, fmt.Sprint(args...))
+}
+
+// Warningln is part of the grpclog.LoggerV2 interface.
+func (Logger) Warningln(args ...interface{}) {
+	flog.WarningDepth(1, fmt.Sprintln(args...))
+}
+
+// Warningf is part of the grpclog.LoggerV2 interface.
+func (Logger) Warningf(format string, args ...interface{}) {
+	flog.WarningDepth(1, fmt.Sprintf(format, args...))
+}
+
+// Error is part of the grpclog.LoggerV2 interface.
+func (Logger) Error(args ...interface{}) {
+	flog.ErrorDepth(1, fmt.Sprint(args...))
+}
+
+// Errorln is part of the grpclog.LoggerV2 interface.
+func (Logger) Errorln(args ...interface{}) {
+	flog.ErrorDepth(1, fmt.Sprintln(args...))
+}
+
+// Errorf is part of the grpclog.LoggerV2 interface.
+func (Logger) Errorf(format string, args ...interface{}) {
+	flog.ErrorDepth(1, fmt.Sprintf(format, args...))
+}
+
+// Fatal is part of the grpclog.LoggerV2 interface.
+func (Logger) Fatal(args ...interface{}) {
+	flog.FatalDepth(1, fmt.Sprint(args...))
+}
+
+// Fatalln is part of the grpclog.LoggerV2 interface.
+func (Logger) Fatalln(args ...interface{}) {
+	flog.FatalDepth(1, fmt.Sprintln(args...))
+}
+
+// Fatalf is part of the grpclog.LoggerV2 interface.
+func (Logger) Fatalf(format string, args ...interface{}) {
+	flog.FatalDepth(1, fmt.Sprintf(format, args...))
+}
+
+// V is part of the grpclog.LoggerV2 interface. gRPC level l is flog level
+// Verbosity+l.
+func (l Logger) V(level int) bool {
+	return bool(flog.VDepth(1, l.Verbosity+flog.Level(level)))
+}
+
+// InfoDepth is part of the grpclog.DepthLoggerV2 interface. Arguments are
+// handled in the manner of fmt.Println, as for all the Depth methods.
+func (l Logger) InfoDepth(depth int, args ...interface{}) {
+	if flog.VDepth(depth+1, l.Verbosity) {
+		flog.InfoDepth(depth+1, fmt.Sprintln(args...))
+	}
+}
+
+// WarningDepth is part of the grpclog.DepthLoggerV2 interface.
+func (Logger) WarningDepth(depth int, args ...interface{}) {
+	flog.WarningDepth(depth+1, fmt.Sprintln(args...))
+}
+
+// ErrorDepth is part of the grpclog.DepthLoggerV2 interface.
+func (Logger) ErrorDepth(depth int, args ...interface{}) {
+	flog.ErrorDepth(depth+1, fmt.Sprintln(args...))
+}
+
+// FatalDepth is part of the grpclog.DepthLoggerV2 interface.
+func (Logger) FatalDepth(depth int, args ...interface{}) {
+	flog.FatalDepth(depth+1, fmt.Sprintln(args...))
+}
@@ -0,0 +1,62 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package floggrpc
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+func capture(f func()) string {
+	var b bytes.Buffer
+	flog.SetOutput(&b)
+	defer flog.SetOutput(os.Stderr)
+	f()
+	return b.String()
+}
+
+// component stands for the gRPC code logging through a depth helper.
+func component(l Logger, msg string) {
+	l.WarningDepth(1, msg)
+}
+
+func TestLogger(t *testing.T) {
+	l := Logger{Verbosity: 1}
+	out := capture(func() { l.Infof("Subchannel Connectivity change to %s", "READY") })
+	if out != "" || l.V(0) {
+		t.Errorf("Info logged without verbosity: %q", out)
+	}
+	out = capture(func() { Logger{}.Infoln("parsed scheme:", "dns") })
+	if !strings.HasPrefix(out, "I") || !strings.Contains(out, "floggrpc_test.go:") || !strings.HasSuffix(out, "] parsed scheme: dns\n") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+	out = capture(func() { l.Errorf("transport: %v", "connection reset") })
+	if !strings.HasPrefix(out, "E") || !strings.Contains(out, "connection reset") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+}
+
+func TestDepth(t *testing.T) {
+	out := capture(func() {
+		component(Logger{}, "addrConn: failed to dial")
+	})
+	if !strings.HasPrefix(out, "W") || !strings.Contains(out, "floggrpc_test.go:57]") {
+		t.Errorf("wrong caller in %q", out)
+	}
+}
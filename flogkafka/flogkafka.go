@@ -0,0 +1,84 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package flogkafka adapts the logging interfaces of Kafka client libraries
+// to flog. Like flogdb it satisfies the interfaces structurally and does not
+// depend on the clients:
+//
+//	sarama.Logger = flogkafka.Sarama{Verbosity: 1}
+//	sarama.DebugLogger = flogkafka.Sarama{Verbosity: 2}
+//
+//	w := &kafka.Writer{
+//		Logger:      flogkafka.KafkaGo{Verbosity: 1},
+//		ErrorLogger: flogkafka.KafkaGoErrors{},
+//	}
+package flogkafka
+
+import (
+	"fmt"
+
+	"github.com/facebookincubator/flog"
+)
+
+// Sarama implements the StdLogger interface of github.com/Shopify/sarama.
+// Sarama reports errors through return values and channels and only logs
+// progress, so messages are logged as Info guarded by V(Verbosity).
+type Sarama struct {
+	Verbosity flog.Level
+}
+
+// Print is part of the sarama.StdLogger interface.
+func (s Sarama) Print(v ...interface{}) {
+	if flog.VDepth(1, s.Verbosity) {
+		flog.InfoDepth(1, v...)
+	}
+}
+
+// Printf is part of the sarama.StdLogger interface.
+func (s Sarama) Printf(format string, v ...interface{}) {
+	if flog.VDepth(1, s.Verbosity) {
+		flog.InfoDepth(1, fmt.Sprintf(format, v...))
+	}
+}
+
+// Println is part of the sarama.StdLogger interface.
+func (s Sarama) Println(v ...interface{}) {
+	if flog.VDepth(1, s.Verbosity) {
+		flog.InfoDepth(1, fmt.Sprintln(v...))
+	}
+}
+
+// KafkaGo implements the Logger interface of github.com/segmentio/kafka-go
+// for its Logger settings. Messages are logged as Info guarded by
+// V(Verbosity).
+type KafkaGo struct {
+	Verbosity flog.Level
+}
+
+// Printf is part of the kafka.Logger interface.
+func (k KafkaGo) Printf(format string, v ...interface{}) {
+	if flog.VDepth(1, k.Verbosity) {
+		flog.InfoDepth(1, fmt.Sprintf(format, v...))
+	}
+}
+
+// KafkaGoErrors implements the Logger interface of
+// github.com/segmentio/kafka-go for its ErrorLogger settings. Messages are
+// logged as Error.
+type KafkaGoErrors struct{}
+
+// Printf is part of the kafka.Logger interface.
+func (KafkaGoErrors) Printf(format string, v ...interface{}) {
+	flog.ErrorDepth(1, fmt.Sprintf(format, v...))
+}
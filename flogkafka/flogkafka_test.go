@@ -0,0 +1,54 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flogkafka
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+func capture(f func()) string {
+	var b bytes.Buffer
+	flog.SetOutput(&b)
+	defer flog.SetOutput(os.Stderr)
+	f()
+	return b.String()
+}
+
+func TestSarama(t *testing.T) {
+	out := capture(func() { Sarama{Verbosity: 1}.Printf("client/metadata fetching metadata for %d topics", 3) })
+	if out != "" {
+		t.Errorf("message logged without verbosity: %q", out)
+	}
+	out = capture(func() { Sarama{}.Println("Connected to broker", "kafka-1:9092") })
+	if !strings.HasPrefix(out, "I") || !strings.Contains(out, "flogkafka_test.go:") || !strings.HasSuffix(out, "Connected to broker kafka-1:9092\n") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+}
+
+func TestKafkaGo(t *testing.T) {
+	out := capture(func() { KafkaGo{}.Printf("writing %d messages", 2) })
+	if !strings.HasPrefix(out, "I") || !strings.Contains(out, "writing 2 messages") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+	out = capture(func() { KafkaGoErrors{}.Printf("error writing messages: %s", "leader not available") })
+	if !strings.HasPrefix(out, "E") || !strings.Contains(out, "leader not available") {
+		t.Errorf("unexpected log line: %q", out)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flogtest
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+// Captured records the entries logged during a test, as created by Capture.
+type Captured struct {
+	t testing.TB
+
+	mu      sync.Mutex
+	entries []flog.Entry
+	out     bytes.Buffer
+	stop    sync.Once
+	restore func()
+}
+
+// Capture records the entries logged for the duration of the test, instead
+// of writing them to the output. The output is restored and the recording
+// stops when the test and its subtests complete, or on Go versions before
+// 1.14, when Stop is called, so tests no longer need to swap buffers
+// themselves:
+//
+//	c := flogtest.Capture(t)
+//	defer c.Stop()
+//	connect(addr)
+//	c.AssertLogged(flog.WarningLog, "retrying")
+func Capture(t testing.TB) *Captured {
+	c := &Captured{t: t}
+	prev := flog.GetOutput()
+	flog.SetOutput(c)
+	remove := flog.AddHook(c.record)
+	c.restore = func() {
+		remove()
+		flog.SetOutput(prev)
+	}
+	cleanup(t, c.Stop)
+	return c
+}
+
+// Stop stops recording and restores the output. It may be called more than
+// once.
+func (c *Captured) Stop() {
+	c.stop.Do(c.restore)
+}
+
+// record is the hook recording e.
+func (c *Captured) record(e flog.Entry) {
+	e.Fields = append([]flog.Field(nil), e.Fields...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, e)
+}
+
+// Write is part of the io.Writer interface. It keeps what flog writes for
+// Output.
+func (c *Captured) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+// Entries returns the entries logged so far, in order.
+func (c *Captured) Entries() []flog.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]flog.Entry(nil), c.entries...)
+}
+
+// Output returns the text written so far, as it would have been written to
+// the output.
+func (c *Captured) Output() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.String()
+}
+
+// Reset forgets the entries logged so far.
+func (c *Captured) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+	c.out.Reset()
+}
+
+// Logged reports whether an entry of severity s whose message contains
+// substr was logged.
+func (c *Captured) Logged(s flog.Severity, substr string) bool {
+	for _, e := range c.Entries() {
+		if e.Severity == s && strings.Contains(e.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertLogged marks the test as failed if no entry of severity s whose
+// message contains substr was logged.
+func (c *Captured) AssertLogged(s flog.Severity, substr string) {
+	c.t.Helper()
+	if !c.Logged(s, substr) {
+		c.t.Errorf("flogtest: no %v entry containing %q in:\n%s", s, substr, c.Output())
+	}
+}
+
+// AssertNotLogged marks the test as failed if an entry of severity s whose
+// message contains substr was logged.
+func (c *Captured) AssertNotLogged(s flog.Severity, substr string) {
+	c.t.Helper()
+	if c.Logged(s, substr) {
+		c.t.Errorf("flogtest: unexpected %v entry containing %q in:\n%s", s, substr, c.Output())
+	}
+}
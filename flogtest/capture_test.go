@@ -0,0 +1,67 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package flogtest
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+func TestCapture(t *testing.T) {
+	var out bytes.Buffer
+	flog.SetOutput(&out)
+	defer flog.SetOutput(os.Stderr)
+
+	t.Run("capture", func(t *testing.T) {
+		c := Capture(t)
+		defer c.Stop()
+		flog.With(flog.F("peer", "db1")).Warning("retrying")
+		flog.Error("gave up")
+		c.AssertLogged(flog.WarningLog, "retry")
+		c.AssertNotLogged(flog.InfoLog, "retry")
+		entries := c.Entries()
+		if len(entries) != 2 || len(entries[0].Fields) != 1 || entries[0].Fields[0].Key != "peer" {
+			t.Errorf("wrong entries: %+v", entries)
+		}
+		if !strings.Contains(c.Output(), "gave up") {
+			t.Errorf("output not captured: %q", c.Output())
+		}
+
+		r := &recorder{TB: t}
+		rc := &Captured{t: r}
+		rc.AssertLogged(flog.ErrorLog, "gave up")
+		if len(r.errors) != 1 {
+			t.Errorf("got %d failures for a missing entry, want 1", len(r.errors))
+		}
+
+		c.Reset()
+		if c.Logged(flog.ErrorLog, "gave up") || c.Output() != "" {
+			t.Error("Reset kept entries")
+		}
+	})
+
+	if out.Len() != 0 {
+		t.Errorf("captured entries were written to the output: %q", out.String())
+	}
+	flog.Info("after")
+	if !strings.Contains(out.String(), "after") {
+		t.Errorf("output not restored: %q", out.String())
+	}
+}
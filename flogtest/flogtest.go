@@ -0,0 +1,109 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package flogtest contains helpers for testing code that logs with flog.
+package flogtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+// StrictMode fails a test when an Error or Critical log is written that the
+// test did not expect. It is created by Strict.
+type StrictMode struct {
+	t testing.TB
+
+	mu       sync.Mutex
+	expected []string
+	panics   bool
+	stop     sync.Once
+	remove   func()
+}
+
+// Strict puts flog in strict mode for the duration of the test: every Error
+// or Critical log whose message or fields contain none of the expected
+// substrings marks t as failed. Logs are still written to the output,
+// whatever its format. Strict mode ends when the test and its subtests
+// complete, or on Go versions before 1.14, when Stop is called:
+//
+//	defer flogtest.Strict(t).Expect("connection reset").Stop()
+func Strict(t testing.TB) *StrictMode {
+	s := &StrictMode{t: t}
+	s.remove = flog.AddHook(s.check)
+	cleanup(t, s.Stop)
+	return s
+}
+
+// Stop ends strict mode. It may be called more than once.
+func (s *StrictMode) Stop() {
+	s.stop.Do(s.remove)
+}
+
+// cleanup registers f to be called when t and its subtests complete, if t
+// supports it. testing.TB has Cleanup since Go 1.14; on earlier versions the
+// helpers must be stopped by the test.
+func cleanup(t testing.TB, f func()) {
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(f)
+	}
+}
+
+// Expect allows Error and Critical logs containing substr.
+func (s *StrictMode) Expect(substr string) *StrictMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expected = append(s.expected, substr)
+	return s
+}
+
+// Panic makes unexpected logs panic in the logging goroutine instead of
+// failing the test, so the stack shows where the log came from.
+func (s *StrictMode) Panic() *StrictMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.panics = true
+	return s
+}
+
+// check is the hook checking that e is expected if it is an Error or
+// Critical entry.
+func (s *StrictMode) check(e flog.Entry) {
+	if e.Severity != flog.ErrorLog && e.Severity != flog.CriticalLog {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d] %s", e.File, e.Line, e.Message)
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	line := b.String()
+	s.mu.Lock()
+	panics := s.panics
+	for _, substr := range s.expected {
+		if strings.Contains(line, substr) {
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.mu.Unlock()
+	if panics {
+		panic("flogtest: unexpected log: " + line)
+	}
+	s.t.Errorf("flogtest: unexpected log: %s", line)
+}
@@ -0,0 +1,93 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flogtest
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+// recorder is a testing.TB that records failures instead of failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestStrict(t *testing.T) {
+	var out bytes.Buffer
+	flog.SetOutput(&out)
+	defer flog.SetOutput(os.Stderr)
+
+	r := &recorder{TB: t}
+	defer Strict(r).Expect("connection reset").Stop()
+	flog.Info("all good")
+	flog.Warning("retrying")
+	flog.Error("read: connection reset by peer")
+	if len(r.errors) != 0 {
+		t.Fatalf("expected logs failed the test: %v", r.errors)
+	}
+	flog.Critical("disk full")
+	if len(r.errors) != 1 {
+		t.Fatalf("got %d failures for an unexpected log, want 1", len(r.errors))
+	}
+	if !strings.Contains(out.String(), "disk full") {
+		t.Errorf("log was not forwarded to the previous output: %q", out.String())
+	}
+}
+
+func TestStrictPanic(t *testing.T) {
+	var out bytes.Buffer
+	flog.SetOutput(&out)
+	defer flog.SetOutput(os.Stderr)
+
+	defer Strict(t).Panic().Stop()
+	defer func() {
+		if s, ok := recover().(string); !ok || !strings.Contains(s, "boom") {
+			t.Errorf("unexpected error should have panicked: %v", s)
+		}
+		// flog must still be usable after the panic.
+		flog.Info("after panic")
+	}()
+	flog.Error("boom")
+}
+
+func TestStrictFormats(t *testing.T) {
+	var out bytes.Buffer
+	flog.SetOutput(&out)
+	defer flog.SetOutput(os.Stderr)
+	defer flog.SetFormat(flog.FormatText)
+	defer flog.SetSeverityPrefix(false)
+
+	r := &recorder{TB: t}
+	defer Strict(r).Stop()
+	flog.SetFormat(flog.FormatJSON)
+	flog.Error("json")
+	flog.SetFormat(flog.FormatLogfmt)
+	flog.Critical("logfmt")
+	flog.SetFormat(flog.FormatText)
+	flog.SetSeverityPrefix(true)
+	flog.Error("prefixed")
+	if len(r.errors) != 3 {
+		t.Errorf("got %d failures for unexpected logs, want 3", len(r.errors))
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// bufferedOutput buffers the writes to the output, see SetBuffer. It is
+// installed as logging.out, in front of the output set by SetOutput.
+type bufferedOutput struct {
+	mu  sync.Mutex
+	out io.Writer
+	buf *bufio.Writer
+	// done is closed to stop the periodic flushes.
+	done chan struct{}
+}
+
+// SetBuffer makes flog buffer up to size bytes of output in memory, which
+// trades durability for throughput: the buffer is written out when it is
+// full, when Flush is called, when a Critical or Fatal entry is logged and,
+// if interval is positive, every interval. Entries are never split across
+// writes. A size of zero, the default, turns buffering off after flushing.
+// Outputs implementing SeverityWriter are not buffered.
+// This function is safe to use concurrently.
+func SetBuffer(size int, interval time.Duration) {
+	ensureInit()
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	out := logging.out
+	if b := logging.buffered; b != nil {
+		out = b.stop()
+		logging.mem.release(b.buf.Size())
+		logging.buffered = nil
+	}
+	if size > 0 {
+		b := &bufferedOutput{out: out, buf: bufio.NewWriterSize(out, size)}
+		logging.mem.hold(b.buf.Size())
+		if interval > 0 {
+			b.done = make(chan struct{})
+			go b.flushEvery(interval, b.done)
+		}
+		logging.buffered = b
+		out = b
+	}
+	logging.out = out
+}
+
+// Flush writes out the entries buffered by SetBuffer or queued by the
+// asynchronous mode, then flushes or syncs the output and the outputs added
+// with AddOutput, such as files. The pending summary entries of sampling,
+// deduplication and the line budgets of the contexts that are done are
+// logged first, see SetSampleRate, SetDedupWindow and LimitContext.
+func Flush() {
+	sweepBudgets()
+	logging.sampler.flush()
+	logging.dedup.flush()
+	logging.mu.Lock()
+	if logging.async.active() {
+		logging.async.drain()
+	}
+	outs := []interface{}{logging.out}
+	outs = append(outs, logging.severityOut.writers()...)
+	for _, o := range logging.outputs {
+		outs = append(outs, o.w)
+	}
+	logging.mu.Unlock()
+	flushOutputs(outs, 0)
+	saveCounters()
+}
+
+// Write is part of the io.Writer interface.
+func (b *bufferedOutput) Write(p []byte) (int, error) {
+	return b.WriteSeverity(InfoLog, p)
+}
+
+// WriteSeverity is part of the SeverityWriter interface. It flushes the
+// buffer after Critical and Fatal entries.
+func (b *bufferedOutput) WriteSeverity(s Severity, p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.out.(SeverityWriter); ok {
+		return writeSeverity(b.out, s, p)
+	}
+	if len(p) > b.buf.Available() {
+		b.buf.Flush()
+	}
+	var n int
+	var err error
+	if len(p) > b.buf.Available() {
+		n, err = b.out.Write(p)
+	} else {
+		n, err = b.buf.Write(p)
+	}
+	if s >= CriticalLog {
+		b.buf.Flush()
+	}
+	return n, err
+}
+
+// Flush writes out the buffer and syncs the output if it can be.
+func (b *bufferedOutput) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err := b.buf.Flush()
+	if s, ok := b.out.(syncer); ok {
+		s.Sync()
+	}
+	return err
+}
+
+// setOut flushes the buffer and makes it write to out.
+func (b *bufferedOutput) setOut(out io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Flush()
+	b.out = out
+	b.buf.Reset(out)
+}
+
+// stop flushes the buffer, stops the periodic flushes and returns the
+// output.
+func (b *bufferedOutput) stop() io.Writer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Flush()
+	if b.done != nil {
+		close(b.done)
+	}
+	return b.out
+}
+
+// flushEvery flushes b every interval until done is closed.
+func (b *bufferedOutput) flushEvery(interval time.Duration, done chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			b.mu.Lock()
+			b.buf.Flush()
+			b.mu.Unlock()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetBuffer(t *testing.T) {
+	var out syncBuffer
+	SetOutput(&out)
+	defer SetOutput(os.Stderr)
+	SetBuffer(4096, 0)
+	defer SetBuffer(0, 0)
+	if GetOutput() != &out {
+		t.Error("GetOutput doesn't return the buffered output")
+	}
+
+	Info("buffered")
+	if out.String() != "" {
+		t.Fatalf("entry written before Flush: %q", out.String())
+	}
+	Flush()
+	if !strings.Contains(out.String(), "] buffered\n") {
+		t.Fatalf("entry not written by Flush: %q", out.String())
+	}
+	SetExitPolicy(ExitPolicy{Critical: ActionLog})
+	defer SetExitPolicy(ExitPolicy{})
+	Critical("flushed")
+	if !strings.Contains(out.String(), "] flushed\n") {
+		t.Errorf("Critical entry not flushed: %q", out.String())
+	}
+}
+
+func TestSetBufferInterval(t *testing.T) {
+	var out syncBuffer
+	SetOutput(&out)
+	defer SetOutput(os.Stderr)
+	SetBuffer(4096, time.Millisecond)
+	defer SetBuffer(0, 0)
+	Info("periodic")
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(out.String(), "] periodic\n"); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("buffer not flushed periodically")
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Format is the format log entries are written in. *Format implements
+// flag.Value; the -log_format flag is of type Format.
+type Format int32
+
+const (
+	// FormatText writes entries as lines with a glog header, the default.
+	FormatText Format = iota
+	// FormatJSON writes every entry as a single line JSON object. Its
+	// severity, timestamp, pid, file, line and message members are followed
+	// by the fields of the entry and, if any, a stack member. Fields whose
+	// key is one of those names get a "field." prefix.
+	FormatJSON
+	// FormatLogfmt writes every entry as a line of key=value pairs: ts,
+	// level, caller and msg, followed by the fields of the entry and, if
+	// any, stack. Fields whose key is one of those names get a "field."
+	// prefix.
+	FormatLogfmt
+	numFormat
+)
+
+var formatName = [numFormat]string{
+	FormatText:   "text",
+	FormatJSON:   "json",
+	FormatLogfmt: "logfmt",
+}
+
+// SetFormat sets the format log entries are written in.
+// This function is safe to use concurrently.
+func SetFormat(f Format) {
+	ensureInit()
+	logging.format.set(f)
+}
+
+// GetFormat returns the format log entries are written in.
+func GetFormat() Format {
+	ensureInit()
+	return logging.format.get()
+}
+
+// get returns the value of the Format.
+func (f *Format) get() Format {
+	return Format(atomic.LoadInt32((*int32)(f)))
+}
+
+// set sets the value of the Format.
+func (f *Format) set(val Format) {
+	atomic.StoreInt32((*int32)(f), int32(val))
+}
+
+// String is part of the flag.Value interface.
+func (f *Format) String() string {
+	if v := f.get(); v >= 0 && v < numFormat {
+		return formatName[v]
+	}
+	return "Format(" + strconv.Itoa(int(*f)) + ")"
+}
+
+// Get is part of the flag.Value interface.
+func (f *Format) Get() interface{} {
+	return f.get()
+}
+
+// Set is part of the flag.Value interface. The value is "text", "json" or
+// "logfmt"; an empty value selects text.
+func (f *Format) Set(value string) error {
+	ensureInit()
+	if value == "" {
+		f.set(FormatText)
+		return nil
+	}
+	for i, name := range formatName {
+		if strings.EqualFold(value, name) {
+			f.set(Format(i))
+			return nil
+		}
+	}
+	return errors.New("unknown format: expect text, json or logfmt")
+}
+
+// jsonReserved lists the member names that fields can't use as is.
+var jsonReserved = map[string]bool{
+	"severity":  true,
+	"timestamp": true,
+	"pid":       true,
+	"file":      true,
+	"line":      true,
+	"message":   true,
+	"stack":     true,
+	"tag":       true,
+}
+
+// encodeJSON writes e to buf as a JSON object, without the trailing newline.
+func encodeJSON(buf *buffer, e *Entry) {
+	s := e.Severity
+	if s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	buf.WriteString(`{"severity":"`)
+	buf.WriteString(severityName[s])
+	buf.WriteByte('"')
+	levels := logging.loadJSONLevels()
+	if levels != nil {
+		buf.WriteString(`,"level":`)
+		buf.Write(strconv.AppendInt(buf.tmp[:0], int64(levels[s]), 10))
+	}
+	buf.WriteString(`,"timestamp":"`)
+	buf.Write(e.Time.AppendFormat(buf.tmp[:0], time.RFC3339Nano))
+	buf.WriteString(`","pid":`)
+	buf.Write(strconv.AppendInt(buf.tmp[:0], int64(getPid()), 10))
+	if e.Tag != "" {
+		buf.WriteString(`,"tag":`)
+		writeJSONString(buf, e.Tag)
+	}
+	buf.WriteString(`,"file":`)
+	writeJSONString(buf, e.File)
+	buf.WriteString(`,"line":`)
+	buf.Write(strconv.AppendInt(buf.tmp[:0], int64(e.Line), 10))
+	buf.WriteString(`,"message":`)
+	writeJSONString(buf, e.Message)
+	for _, f := range e.Fields {
+		buf.WriteByte(',')
+		if jsonReserved[f.Key] || levels != nil && f.Key == levelKey {
+			writeJSONString(buf, "field."+f.Key)
+		} else {
+			writeJSONString(buf, f.Key)
+		}
+		buf.WriteByte(':')
+		writeJSONValue(buf, f.Value)
+	}
+	if len(e.Stack) > 0 {
+		buf.WriteString(`,"stack":`)
+		writeJSONString(buf, string(e.Stack))
+	}
+	buf.WriteByte('}')
+}
+
+// SetJSONTypeTags turns type tags on or off in the JSON format. Durations,
+// times and byte sizes otherwise come out as plain numbers and strings,
+// which downstream schema inference easily misclassifies. With type tags on,
+// they are written as objects naming their type:
+//
+//	{"type":"duration","value":1500000,"unit":"ns"}
+//	{"type":"timestamp","value":"2019-03-01T10:00:00.5Z"}
+//	{"type":"bytes","value":2048}
+//
+// This function is safe to use concurrently.
+func SetJSONTypeTags(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.jsonTypeTags, v)
+}
+
+// ByteSize is a number of bytes. Use it for field values that are sizes so
+// they print as such, as in 1.5MiB, and are tagged as bytes in JSON.
+type ByteSize int64
+
+// String returns the size with a binary unit prefix, to one decimal.
+func (b ByteSize) String() string {
+	const units = "KMGTPE"
+	if b < 1024 && b > -1024 {
+		return strconv.FormatInt(int64(b), 10) + "B"
+	}
+	v := float64(b) / 1024
+	i := 0
+	for (v >= 1024 || v <= -1024) && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(v, 'f', 1, 64), ".0") + units[i:i+1] + "iB"
+}
+
+// writeJSONValue writes v to buf as a JSON value. Errors are written as their
+// message and values that can't be marshaled in the manner of fmt.Print.
+func writeJSONValue(buf *buffer, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		writeJSONString(buf, v)
+		return
+	case error:
+		writeJSONString(buf, v.Error())
+		return
+	}
+	if s, finite, ok := floatField(v); ok {
+		if finite {
+			buf.WriteString(s)
+		} else {
+			writeJSONString(buf, s)
+		}
+		return
+	}
+	if atomic.LoadInt32(&logging.jsonTypeTags) != 0 && writeJSONTagged(buf, v) {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeJSONString(buf, fmt.Sprint(v))
+		return
+	}
+	buf.Write(data)
+}
+
+// writeJSONTagged writes v to buf as an object tagged with its type, as
+// described for SetJSONTypeTags, and reports whether v is of a tagged type.
+func writeJSONTagged(buf *buffer, v interface{}) bool {
+	switch v := v.(type) {
+	case time.Duration:
+		buf.WriteString(`{"type":"duration","value":`)
+		buf.Write(strconv.AppendInt(buf.tmp[:0], int64(v), 10))
+		buf.WriteString(`,"unit":"ns"}`)
+	case time.Time:
+		buf.WriteString(`{"type":"timestamp","value":"`)
+		buf.Write(v.AppendFormat(buf.tmp[:0], time.RFC3339Nano))
+		buf.WriteString(`"}`)
+	case ByteSize:
+		buf.WriteString(`{"type":"bytes","value":`)
+		buf.Write(strconv.AppendInt(buf.tmp[:0], int64(v), 10))
+		buf.WriteByte('}')
+	default:
+		return false
+	}
+	return true
+}
+
+// writeJSONString writes s to buf as a JSON string. Unlike encoding/json, it
+// doesn't escape HTML characters, which keeps values such as "<redacted>"
+// readable.
+func writeJSONString(buf *buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf.WriteString(s[start:i])
+				buf.WriteString(`\ufffd`)
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		buf.WriteString(s[start:i])
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xf])
+		}
+		i++
+		start = i
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,121 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatJSON(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+
+	fields := []Field{{"user", "<bob>"}, {"line", 5}, {"err", errors.New("timeout")}}
+	logging.printWithFileLine(WarningLog, "f.go", 3, fields, "hi \"there\"\x01\xff")
+	out := contents()
+	if strings.Count(out, "\n") != 1 || !strings.HasSuffix(out, "}\n") {
+		t.Fatalf("not a single line JSON object: %q", out)
+	}
+	if !strings.Contains(out, `"user":"<bob>"`) {
+		t.Errorf("HTML characters escaped: %q", out)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	want := map[string]interface{}{
+		"severity":   "WARNING",
+		"pid":        float64(pid),
+		"file":       "f.go",
+		"line":       float64(3),
+		"message":    "hi \"there\"\x01�",
+		"user":       "<bob>",
+		"field.line": float64(5),
+		"err":        "timeout",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, got[k], v)
+		}
+	}
+	if _, ok := got["timestamp"].(string); !ok {
+		t.Errorf("missing timestamp in %q", out)
+	}
+}
+
+func TestFormatSet(t *testing.T) {
+	var f Format
+	for value, want := range map[string]Format{"": FormatText, "TEXT": FormatText, "json": FormatJSON, "logfmt": FormatLogfmt} {
+		if err := f.Set(value); err != nil || f != want {
+			t.Errorf("Set(%q) = %v, %v; want %v", value, f, err, want)
+		}
+	}
+	if err := f.Set("xml"); err == nil {
+		t.Error("Set(\"xml\") succeeded")
+	}
+	f.Set("json")
+	if s := f.String(); s != "json" {
+		t.Errorf("String() = %q, want json", s)
+	}
+}
+
+func TestJSONTypeTags(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	fields := []Field{
+		{"latency", 1500 * time.Microsecond},
+		{"at", time.Date(2019, 3, 1, 10, 0, 0, 5e8, time.UTC)},
+		{"size", ByteSize(2048)},
+	}
+	logging.printWithFileLine(InfoLog, "f.go", 1, fields, "untagged")
+	if !contains(`"latency":1500000,"at":"2019-03-01T10:00:00.5Z","size":2048}`) {
+		t.Errorf("values tagged with tags off: %q", contents())
+	}
+
+	SetJSONTypeTags(true)
+	defer SetJSONTypeTags(false)
+	logging.newBuffers()
+	logging.printWithFileLine(InfoLog, "f.go", 1, fields, "tagged")
+	want := `"latency":{"type":"duration","value":1500000,"unit":"ns"},` +
+		`"at":{"type":"timestamp","value":"2019-03-01T10:00:00.5Z"},` +
+		`"size":{"type":"bytes","value":2048}}`
+	if !contains(want) {
+		t.Errorf("missing %s in %q", want, contents())
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for size, want := range map[ByteSize]string{
+		0:             "0B",
+		1023:          "1023B",
+		1024:          "1KiB",
+		1536:          "1.5KiB",
+		5 << 20:       "5MiB",
+		-3 << 30:      "-3GiB",
+		1<<62 + 1<<61: "6EiB",
+	} {
+		if got := size.String(); got != want {
+			t.Errorf("ByteSize(%d) = %q, want %q", int64(size), got, want)
+		}
+	}
+}
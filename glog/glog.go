@@ -0,0 +1,177 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package glog exposes the exported API of github.com/golang/glog backed by
+// flog, so a code base can move to flog by changing its import paths only:
+//
+//	import "github.com/facebookincubator/flog/glog"
+//
+// Like glog, the package registers its flags on flag.CommandLine. -v, -vmodule
+// and -log_backtrace_at behave as in glog. -logtostderr, -alsologtostderr,
+// -stderrthreshold and -log_dir are accepted for compatibility and ignored
+// since flog always logs to stderr.
+package glog
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/facebookincubator/flog"
+)
+
+// Level is the type of the verbosity level passed to V.
+type Level = flog.Level
+
+// Verbose is returned by V and logs at Info level if true.
+type Verbose = flog.Verbose
+
+// OutputStats tracks the number of output lines and bytes written.
+type OutputStats = flog.OutputStats
+
+// Stats tracks the number of lines of output and number of bytes per
+// severity level.
+var Stats = &flog.Stats
+
+// MaxSize is accepted for compatibility. flog doesn't write log files.
+var MaxSize uint64 = 1024 * 1024 * 1800
+
+func init() {
+	_ = flog.AddFlags(flag.CommandLine, nil)
+	flag.Bool("logtostderr", false, "ignored, flog always logs to standard error")
+	flag.Bool("alsologtostderr", false, "ignored, flog always logs to standard error")
+	flag.String("stderrthreshold", "ERROR", "ignored, flog always logs to standard error")
+	flag.String("log_dir", "", "ignored, flog doesn't write log files")
+}
+
+// Flush writes out the entries buffered or queued by flog and flushes the
+// outputs, see flog.Flush.
+func Flush() {
+	flog.Flush()
+}
+
+// CopyStandardLogTo arranges for messages written to the Go "log" package's
+// default logs to also appear in the flog logs for the named severity.
+func CopyStandardLogTo(name string) {
+	flog.CopyStandardLogTo(name)
+}
+
+// V reports whether verbosity at the call site is at least the requested
+// level.
+func V(level Level) Verbose {
+	return flog.VDepth(1, level)
+}
+
+// Info logs to the INFO log.
+func Info(args ...interface{}) {
+	flog.InfoDepth(1, args...)
+}
+
+// InfoDepth acts as Info but uses depth to determine which call frame to log.
+func InfoDepth(depth int, args ...interface{}) {
+	flog.InfoDepth(depth+1, args...)
+}
+
+// Infoln logs to the INFO log.
+func Infoln(args ...interface{}) {
+	flog.InfoDepth(1, fmt.Sprintln(args...))
+}
+
+// Infof logs to the INFO log.
+func Infof(format string, args ...interface{}) {
+	flog.InfoDepth(1, fmt.Sprintf(format, args...))
+}
+
+// Warning logs to the WARNING log.
+func Warning(args ...interface{}) {
+	flog.WarningDepth(1, args...)
+}
+
+// WarningDepth acts as Warning but uses depth to determine which call frame
+// to log.
+func WarningDepth(depth int, args ...interface{}) {
+	flog.WarningDepth(depth+1, args...)
+}
+
+// Warningln logs to the WARNING log.
+func Warningln(args ...interface{}) {
+	flog.WarningDepth(1, fmt.Sprintln(args...))
+}
+
+// Warningf logs to the WARNING log.
+func Warningf(format string, args ...interface{}) {
+	flog.WarningDepth(1, fmt.Sprintf(format, args...))
+}
+
+// Error logs to the ERROR log.
+func Error(args ...interface{}) {
+	flog.ErrorDepth(1, args...)
+}
+
+// ErrorDepth acts as Error but uses depth to determine which call frame to
+// log.
+func ErrorDepth(depth int, args ...interface{}) {
+	flog.ErrorDepth(depth+1, args...)
+}
+
+// Errorln logs to the ERROR log.
+func Errorln(args ...interface{}) {
+	flog.ErrorDepth(1, fmt.Sprintln(args...))
+}
+
+// Errorf logs to the ERROR log.
+func Errorf(format string, args ...interface{}) {
+	flog.ErrorDepth(1, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs to the FATAL log, including a stack trace of all running
+// goroutines, then calls os.Exit(255).
+func Fatal(args ...interface{}) {
+	flog.FatalDepth(1, args...)
+}
+
+// FatalDepth acts as Fatal but uses depth to determine which call frame to
+// log.
+func FatalDepth(depth int, args ...interface{}) {
+	flog.FatalDepth(depth+1, args...)
+}
+
+// Fatalln logs to the FATAL log, then exits like Fatal.
+func Fatalln(args ...interface{}) {
+	flog.FatalDepth(1, fmt.Sprintln(args...))
+}
+
+// Fatalf logs to the FATAL log, then exits like Fatal.
+func Fatalf(format string, args ...interface{}) {
+	flog.FatalDepth(1, fmt.Sprintf(format, args...))
+}
+
+// Exit logs to the FATAL log, then calls os.Exit(1).
+func Exit(args ...interface{}) {
+	flog.ExitDepth(1, args...)
+}
+
+// ExitDepth acts as Exit but uses depth to determine which call frame to log.
+func ExitDepth(depth int, args ...interface{}) {
+	flog.ExitDepth(depth+1, args...)
+}
+
+// Exitln logs to the FATAL log, then calls os.Exit(1).
+func Exitln(args ...interface{}) {
+	flog.ExitDepth(1, fmt.Sprintln(args...))
+}
+
+// Exitf logs to the FATAL log, then calls os.Exit(1).
+func Exitf(format string, args ...interface{}) {
+	flog.ExitDepth(1, fmt.Sprintf(format, args...))
+}
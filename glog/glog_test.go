@@ -0,0 +1,60 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glog
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/facebookincubator/flog"
+)
+
+func TestCallSite(t *testing.T) {
+	var out bytes.Buffer
+	flog.SetOutput(&out)
+	defer flog.SetOutput(os.Stderr)
+
+	lines := Stats.Info.Lines()
+	Info("plain")
+	Infof("formatted %d", 1)
+	Infoln("with", "newline")
+	V(0).Info("verbose")
+	V(1).Info("hidden")
+	for _, want := range []string{"] plain\n", "] formatted 1\n", "] with newline\n", "] verbose\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in %q", want, out.String())
+		}
+	}
+	if strings.Count(out.String(), "glog_test.go:") != 4 {
+		t.Errorf("wrong call site in %q", out.String())
+	}
+	if strings.Contains(out.String(), "hidden") {
+		t.Errorf("V(1) logged at verbosity 0: %q", out.String())
+	}
+	if got := Stats.Info.Lines() - lines; got != 4 {
+		t.Errorf("Stats.Info.Lines() increased by %d, want 4", got)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	for _, name := range []string{"v", "vmodule", "log_backtrace_at", "logtostderr", "alsologtostderr", "stderrthreshold", "log_dir"} {
+		if flag.Lookup(name) == nil {
+			t.Errorf("flag -%s is not registered", name)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"sync/atomic"
+)
+
+// AdminHandler returns a handler serving VerbosityHandler, StatsHandler and
+// RecentHandler at the verbosity, stats and recent paths under the path it
+// is mounted at, which the flogclient package reads:
+//
+//	http.Handle("/debug/flog/", flog.AdminHandler())
+//
+// Like the handlers it serves, it doesn't authenticate requests.
+func AdminHandler() http.Handler {
+	verbosity, stats, recent := VerbosityHandler(), StatsHandler(), RecentHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "verbosity":
+			verbosity.ServeHTTP(w, r)
+		case "stats":
+			stats.ServeHTTP(w, r)
+		case "recent":
+			recent.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// StatsSnapshot is a copy of Stats at some point, as served by StatsHandler.
+type StatsSnapshot struct {
+	// Lines and Bytes count the output per severity name, and
+	// LifetimeLines and LifetimeBytes also the output of the previous runs,
+	// see SetCounterFile.
+	Lines              map[string]int64 `json:"lines"`
+	Bytes              map[string]int64 `json:"bytes"`
+	LifetimeLines      map[string]int64 `json:"lifetime_lines"`
+	LifetimeBytes      map[string]int64 `json:"lifetime_bytes"`
+	SlowOutputWarnings int64            `json:"slow_output_warnings"`
+	// SlowOutputs counts the warnings about slow outputs by output name,
+	// see SetSlowOutputThreshold.
+	SlowOutputs       map[string]int64 `json:"slow_outputs,omitempty"`
+	Suppressed        int64            `json:"suppressed"`
+	ClockJumps        int64            `json:"clock_jumps"`
+	PidChanges        int64            `json:"pid_changes"`
+	VChecks           int64            `json:"v_checks"`
+	VPasses           int64            `json:"v_passes"`
+	Deprecations      int64            `json:"deprecations"`
+	Sampled           int64            `json:"sampled"`
+	AsyncDropped      int64            `json:"async_dropped"`
+	AlertsFired       int64            `json:"alerts_fired"`
+	SubscriberDropped int64            `json:"subscriber_dropped"`
+	Deduplicated      int64            `json:"deduplicated"`
+	ContextDropped    int64            `json:"context_dropped"`
+	// VSites lists the call sites observed by SetVStats, see VStats.
+	VSites []VSite `json:"v_sites,omitempty"`
+	// Alerts lists the state of the alert rules, see AlertStates.
+	Alerts []AlertState `json:"alerts,omitempty"`
+	// Sites lists the call sites writing the most bytes, see SiteStats.
+	Sites []SiteStat `json:"sites,omitempty"`
+}
+
+// GetStats returns a copy of Stats.
+func GetStats() StatsSnapshot {
+	s := StatsSnapshot{
+		Lines:              make(map[string]int64),
+		Bytes:              make(map[string]int64),
+		LifetimeLines:      make(map[string]int64),
+		LifetimeBytes:      make(map[string]int64),
+		SlowOutputWarnings: atomic.LoadInt64(&Stats.SlowOutputWarnings),
+		SlowOutputs:        slowOutputCounts(),
+		Suppressed:         atomic.LoadInt64(&Stats.Suppressed),
+		ClockJumps:         atomic.LoadInt64(&Stats.ClockJumps),
+		PidChanges:         atomic.LoadInt64(&Stats.PidChanges),
+		VChecks:            atomic.LoadInt64(&Stats.VChecks),
+		VPasses:            atomic.LoadInt64(&Stats.VPasses),
+		Deprecations:       atomic.LoadInt64(&Stats.Deprecations),
+		Sampled:            atomic.LoadInt64(&Stats.Sampled),
+		AsyncDropped:       atomic.LoadInt64(&Stats.AsyncDropped),
+		VSites:             VStats(),
+		AlertsFired:        atomic.LoadInt64(&Stats.AlertsFired),
+		Alerts:             AlertStates(),
+		SubscriberDropped:  atomic.LoadInt64(&Stats.SubscriberDropped),
+		Deduplicated:       atomic.LoadInt64(&Stats.Deduplicated),
+		ContextDropped:     atomic.LoadInt64(&Stats.ContextDropped),
+		Sites:              topSiteStats(),
+	}
+	for sev, stats := range severityStats {
+		if stats != nil {
+			s.Lines[severityName[sev]] = stats.Lines()
+			s.Bytes[severityName[sev]] = stats.Bytes()
+			s.LifetimeLines[severityName[sev]] = stats.LifetimeLines()
+			s.LifetimeBytes[severityName[sev]] = stats.LifetimeBytes()
+		}
+	}
+	return s
+}
+
+// StatsHandler returns a handler serving GetStats as a JSON object to GET
+// requests.
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowGet(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetStats())
+	})
+}
+
+// RecentHandler returns a handler serving the entries returned by
+// TailRecent to GET requests, one per line in the JSON format, oldest first.
+// The n query parameter sets the number of entries, 100 by default.
+func RecentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowGet(w, r) {
+			return
+		}
+		n := 100
+		if v := r.URL.Query().Get("n"); v != "" {
+			var err error
+			if n, err = strconv.Atoi(v); err != nil || n < 0 {
+				http.Error(w, "bad n: "+v, http.StatusBadRequest)
+				return
+			}
+		}
+		entries, err := TailRecent(n)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		buf := logging.getBuffer()
+		defer logging.putBuffer(buf)
+		for i := range entries {
+			encodeJSON(buf, &entries[i])
+			buf.WriteByte('\n')
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write(buf.Bytes())
+	})
+}
+
+// allowGet replies with an error to requests other than GET and HEAD, and
+// reports whether r is one.
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// verbositySettings is the body of the requests and responses of
+// VerbosityHandler. Members left out of a PUT request are not changed.
+type verbositySettings struct {
+	Verbosity *string `json:"verbosity,omitempty"`
+	Vmodule   *string `json:"vmodule,omitempty"`
+	Vlogger   *string `json:"vlogger,omitempty"`
+}
+
+// VerbosityHandler returns a handler letting operators read and change the
+// verbosity, vmodule and vlogger settings at runtime, with the syntax of the
+// -v, -vmodule and -vlogger flags, rather than restarting a service with a
+// different FLOG_VERBOSITY. GET returns the settings as a JSON object:
+//
+//	{"verbosity":"0","vmodule":"gopher*=3","vlogger":""}
+//
+// and PUT takes an object with the members to change. The settings are
+// validated before any is changed. The handler doesn't authenticate
+// requests, so mount it on an admin-only listener.
+func VerbosityHandler() http.Handler {
+	return http.HandlerFunc(serveVerbosity)
+}
+
+func serveVerbosity(w http.ResponseWriter, r *http.Request) {
+	ensureInit()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		var req verbositySettings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := req.set(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	v := strconv.Itoa(int(GetVerbosity()))
+	vmodule := logging.vmodule.String()
+	vlogger := logging.vlogger.String()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verbositySettings{&v, &vmodule, &vlogger})
+}
+
+// set validates the settings of s, then sets them.
+func (s verbositySettings) set() error {
+	if s.Verbosity != nil {
+		if _, err := parseLevel(*s.Verbosity); err != nil {
+			return err
+		}
+	}
+	if s.Vmodule != nil {
+		if _, err := parseModulePats(*s.Vmodule); err != nil {
+			return err
+		}
+	}
+	if s.Vlogger != nil {
+		if _, err := parseModulePats(*s.Vlogger); err != nil {
+			return errVloggerSyntax
+		}
+	}
+	if s.Vmodule != nil {
+		logging.vmodule.Set(*s.Vmodule)
+	}
+	if s.Vlogger != nil {
+		logging.vlogger.Set(*s.Vlogger)
+	}
+	if s.Verbosity != nil {
+		logging.verbosity.Set(*s.Verbosity)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerbosityHandler(t *testing.T) {
+	defer logging.verbosity.Set("0")
+	defer logging.vmodule.Set("")
+	h := VerbosityHandler()
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/debug/verbosity", strings.NewReader(body)))
+		return rec
+	}
+	rec := do("PUT", `{"verbosity":"3","vmodule":"gopher*=2"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", rec.Code, rec.Body)
+	}
+	if GetVerbosity() != 3 || logging.vmodule.String() != "gopher*=2" {
+		t.Errorf("settings not changed: v=%d vmodule=%q", GetVerbosity(), logging.vmodule.String())
+	}
+
+	rec = do("PUT", `{"verbosity":"5","vmodule":"bad"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT of a bad vmodule: %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if GetVerbosity() != 3 {
+		t.Error("verbosity changed by a rejected request")
+	}
+
+	rec = do("GET", "")
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"verbosity":"3","vmodule":"gopher*=2","vlogger":""}`; got != want {
+		t.Errorf("GET = %s, want %s", got, want)
+	}
+	if rec := do("POST", "{}"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
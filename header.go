@@ -0,0 +1,65 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// HeaderFormatter writes the header of an entry in the text format to buf,
+// replacing the glog-style prefix
+//
+//	I0102 15:04:05.067890    1234 file.go:42]
+//
+// The message follows what it writes immediately, so it usually ends with a
+// space. It may be called concurrently and must not log through flog.
+type HeaderFormatter func(buf *bytes.Buffer, s Severity, file string, line int, t time.Time)
+
+// SetHeaderFormatter makes entries in the text format use the header written
+// by f, so downstream parsers expecting another prefix can read them, for
+// instance with RFC3339 timestamps and no pid:
+//
+//	flog.SetHeaderFormatter(func(buf *bytes.Buffer, s flog.Severity, file string, line int, t time.Time) {
+//		fmt.Fprintf(buf, "%s %s %s:%d ", t.Format(time.RFC3339Nano), s.Name(), file, line)
+//	})
+//
+// A nil f restores the default header. TailRecent can't parse entries with
+// custom headers back from files.
+// This function is safe to use concurrently.
+func SetHeaderFormatter(f HeaderFormatter) {
+	ensureInit()
+	logging.headerFormatter.Store(f)
+}
+
+// Name returns the name of the severity, such as INFO, for use in headers.
+func (s Severity) Name() string {
+	if s < 0 || int(s) >= len(severityName) {
+		return "Severity(" + strconv.Itoa(int(s)) + ")"
+	}
+	return severityName[s]
+}
+
+// customHeader writes the header of e with the HeaderFormatter, if one is
+// set, and reports whether it did.
+func (l *loggingT) customHeader(buf *buffer, e *Entry) bool {
+	f, _ := l.headerFormatter.Load().(HeaderFormatter)
+	if f == nil {
+		return false
+	}
+	f(&buf.Buffer, e.Severity, e.File, e.Line, e.Time)
+	return true
+}
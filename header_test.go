@@ -0,0 +1,43 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHeaderFormatter(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	SetHeaderFormatter(func(buf *bytes.Buffer, s Severity, file string, line int, t time.Time) {
+		fmt.Fprintf(buf, "%s %s %s:%d ", t.Format(time.RFC3339Nano), s.Name(), file, line)
+	})
+	defer SetHeaderFormatter(nil)
+	Warning("custom")
+	if want := "2020-01-02T03:04:05.000006Z WARNING header_test.go:"; !contains(want) || !contains(" custom\n") {
+		t.Errorf("output %q, want the custom header %q", contents(), want)
+	}
+	SetHeaderFormatter(nil)
+	Info("default")
+	if !contains("I0102 03:04:05.000006") {
+		t.Errorf("default header not restored: %q", contents())
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is called with every entry about to be written, such as to forward
+// errors to an error tracker or count entries in metrics. It gets the entry
+// once it went through the pipeline, and must neither modify its Fields nor
+// log through flog. Use a Processor to modify or drop entries.
+type Hook func(e Entry)
+
+// hooks holds the hooks added with AddHook.
+type hooks struct {
+	// list holds the []hookEntry to call. It is replaced as a whole under
+	// mu.
+	list atomic.Value
+
+	mu   sync.Mutex
+	next int
+}
+
+// hookEntry is a hook added with AddHook, with an id to remove it by.
+type hookEntry struct {
+	id int
+	h  Hook
+}
+
+// AddHook adds h to the hooks called with every entry written, including
+// the entries of Loggers, in the goroutine logging them. It returns a
+// function removing h.
+// This function is safe to use concurrently.
+func AddHook(h Hook) (remove func()) {
+	ensureInit()
+	s := &logging.hooks
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.next
+	s.next++
+	old, _ := s.list.Load().([]hookEntry)
+	list := make([]hookEntry, 0, len(old)+1)
+	list = append(list, old...)
+	s.list.Store(append(list, hookEntry{id, h}))
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		old, _ := s.list.Load().([]hookEntry)
+		for i, e := range old {
+			if e.id == id {
+				list := make([]hookEntry, 0, len(old)-1)
+				list = append(list, old[:i]...)
+				s.list.Store(append(list, old[i+1:]...))
+				return
+			}
+		}
+	}
+}
+
+// call calls the hooks with e.
+func (s *hooks) call(e *Entry) {
+	list, _ := s.list.Load().([]hookEntry)
+	for _, h := range list {
+		h.h(*e)
+	}
+}
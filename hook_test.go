@@ -0,0 +1,39 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestAddHook(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var got []Entry
+	remove := AddHook(func(e Entry) { got = append(got, e) })
+	With(F("user", "ann")).Error("denied")
+	remove()
+	Info("after removal")
+	if len(got) != 1 {
+		t.Fatalf("hook called %d times, want 1", len(got))
+	}
+	e := got[0]
+	if e.Severity != ErrorLog || e.Message != "denied" || e.File != "hook_test.go" || e.Line == 0 {
+		t.Errorf("hook got %+v", e)
+	}
+	if len(e.Fields) != 1 || e.Fields[0] != (Field{"user", "ann"}) {
+		t.Errorf("hook got fields %v", e.Fields)
+	}
+}
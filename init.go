@@ -0,0 +1,69 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// initState tracks the lazy application of the env vars.
+var initState struct {
+	// g is the ID of the goroutine running initConfig, zero if none, so the
+	// setters it calls don't wait for it. It is read and written using
+	// sync/atomic, and comes first to be 64-bit aligned.
+	g uint64
+	// done is nonzero once initConfig ran. It is read and written using
+	// sync/atomic.
+	done int32
+	mu   sync.Mutex
+}
+
+// Init applies the env vars, such as FLOG_VERBOSITY and FLOG_OUTPUT, if not
+// done yet. Calling it is optional: flog reads no env vars when imported, so
+// it is safe to import in tools that fork or exec early or that restrict
+// access to the environment, and applies them on first use instead, that is
+// with the first entry or V check, or the first call to Config.Set, to the
+// Set method of a flag or to one of the setters and getters. Init
+// makes that happen at a time of the program's choosing, such as to report
+// invalid env vars at startup.
+// This function is safe to use concurrently.
+func Init() {
+	ensureInit()
+}
+
+// ensureInit runs initConfig unless it ran already. Called from initConfig,
+// directly or not, it returns at once.
+func ensureInit() {
+	if atomic.LoadInt32(&initState.done) == 0 {
+		initSlow()
+	}
+}
+
+func initSlow() {
+	id := goroutineID()
+	if atomic.LoadUint64(&initState.g) == id {
+		return
+	}
+	initState.mu.Lock()
+	defer initState.mu.Unlock()
+	if atomic.LoadInt32(&initState.done) != 0 {
+		return
+	}
+	atomic.StoreUint64(&initState.g, id)
+	initConfig()
+	atomic.StoreUint64(&initState.g, 0)
+	atomic.StoreInt32(&initState.done, 1)
+}
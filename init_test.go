@@ -0,0 +1,54 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestLazyInitHelper is the child process of TestLazyInit.
+func TestLazyInitHelper(t *testing.T) {
+	if os.Getenv("FLOG_TEST_CHILD") != "3" {
+		return
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	AddFlags(fs, nil)
+	fmt.Printf("%s %d ", logging.format.String(), logging.verbosity.get())
+	fs.Parse([]string{"-v=5"})
+	fmt.Printf("%s %d ", logging.format.String(), logging.verbosity.get())
+	SetFormat(FormatText)
+	f := GetFormat()
+	fmt.Printf("%s %d\n", f.String(), GetVerbosity())
+	os.Exit(0)
+}
+
+func TestLazyInit(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=TestLazyInitHelper")
+	cmd.Env = append(os.Environ(), "FLOG_TEST_CHILD=3", "FLOG_FORMAT=json", "FLOG_VERBOSITY=2")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The env vars are not applied by registering the flags but on first
+	// use, here setting a flag, and before the flag or setter, which
+	// override them.
+	if got, want := string(out), "text 0 json 5 text 5\n"; got != want {
+		t.Errorf("child printed %q, want %q", got, want)
+	}
+}
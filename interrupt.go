@@ -0,0 +1,85 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// LineInterruptor is implemented by the owners of a line that is redrawn
+// in place at the bottom of a terminal, such as a progress bar, which
+// entries written to the same terminal would otherwise garble.
+type LineInterruptor interface {
+	// ClearLine erases the line, leaving the cursor at its start.
+	ClearLine()
+	// RedrawLine draws the line again, below the entries just written.
+	RedrawLine()
+}
+
+// lineInterrupt holds the LineInterruptor set by SetLineInterruptor.
+var lineInterrupt struct {
+	// mu is held while writing entries around which the line is cleared
+	// and redrawn, and by WithOutputLock.
+	mu sync.Mutex
+	li atomic.Value // of interruptorHolder
+}
+
+// interruptorHolder holds a LineInterruptor, which may be nil, so that it
+// can be stored in an atomic.Value.
+type interruptorHolder struct{ li LineInterruptor }
+
+// SetLineInterruptor makes the entries written to the output and the
+// outputs set by SetSeverityOutput be preceded by a call to li.ClearLine
+// and followed by a call to li.RedrawLine, so that a progress bar drawn on
+// the terminal the entries go to stays below them, or turns that off if li
+// is nil. Neither method may log. A line updated on its own, such as on a
+// timer, must be drawn inside WithOutputLock so it never interleaves with
+// an entry.
+// This function is safe to use concurrently.
+func SetLineInterruptor(li LineInterruptor) {
+	ensureInit()
+	lineInterrupt.mu.Lock()
+	defer lineInterrupt.mu.Unlock()
+	lineInterrupt.li.Store(interruptorHolder{li})
+}
+
+// WithOutputLock calls f while no entry is being written to the output, for
+// the owner of a LineInterruptor to draw its line. f may not log.
+func WithOutputLock(f func()) {
+	lineInterrupt.mu.Lock()
+	defer lineInterrupt.mu.Unlock()
+	f()
+}
+
+// interruptLine clears the line of the LineInterruptor, if any, and
+// returns a function redrawing it once the entry is written.
+func interruptLine() (redraw func()) {
+	h, _ := lineInterrupt.li.Load().(interruptorHolder)
+	if h.li == nil {
+		return nil
+	}
+	lineInterrupt.mu.Lock()
+	h, _ = lineInterrupt.li.Load().(interruptorHolder)
+	if h.li == nil {
+		lineInterrupt.mu.Unlock()
+		return nil
+	}
+	h.li.ClearLine()
+	return func() {
+		h.li.RedrawLine()
+		lineInterrupt.mu.Unlock()
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strconv"
+	"testing"
+)
+
+// progressBar draws its line to the test buffer.
+type progressBar struct {
+	percent int
+}
+
+func (p *progressBar) ClearLine() {
+	logging.out.Write([]byte("\r\x1b[K"))
+}
+
+func (p *progressBar) RedrawLine() {
+	logging.out.Write([]byte("[" + strconv.Itoa(p.percent) + "%]"))
+}
+
+func TestLineInterruptor(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	bar := &progressBar{}
+	SetLineInterruptor(bar)
+	defer SetLineInterruptor(nil)
+	bar.RedrawLine()
+	Info("first")
+	WithOutputLock(func() {
+		bar.percent = 50
+		bar.ClearLine()
+		bar.RedrawLine()
+	})
+	Info("second")
+	got := contents()
+	for _, want := range []string{"[0%]\r\x1b[KI", "] first\n[0%]\r\x1b[K[50%]\r\x1b[KI", "] second\n[50%]"} {
+		if !contains(want) {
+			t.Errorf("output %q lacks %q", got, want)
+		}
+	}
+}
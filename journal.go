@@ -0,0 +1,197 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// journalSocket is the socket of the native protocol of systemd-journald.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalPriorities maps severities to the PRIORITY values of journald,
+// which are syslog levels.
+var journalPriorities = [numSeverity]string{
+	DebugLog:    "7",
+	InfoLog:     "6",
+	WarningLog:  "4",
+	ErrorLog:    "3",
+	CriticalLog: "2",
+	FatalLog:    "1",
+}
+
+// JournalWriter sends entries to systemd-journald through its native
+// protocol, as structured journal entries rather than lines of stderr
+// captured by systemd: the message is MESSAGE, the severity is PRIORITY,
+// mapped like for SyslogWriter, the source location is CODE_FILE and
+// CODE_LINE, and the fields of the entry are fields of their own, with their
+// names uppercased and the characters journald doesn't accept replaced by
+// underscores. journalctl -p then filters entries by severity, and
+// journalctl USER=ann by field.
+type JournalWriter struct {
+	mu      sync.Mutex
+	conn    *net.UnixConn
+	ident   string
+	buf     bytes.Buffer
+	dropped int64 // read and written using sync/atomic
+}
+
+// NewJournalWriter connects to the local journald and returns a writer
+// sending entries with ident as their SYSLOG_IDENTIFIER, or the program name
+// if ident is empty. Pass its Export method to AddHook, or set FLOG_OUTPUT to
+// journald to send the entries there instead of stderr.
+func NewJournalWriter(ident string) (*JournalWriter, error) {
+	return DialJournal(journalSocket, ident)
+}
+
+// DialJournal is like NewJournalWriter but connects to the journald socket
+// at path.
+func DialJournal(path, ident string) (*JournalWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	if ident == "" {
+		ident = filepath.Base(os.Args[0])
+	}
+	return &JournalWriter{conn: conn, ident: ident}, nil
+}
+
+// Export sends e to journald. It is a Hook. Entries journald doesn't
+// accept, such as those larger than a datagram, are dropped.
+func (w *JournalWriter) Export(e Entry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := &w.buf
+	b.Reset()
+	writeJournalField(b, "MESSAGE", e.Message)
+	writeJournalField(b, "PRIORITY", journalPriorities[e.Severity])
+	writeJournalField(b, "SYSLOG_IDENTIFIER", w.ident)
+	if e.File != "" {
+		writeJournalField(b, "CODE_FILE", e.File)
+		writeJournalField(b, "CODE_LINE", strconv.Itoa(e.Line))
+	}
+	for _, f := range e.Fields {
+		if name := journalName(f.Key); name != "" {
+			v, _, ok := floatField(f.Value)
+			if !ok {
+				v = fmt.Sprint(f.Value)
+			}
+			writeJournalField(b, name, v)
+		}
+	}
+	if _, err := w.conn.Write(b.Bytes()); err != nil {
+		atomic.AddInt64(&w.dropped, 1)
+	}
+}
+
+// Dropped returns the number of entries journald didn't accept.
+func (w *JournalWriter) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Close closes the connection to journald.
+func (w *JournalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.Close()
+}
+
+// writeJournalField appends a field to b in the native protocol, in the
+// binary form if value spans several lines.
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if strings.IndexByte(value, '\n') < 0 {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(value)))
+	b.WriteByte('\n')
+	b.Write(n[:])
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalName returns key as a journal field name: uppercase letters, digits
+// and underscores, not starting with an underscore or digit, as those are
+// reserved, and at most 64 bytes. It is empty if nothing remains.
+func journalName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(name) < 64; i++ {
+		c := key[i]
+		switch {
+		case 'a' <= c && c <= 'z':
+			c -= 'a' - 'A'
+		case 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' || c == '_':
+			if len(name) == 0 {
+				continue
+			}
+		default:
+			if len(name) == 0 {
+				continue
+			}
+			c = '_'
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+// configJournal is the journald output set through FLOG_OUTPUT.
+var configJournal struct {
+	mu     sync.Mutex
+	w      *JournalWriter
+	remove func()
+}
+
+// setJournal makes a JournalWriter connected to the local journald receive
+// the entries, instead of the output, if on, or restores stderr if not and
+// journald received them.
+func setJournal(on bool) error {
+	configJournal.mu.Lock()
+	defer configJournal.mu.Unlock()
+	old := configJournal.w
+	if on == (old != nil) {
+		return nil
+	}
+	if !on {
+		configJournal.remove()
+		SetOutput(os.Stderr)
+		old.Close()
+		configJournal.w, configJournal.remove = nil, nil
+		return nil
+	}
+	w, err := NewJournalWriter("")
+	if err != nil {
+		return err
+	}
+	SetOutput(ioutil.Discard)
+	configJournal.w, configJournal.remove = w, AddHook(w.Export)
+	return nil
+}
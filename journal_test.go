@@ -0,0 +1,93 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalWriter(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "socket")
+	pc, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+	w, err := DialJournal(path, "flogtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	remove := AddHook(w.Export)
+	defer remove()
+
+	With(F("user", "ann"), F("req-id", 7), F("_hidden", 1), F("trace", "a\nb")).Warning("disk almost full")
+	buf := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := pc.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	for _, want := range []string{
+		"MESSAGE=disk almost full\n",
+		"PRIORITY=4\n",
+		"SYSLOG_IDENTIFIER=flogtest\n",
+		"CODE_FILE=journal_test.go\n",
+		"CODE_LINE=",
+		"USER=ann\n",
+		"REQ_ID=7\n",
+		"HIDDEN=1\n",
+		"TRACE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("%q missing from %q", want, msg)
+		}
+	}
+	if w.Dropped() != 0 {
+		t.Errorf("dropped %d entries", w.Dropped())
+	}
+}
+
+func TestJournalName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user":    "USER",
+		"req.id":  "REQ_ID",
+		"__x":     "X",
+		"9lives":  "LIVES",
+		"ü":       "",
+		"Trace_1": "TRACE_1",
+	} {
+		if got := journalName(key); got != want {
+			t.Errorf("journalName(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := journalName(strings.Repeat("a", 100)); len(got) != 64 {
+		t.Errorf("name of %d bytes", len(got))
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// levelKey is the member holding the numeric level of an entry in JSON.
+const levelKey = "level"
+
+// syslogLevels are the syslog severity levels of the severities.
+var syslogLevels = [numSeverity]int{
+	DebugLog:    7,
+	InfoLog:     6,
+	WarningLog:  4,
+	ErrorLog:    3,
+	CriticalLog: 2,
+	FatalLog:    1,
+}
+
+// SyslogLevels returns the syslog severity levels of the severities, from 7
+// for Debug to 1, alert, for Fatal, for use with SetJSONLevels.
+func SyslogLevels() map[Severity]int {
+	m := make(map[Severity]int, numSeverity)
+	for s, n := range syslogLevels {
+		m[Severity(s)] = n
+	}
+	return m
+}
+
+// SetJSONLevels makes entries in the JSON format carry a numeric level
+// member after their severity, as given by levels, so that backends sorting
+// or thresholding numerically need no lookup table:
+//
+//	flog.SetJSONLevels(flog.SyslogLevels())
+//	{"severity":"WARNING","level":4,"timestamp":...}
+//
+// Severities missing from levels get their syslog level. A field named
+// level is then written as field.level. A nil levels removes the member.
+// This function is safe to use concurrently.
+func SetJSONLevels(levels map[Severity]int) {
+	ensureInit()
+	if levels == nil {
+		logging.jsonLevels.Store((*[numSeverity]int)(nil))
+		return
+	}
+	table := syslogLevels
+	for s, n := range levels {
+		if s >= 0 && s < numSeverity {
+			table[s] = n
+		}
+	}
+	logging.jsonLevels.Store(&table)
+}
+
+// loadJSONLevels returns the levels set by SetJSONLevels, nil if none.
+func (l *loggingT) loadJSONLevels() *[numSeverity]int {
+	table, _ := l.jsonLevels.Load().(*[numSeverity]int)
+	return table
+}
+
+// parseJSONLevels parses the value of FLOG_JSON_LEVELS: "syslog" or a
+// comma-separated list of severity=N pairs overriding the syslog levels.
+// Empty and "off" return nil.
+func parseJSONLevels(value string) (map[Severity]int, error) {
+	if value == "" || strings.EqualFold(value, "off") {
+		return nil, nil
+	}
+	levels := SyslogLevels()
+	if strings.EqualFold(value, "syslog") {
+		return levels, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		eq := strings.IndexByte(pair, '=')
+		if eq < 0 {
+			return nil, errors.New("expect syslog or comma-separated severity=N pairs")
+		}
+		s, err := ParseSeverity(pair[:eq])
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(pair[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+		levels[s] = n
+	}
+	return levels, nil
+}
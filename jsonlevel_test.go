@@ -0,0 +1,57 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestJSONLevels(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	fields := []Field{{"level", "high"}}
+	logging.printWithFileLine(WarningLog, "f.go", 1, fields, "no level")
+	if contains(`"level":4`) || !contains(`"level":"high"`) {
+		t.Errorf("level written when off: %q", contents())
+	}
+
+	levels, err := parseJSONLevels("critical=50,WARNING=40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetJSONLevels(levels)
+	defer SetJSONLevels(nil)
+	logging.newBuffers()
+	logging.printWithFileLine(WarningLog, "f.go", 1, fields, "level")
+	logging.printWithFileLine(InfoLog, "f.go", 2, nil, "syslog level")
+	if !contains(`{"severity":"WARNING","level":40,"timestamp":`) || !contains(`"field.level":"high"`) {
+		t.Errorf("wrong warning level: %q", contents())
+	}
+	if !contains(`{"severity":"INFO","level":6,"timestamp":`) {
+		t.Errorf("wrong info level: %q", contents())
+	}
+	e, err := ParseEntry([]byte(`{"severity":"INFO","level":6,"timestamp":"2019-03-01T10:00:00Z","message":"m"}`))
+	if err != nil || len(e.Fields) != 0 {
+		t.Errorf("parsed %+v, %v", e, err)
+	}
+
+	for _, bad := range []string{"loud", "info=x", "chatty=1"} {
+		if _, err := parseJSONLevels(bad); err == nil {
+			t.Errorf("parsed %q", bad)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseKeyValues is a processor that promotes the key=value tokens of a
+// plain message to fields, so messages such as
+//
+//	flog.Infof("fetched user=%s items=%d", user, n)
+//
+// carry user and items as fields without editing the call site, which helps
+// migrating legacy code to structured output. Values may be quoted in the
+// manner of strconv.Quote. Tokens whose key is already a field of the entry
+// are left in the message. It is opt-in:
+//
+//	flog.AddProcessor(flog.PhaseEnrich, "kv", flog.ParseKeyValues)
+func ParseKeyValues(e *Entry) bool {
+	if strings.IndexByte(e.Message, '=') < 0 {
+		return true
+	}
+	var rest []string
+	found := false
+	for msg := e.Message; msg != ""; {
+		n, key, value, ok := scanKeyValue(msg)
+		if ok && e.field(key) < 0 {
+			e.Fields = append(e.Fields, Field{key, value})
+			found = true
+		} else if n > 0 {
+			rest = append(rest, msg[:n])
+		}
+		msg = strings.TrimLeft(msg[n:], " ")
+	}
+	if found {
+		e.Message = strings.Join(rest, " ")
+	}
+	return true
+}
+
+// scanKeyValue scans the first space-separated token of s, returning its
+// length and, if it is a key=value pair, its key and unquoted value.
+func scanKeyValue(s string) (n int, key, value string, ok bool) {
+	for n < len(s) && isKeyByte(s[n], n == 0) {
+		n++
+	}
+	if n == 0 || n == len(s) || s[n] != '=' {
+		return tokenLen(s), "", "", false
+	}
+	key, n = s[:n], n+1
+	if n < len(s) && s[n] == '"' {
+		end := n + 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end < len(s) && (end+1 == len(s) || s[end+1] == ' ') {
+			if v, err := strconv.Unquote(s[n : end+1]); err == nil {
+				return end + 1, key, v, true
+			}
+		}
+	}
+	end := n + tokenLen(s[n:])
+	return end, key, s[n:end], true
+}
+
+// tokenLen returns the length of the first space-separated token of s.
+func tokenLen(s string) int {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return i
+	}
+	return len(s)
+}
+
+// isKeyByte reports whether c may appear in a key, at its start if first.
+func isKeyByte(c byte, first bool) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '_':
+		return true
+	case '0' <= c && c <= '9', c == '.', c == '-':
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	for _, test := range []struct {
+		msg    string
+		want   string
+		fields []Field
+	}{
+		{"no pairs here", "no pairs here", nil},
+		{"fetched user=bob items=3", "fetched", []Field{{"user", "bob"}, {"items", "3"}}},
+		{`saved path="a b.txt" in 3ms`, "saved in 3ms", []Field{{"path", "a b.txt"}}},
+		{`bad quote="a b`, `bad b`, []Field{{"quote", `"a`}}},
+		{"1+1=2 =x a=", "1+1=2 =x", []Field{{"a", ""}}},
+		{"dup id=1 id=2", "dup id=2", []Field{{"id", "1"}}},
+	} {
+		e := &Entry{Message: test.msg}
+		ParseKeyValues(e)
+		if e.Message != test.want || !reflect.DeepEqual(e.Fields, test.fields) {
+			t.Errorf("ParseKeyValues(%q) = %q %v, want %q %v", test.msg, e.Message, e.Fields, test.want, test.fields)
+		}
+	}
+}
+
+func TestParseKeyValuesProcessor(t *testing.T) {
+	defer resetPipeline()
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if err := AddProcessor(PhaseEnrich, "kv", ParseKeyValues); err != nil {
+		t.Fatal(err)
+	}
+	Infof("fetched user=%s items=%d", "bob", 3)
+	if !contains("] fetched user=bob items=3\n") {
+		t.Errorf("wrong output: %q", contents())
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// latencySamples is the number of recent writes the p99 latency of an
+	// output is computed over.
+	latencySamples = 256
+	// latencyCheckEvery is how many writes pass between two checks of the
+	// p99 latency, to amortize the sorting.
+	latencyCheckEvery = 64
+	// slowOutputWarningInterval is the minimum time between two warnings
+	// about the same output.
+	slowOutputWarningInterval = time.Minute
+)
+
+// latencyTracker keeps the latencies of the recent writes to an output.
+// Each output has its own: the output set by SetOutput, those set by
+// SetSeverityOutput, those added with AddOutput and those of Loggers.
+type latencyTracker struct {
+	// mu protects the remaining elements of this structure, as the
+	// asynchronous mode writes without holding l.mu.
+	mu          sync.Mutex
+	samples     [latencySamples]time.Duration
+	n           int // number of writes recorded
+	lastWarning time.Time
+}
+
+// slowOutput is an output found slow by latencyTracker.observe.
+type slowOutput struct {
+	name string
+	p99  time.Duration
+}
+
+// observe records the latency of a write to w that started at start, if
+// threshold is positive, and appends w to slow if it is due for a warning.
+// t may be nil, for outputs that are not measured.
+func (t *latencyTracker) observe(slow []slowOutput, w io.Writer, threshold time.Duration, start time.Time) []slowOutput {
+	if threshold <= 0 || t == nil {
+		return slow
+	}
+	d := time.Since(start)
+	t.mu.Lock()
+	t.record(d)
+	p99, ok := t.slow(threshold, start)
+	t.mu.Unlock()
+	if ok {
+		slow = append(slow, slowOutput{outputName(w), p99})
+	}
+	return slow
+}
+
+// record adds the latency d of a write.
+// t.mu is held.
+func (t *latencyTracker) record(d time.Duration) {
+	t.samples[t.n%latencySamples] = d
+	t.n++
+}
+
+// p99 returns the 99th percentile of the recorded latencies.
+// t.mu is held.
+func (t *latencyTracker) p99() time.Duration {
+	n := t.n
+	if n > latencySamples {
+		n = latencySamples
+	}
+	if n == 0 {
+		return 0
+	}
+	s := make([]time.Duration, n)
+	copy(s, t.samples[:n])
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s[(n*99)/100]
+}
+
+// slow returns the p99 latency and reports whether it is above threshold and
+// due for a warning at now.
+// t.mu is held.
+func (t *latencyTracker) slow(threshold time.Duration, now time.Time) (time.Duration, bool) {
+	if t.n%latencyCheckEvery != 0 || now.Sub(t.lastWarning) < slowOutputWarningInterval {
+		return 0, false
+	}
+	p99 := t.p99()
+	if p99 <= threshold {
+		return p99, false
+	}
+	t.lastWarning = now
+	return p99, true
+}
+
+// outputName returns a name for w to use in messages about it.
+func outputName(w io.Writer) string {
+	if f, ok := w.(*os.File); ok {
+		return f.Name()
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// SetSlowOutputThreshold makes flog measure how long writes to each output
+// take: the output set by SetOutput, those set by SetSeverityOutput, those
+// added with AddOutput and those of Loggers, including in the asynchronous
+// mode. When the 99th percentile of the latency of the recent writes to an
+// output exceeds threshold, a Warning naming it is logged, at most once a
+// minute per output, and Stats.SlowOutputWarnings and the count of the
+// output in the SlowOutputs of GetStats are incremented. This makes slow
+// disks and sockets behind the logger visible before they cause incidents.
+// A threshold of zero or less, the default, turns measuring off.
+func SetSlowOutputThreshold(threshold time.Duration) {
+	ensureInit()
+	if threshold < 0 {
+		threshold = 0
+	}
+	atomic.StoreInt64(&logging.slowThreshold, int64(threshold))
+}
+
+// slowOutputs counts the warnings about slow outputs, by output name.
+var slowOutputs struct {
+	mu sync.Mutex
+	m  map[string]int64
+}
+
+// slowOutputCounts returns a copy of slowOutputs.m.
+func slowOutputCounts() map[string]int64 {
+	slowOutputs.mu.Lock()
+	defer slowOutputs.mu.Unlock()
+	if len(slowOutputs.m) == 0 {
+		return nil
+	}
+	m := make(map[string]int64, len(slowOutputs.m))
+	for name, n := range slowOutputs.m {
+		m[name] = n
+	}
+	return m
+}
+
+// warnSlowOutput logs a warning about a slow output.
+func (l *loggingT) warnSlowOutput(name string, p99, threshold time.Duration) {
+	atomic.AddInt64(&Stats.SlowOutputWarnings, 1)
+	slowOutputs.mu.Lock()
+	if slowOutputs.m == nil {
+		slowOutputs.m = make(map[string]int64)
+	}
+	slowOutputs.m[name]++
+	slowOutputs.mu.Unlock()
+	e := newEntry(WarningLog, 0, "flog", 0, "slow log output")
+	e.Fields = []Field{
+		{"output", name},
+		{"p99", p99},
+		{"threshold", threshold},
+	}
+	l.output(e)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowWriter is an output that takes at least delay to write.
+type slowWriter struct {
+	bytes.Buffer
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.Buffer.Write(p)
+}
+
+func TestSlowOutput(t *testing.T) {
+	w := &slowWriter{delay: 2 * time.Millisecond}
+	SetOutput(w)
+	defer logging.revertBuffer()
+	defer SetSlowOutputThreshold(0)
+	SetSlowOutputThreshold(time.Millisecond)
+	before := atomic.LoadInt64(&Stats.SlowOutputWarnings)
+
+	for i := 0; i < 2*latencyCheckEvery; i++ {
+		Info("test")
+	}
+	if n := strings.Count(w.String(), "slow log output"); n != 1 {
+		t.Errorf("got %d slow output warnings, want 1:\n%s", n, w.String())
+	}
+	if !strings.Contains(w.String(), "output=*flog.slowWriter") {
+		t.Errorf("warning does not name the output:\n%s", w.String())
+	}
+	if got := atomic.LoadInt64(&Stats.SlowOutputWarnings) - before; got != 1 {
+		t.Errorf("Stats.SlowOutputWarnings grew by %d, want 1", got)
+	}
+}
+
+func TestSlowOutputPerSink(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	w := &slowWriter{delay: 2 * time.Millisecond}
+	AddOutput(w, InfoLog)
+	defer RemoveOutput(w)
+	defer SetSlowOutputThreshold(0)
+	SetSlowOutputThreshold(time.Millisecond)
+	before := GetStats().SlowOutputs["*flog.slowWriter"]
+
+	for i := 0; i < 2*latencyCheckEvery; i++ {
+		Info("test")
+	}
+	if n := strings.Count(contents(), "slow log output"); n != 1 {
+		t.Errorf("got %d slow output warnings, want 1:\n%s", n, contents())
+	}
+	if !contains("output=*flog.slowWriter") {
+		t.Errorf("warning does not name the slow output:\n%s", contents())
+	}
+	if got := GetStats().SlowOutputs["*flog.slowWriter"] - before; got != 1 {
+		t.Errorf("slow output count grew by %d, want 1", got)
+	}
+}
+
+func TestSlowOutputAsync(t *testing.T) {
+	w := &slowWriter{delay: 2 * time.Millisecond}
+	SetOutput(w)
+	defer logging.revertBuffer()
+	defer SetSlowOutputThreshold(0)
+	SetSlowOutputThreshold(time.Millisecond)
+	SetAsync(4*latencyCheckEvery, AsyncBlock)
+	defer SetAsync(0, AsyncBlock)
+
+	for i := 0; i < 2*latencyCheckEvery; i++ {
+		Info("test")
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		Flush()
+		logging.mu.Lock()
+		ok := strings.Contains(w.String(), "slow log output")
+		logging.mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no slow output warning in the asynchronous mode:\n%s", w.String())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// LazyValue is a value computed only when an entry it is an argument or a
+// field value of is written, see Lazy.
+type LazyValue struct {
+	once sync.Once
+	f    func() interface{}
+	v    interface{}
+}
+
+// Lazy returns a value computed by f only once an entry it is an argument
+// or field value of is going to be written, so that expensive arguments,
+// such as dumps of large structures, cost nothing when the V level or the
+// severity is disabled:
+//
+//	flog.V(3).Infof("state: %s", flog.Lazy(func() interface{} { return spew.Sdump(s) }))
+//	flog.With(flog.F("state", flog.Lazy(dumpState))).Warning("stuck")
+//
+// f is called at most once, however many outputs the entry goes to. Field
+// values are replaced by the result of f before hooks see them.
+func Lazy(f func() interface{}) *LazyValue {
+	return &LazyValue{f: f}
+}
+
+// Value returns the result of f, calling it the first time.
+// This method is safe to use concurrently.
+func (l *LazyValue) Value() interface{} {
+	l.once.Do(func() {
+		if l.f != nil {
+			l.v = l.f()
+		}
+	})
+	return l.v
+}
+
+// String formats the value in the manner of fmt.Print.
+func (l *LazyValue) String() string {
+	return fmt.Sprint(l.Value())
+}
+
+// Format is part of the fmt.Formatter interface. It formats the value with
+// the verb and flags it is given.
+func (l *LazyValue) Format(s fmt.State, verb rune) {
+	format := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			format = append(format, byte(flag))
+		}
+	}
+	if width, ok := s.Width(); ok {
+		format = strconv.AppendInt(format, int64(width), 10)
+	}
+	if prec, ok := s.Precision(); ok {
+		format = append(format, '.')
+		format = strconv.AppendInt(format, int64(prec), 10)
+	}
+	format = append(format, string(verb)...)
+	fmt.Fprintf(s, string(format), l.Value())
+}
+
+// MarshalJSON is part of the json.Marshaler interface.
+func (l *LazyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Value())
+}
+
+// resolveLazy replaces the lazy field values of e by their value. The
+// fields are copied first, as they may be shared with a Logger.
+func resolveLazy(e *Entry) {
+	for i, f := range e.Fields {
+		if _, ok := f.Value.(*LazyValue); !ok {
+			continue
+		}
+		fields := append([]Field(nil), e.Fields...)
+		for j := i; j < len(fields); j++ {
+			if lv, ok := fields[j].Value.(*LazyValue); ok {
+				fields[j].Value = lv.Value()
+			}
+		}
+		e.Fields = fields
+		return
+	}
+}
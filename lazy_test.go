@@ -0,0 +1,57 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	calls := 0
+	dump := func() interface{} {
+		calls++
+		return 3.14159
+	}
+	V(2).Infof("state %v", Lazy(dump))
+	logging.minSeverity.Set("INFO")
+	With(F("state", Lazy(dump))).Debug("dropped")
+	logging.minSeverity.Set("")
+	if calls != 0 {
+		t.Errorf("lazy value computed %d times for disabled entries", calls)
+	}
+	Infof("pi %6.2f", Lazy(dump))
+	if calls != 1 || !contains("] pi   3.14\n") {
+		t.Errorf("wrong lazy argument after %d calls: %q", calls, contents())
+	}
+
+	var got []Entry
+	remove := AddHook(func(e Entry) { got = append(got, e) })
+	defer remove()
+	lg := With(F("state", Lazy(dump)))
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	lg.Warning("stuck")
+	if !contains(`"state":3.14159`) {
+		t.Errorf("wrong lazy field: %q", contents())
+	}
+	if len(got) != 1 || got[0].Fields[0].Value != 3.14159 {
+		t.Errorf("hook got %v", got)
+	}
+	if _, ok := lg.fields[0].Value.(*LazyValue); !ok {
+		t.Error("fields of the logger modified")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelHook lets a dynamic configuration or feature-flag system drive the
+// log levels of a process, per package or file, without polling loops in
+// the application. Its decisions are cached until InvalidateLevels is
+// called, so its methods are rarely called, but they may be called
+// concurrently and must not log through flog.
+type LevelHook interface {
+	// Verbosity returns the V level enabled at the call sites of the file
+	// of the package with the given import path, such as "gfs" and
+	// "github.com/a/gfs", and whether the hook decides it. Like -vmodule,
+	// it can only enable more than -v does.
+	Verbosity(pkg, file string) (Level, bool)
+	// MinSeverity returns the severity below which entries are dropped,
+	// and whether the hook decides it. It overrides SetMinSeverity; Fatal
+	// entries are always written.
+	MinSeverity() (Severity, bool)
+}
+
+// levelHookHolder holds a LevelHook in logging.levelHook, as an
+// atomic.Value can't hold nil.
+type levelHookHolder struct {
+	h LevelHook
+}
+
+// SetLevelHook makes V, VDepth, Logger.V and the minimum severity consult
+// h, or stops consulting a hook if h is nil. See LevelHook.
+// This function is safe to use concurrently.
+func SetLevelHook(h LevelHook) {
+	ensureInit()
+	logging.levelHook.Store(levelHookHolder{h})
+	InvalidateLevels()
+}
+
+// InvalidateLevels drops the decisions of the LevelHook, so that it is
+// consulted again, and changes VEpoch so that the decisions cached by
+// wrappers are dropped too. Call it whenever the configuration behind the
+// hook changes.
+// This function is safe to use concurrently.
+func InvalidateLevels() {
+	h := logging.loadLevelHook()
+	var min int32
+	if h != nil {
+		if s, ok := h.MinSeverity(); ok {
+			if s > FatalLog {
+				s = FatalLog
+			}
+			min = int32(s) + 1
+		}
+	}
+	atomic.StoreInt32(&logging.hookMinSeverity, min)
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.publishV()
+}
+
+// loadLevelHook returns the hook set by SetLevelHook, nil if there is none.
+func (l *loggingT) loadLevelHook() LevelHook {
+	holder, _ := l.levelHook.Load().(levelHookHolder)
+	return holder.h
+}
+
+// hookLevel returns the level h enables at pc, and whether it decides it.
+func hookLevel(h LevelHook, pc uintptr) (Level, bool) {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return 0, false
+	}
+	file, _ := fn.FileLine(pc)
+	file = strings.TrimSuffix(file, ".go")
+	if slash := strings.LastIndex(file, "/"); slash >= 0 {
+		file = file[slash+1:]
+	}
+	return h.Verbosity(funcPackage(fn.Name()), file)
+}
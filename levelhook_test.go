@@ -0,0 +1,82 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// flagLevels is a LevelHook backed by fake feature flags.
+type flagLevels struct {
+	level   int32 // V level of this file, zero if not set
+	min     int32 // one more than the minimum severity, zero if not set
+	lookups int32
+}
+
+func (f *flagLevels) Verbosity(pkg, file string) (Level, bool) {
+	atomic.AddInt32(&f.lookups, 1)
+	if !strings.HasSuffix(pkg, "/flog") || file != "levelhook_test" {
+		return 0, false
+	}
+	level := atomic.LoadInt32(&f.level)
+	return Level(level), level > 0
+}
+
+func (f *flagLevels) MinSeverity() (Severity, bool) {
+	min := atomic.LoadInt32(&f.min)
+	return Severity(min - 1), min > 0
+}
+
+func TestLevelHook(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	flags := &flagLevels{level: 2}
+	SetLevelHook(flags)
+	defer SetLevelHook(nil)
+	for i := 0; i < 3; i++ {
+		V(2).Info("enabled by flag")
+		V(3).Info("still disabled")
+	}
+	if got := strings.Count(contents(), "enabled by flag"); got != 3 || contains("still disabled") {
+		t.Errorf("wrong V decisions: %q", contents())
+	}
+	if n := atomic.LoadInt32(&flags.lookups); n != 2 {
+		t.Errorf("hook consulted %d times for 2 call sites", n)
+	}
+
+	epoch := VEpoch()
+	atomic.StoreInt32(&flags.level, 0)
+	atomic.StoreInt32(&flags.min, int32(WarningLog)+1)
+	InvalidateLevels()
+	if VEpoch() == epoch {
+		t.Error("epoch unchanged by InvalidateLevels")
+	}
+	logging.newBuffers()
+	V(2).Info("flag removed")
+	Info("below min severity")
+	Warning("kept")
+	if contains("flag removed") || contains("below min severity") || !contains("kept") {
+		t.Errorf("invalidation ignored: %q", contents())
+	}
+
+	SetLevelHook(nil)
+	logging.newBuffers()
+	Info("no hook")
+	if !contains("no hook") {
+		t.Error("min severity of a removed hook still applied")
+	}
+}
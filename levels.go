@@ -0,0 +1,95 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// levelNames holds the verbosity level names registered with
+// RegisterLevelName.
+var levelNames struct {
+	mu sync.RWMutex
+	// byName maps lower case names to their levels.
+	byName map[string]Level
+	// pendingV and pendingVmodule hold the values of FLOG_VERBOSITY and
+	// FLOG_VMODULE if they named a level that wasn't registered yet.
+	pendingV, pendingVmodule string
+}
+
+// RegisterLevelName makes name, in any case, usable in place of the number
+// of level wherever a verbosity level is expected: in the -v and -vmodule
+// flags, their env vars and Config. For example, after
+//
+//	flog.RegisterLevelName("debug", 4)
+//	flog.RegisterLevelName("trace", 8)
+//
+// FLOG_VERBOSITY=debug is the same as FLOG_VERBOSITY=4. Since env vars are
+// read before names can be registered, FLOG_VERBOSITY and FLOG_VMODULE are
+// applied again once the names they use are registered, unless the setting
+// has been changed since.
+func RegisterLevelName(name string, level Level) error {
+	if name == "" || strings.ContainsAny(name, "=,") {
+		return errors.New("invalid level name: " + strconv.Quote(name))
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return errors.New("invalid level name: " + strconv.Quote(name) + " is a number")
+	}
+	if level < 0 {
+		return errors.New("negative value for level " + strconv.Quote(name))
+	}
+	levelNames.mu.Lock()
+	if levelNames.byName == nil {
+		levelNames.byName = make(map[string]Level)
+	}
+	levelNames.byName[strings.ToLower(name)] = level
+	v, vmodule := levelNames.pendingV, levelNames.pendingVmodule
+	levelNames.mu.Unlock()
+
+	if v != "" {
+		logging.verbosity.Set(v)
+	}
+	if vmodule != "" {
+		logging.vmodule.Set(vmodule)
+	}
+	return nil
+}
+
+// clearPending forgets the pending value of an env var once the setting
+// it was for has been set.
+func clearPending(pending *string) {
+	levelNames.mu.Lock()
+	defer levelNames.mu.Unlock()
+	*pending = ""
+}
+
+var errUnknownLevel = errors.New("syntax error: expect a number or a registered level name")
+
+// parseLevel parses a verbosity level, given as a number or a registered
+// name.
+func parseLevel(s string) (Level, error) {
+	if v, err := strconv.Atoi(s); err == nil {
+		return Level(v), nil
+	}
+	levelNames.mu.RLock()
+	defer levelNames.mu.RUnlock()
+	if level, ok := levelNames.byName[strings.ToLower(s)]; ok {
+		return level, nil
+	}
+	return 0, errUnknownLevel
+}
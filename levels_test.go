@@ -0,0 +1,72 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestLevelNames(t *testing.T) {
+	defer logging.verbosity.Set("0")
+	defer logging.vmodule.Set("")
+	if err := RegisterLevelName("Trace", 8); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "4", "a=b", "a,b"} {
+		if RegisterLevelName(name, 1) == nil {
+			t.Errorf("RegisterLevelName(%q) should have failed", name)
+		}
+	}
+
+	if err := logging.verbosity.Set("TRACE"); err != nil {
+		t.Fatal(err)
+	}
+	if v := GetVerbosity(); v != 8 {
+		t.Errorf("verbosity is %d after setting it to TRACE, want 8", v)
+	}
+	if err := logging.verbosity.Set("unregistered"); err == nil {
+		t.Error("setting verbosity to an unregistered name should have failed")
+	}
+	if err := logging.vmodule.Set("levels_test=trace"); err != nil {
+		t.Fatal(err)
+	}
+	if !V(8) || V(9) {
+		t.Error("vmodule level given by name was not applied")
+	}
+}
+
+func TestPendingLevelName(t *testing.T) {
+	defer logging.verbosity.Set("0")
+	levelNames.mu.Lock()
+	levelNames.pendingV = "chatty"
+	levelNames.mu.Unlock()
+	if err := RegisterLevelName("chatty", 3); err != nil {
+		t.Fatal(err)
+	}
+	if v := GetVerbosity(); v != 3 {
+		t.Errorf("pending verbosity not applied on registration: got %d, want 3", v)
+	}
+
+	levelNames.mu.Lock()
+	levelNames.pendingV = "noisy"
+	levelNames.mu.Unlock()
+	logging.verbosity.Set("1")
+	if err := RegisterLevelName("noisy", 5); err != nil {
+		t.Fatal(err)
+	}
+	if v := GetVerbosity(); v != 1 {
+		t.Errorf("pending verbosity overrode a later setting: got %d, want 1", v)
+	}
+}
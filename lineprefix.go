@@ -0,0 +1,44 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strings"
+)
+
+// SetLinePrefix sets a prefix written at the start of every line of the
+// entries written in the text format, including the continuation lines of
+// multi-line messages and stack traces, such as "[worker-3] ". Supervisors
+// multiplexing the output of their children onto one stream can so tell
+// where lines come from without switching to the JSON format, which is not
+// affected. With SetSeverityPrefix, the prefix follows the severity token.
+// The prefix can't contain newlines. An empty prefix, the default, turns it
+// off.
+// This function is safe to use concurrently.
+func SetLinePrefix(prefix string) error {
+	ensureInit()
+	if strings.ContainsAny(prefix, "\r\n") {
+		return errors.New("line prefix contains a newline")
+	}
+	logging.linePrefix.Store(prefix)
+	return nil
+}
+
+// loadLinePrefix returns the prefix set by SetLinePrefix.
+func (l *loggingT) loadLinePrefix() string {
+	prefix, _ := l.linePrefix.Load().(string)
+	return prefix
+}
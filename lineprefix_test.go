@@ -0,0 +1,48 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLinePrefix(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if err := SetLinePrefix("[worker-3] "); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLinePrefix("")
+	defer SetHeaderFormatter(nil)
+	SetHeaderFormatter(func(buf *bytes.Buffer, s Severity, file string, line int, _ time.Time) {
+		buf.WriteString(s.Name() + ": ")
+	})
+	Info("first\nsecond")
+	SetSeverityPrefix(true)
+	Error("oops")
+	SetSeverityPrefix(false)
+	SetFormat(FormatJSON)
+	Warning("json")
+	SetFormat(FormatText)
+	want := "[worker-3] INFO: first\n[worker-3] second\n|E| [worker-3] ERROR: oops\n{"
+	if got := contents(); len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("output %q, want it to start with %q", got, want)
+	}
+	if err := SetLinePrefix("a\nb"); err == nil {
+		t.Error("prefix with a newline accepted")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logfmtLevels are the values of the level key of the logfmt format.
+var logfmtLevels = [numSeverity]string{
+	DebugLog:    "debug",
+	InfoLog:     "info",
+	WarningLog:  "warning",
+	ErrorLog:    "error",
+	CriticalLog: "critical",
+	FatalLog:    "fatal",
+}
+
+// logfmtReserved lists the keys that fields can't use as is.
+var logfmtReserved = map[string]bool{
+	"ts":     true,
+	"level":  true,
+	"caller": true,
+	"msg":    true,
+	"stack":  true,
+	"tag":    true,
+}
+
+// encodeLogfmt writes e to buf as logfmt key=value pairs, without the
+// trailing newline.
+func encodeLogfmt(buf *buffer, e *Entry) {
+	s := e.Severity
+	if s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	buf.WriteString("ts=")
+	buf.Write(e.Time.AppendFormat(buf.tmp[:0], time.RFC3339Nano))
+	buf.WriteString(" level=")
+	buf.WriteString(logfmtLevels[s])
+	if e.Tag != "" {
+		buf.WriteString(" tag=")
+		writeTextValue(buf, e.Tag)
+	}
+	buf.WriteString(" caller=")
+	writeTextValue(buf, e.File+":"+strconv.Itoa(e.Line))
+	buf.WriteString(" msg=")
+	writeTextValue(buf, e.Message)
+	for _, f := range e.Fields {
+		buf.WriteByte(' ')
+		if logfmtReserved[f.Key] {
+			buf.WriteString("field.")
+		}
+		writeLogfmtKey(buf, f.Key)
+		buf.WriteByte('=')
+		writeTextValue(buf, f.Value)
+	}
+	if len(e.Stack) > 0 {
+		buf.WriteString(" stack=")
+		writeTextValue(buf, strings.TrimSuffix(string(e.Stack), "\n"))
+	}
+}
+
+// writeLogfmtKey appends key to buf with the characters logfmt doesn't allow
+// in keys, spaces, equal signs, quotes and control characters, replaced by
+// underscores.
+func writeLogfmtKey(buf *buffer, key string) {
+	if key == "" {
+		buf.WriteByte('_')
+		return
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			c = '_'
+		}
+		buf.WriteByte(c)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatLogfmt(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetFormat(FormatLogfmt)
+	defer SetFormat(FormatText)
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Date(2019, 3, 1, 10, 0, 0, 500000000, time.UTC) }
+
+	fields := []Field{{"user", "bob smith"}, {"msg", "shadowed"}, {"req id", 7}, {"ok", true}}
+	logging.printWithFileLine(WarningLog, "f.go", 3, fields, `disk "sda" full`)
+	want := `ts=2019-03-01T10:00:00.5Z level=warning caller=f.go:3 msg="disk \"sda\" full" user="bob smith" field.msg=shadowed req_id=7 ok=true` + "\n"
+	if got := contents(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,453 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// Logger logs entries carrying a set of fields, optionally to an output of
+// its own and through a buffer. Its entries go through the same pipeline as
+// the package's and honor the same exit policy. Unless created by New with
+// WithVerbosity or WithVmodule, it also honors the package's verbosity and
+// vmodule settings, so that libraries embedded in the same binary can
+// configure their logging without changing that of the others.
+// The package-level functions log through the default instance.
+// Create loggers with New or With.
+type Logger struct {
+	name   string // set by Named
+	fields []Field
+	stack  []byte        // written after the entries, set by WithError
+	output *loggerOutput // nil to write to the package's output
+	levels *loggerLevels // nil to honor the package's verbosity and vmodule
+	hooks  []Hook        // called after the package's hooks, set by WithHook
+	minV   Level         // V levels enabled whatever the settings, see RaiseV
+}
+
+// loggerLevels holds the verbosity and vmodule settings of a logger, shared
+// with the loggers derived from it.
+type loggerLevels struct {
+	verbosity Level
+	sites     siteLevels // the vmodule filter and levels of the call sites
+}
+
+// loggerOutput is the output of a logger, shared with the loggers derived
+// from it.
+type loggerOutput struct {
+	out io.Writer // set by New and never changed
+
+	// mu protects the remaining elements of this structure and writes to
+	// out. When both are needed, logging.mu is taken first.
+	mu  sync.Mutex
+	buf *bufio.Writer // nil if the output is unbuffered
+	// lat tracks the write latencies of out.
+	lat latencyTracker
+	// done is closed by Close to stop the periodic flushes.
+	done chan struct{}
+}
+
+// Option configures a Logger created by New.
+type Option func(*Logger)
+
+// WithOutput makes the logger write to w. By default, a logger writes to the
+// package's output.
+func WithOutput(w io.Writer) Option {
+	return func(lg *Logger) {
+		lg.ownOutput().out = w
+	}
+}
+
+// WithBuffer makes the logger buffer up to size bytes of output in memory,
+// which trades latency for throughput on chatty loggers such as access logs.
+// The buffer is written out when it is full, when Sync or Close is called,
+// when a Critical or Fatal entry is logged and, if interval is positive,
+// every interval. Entries are never split across writes. Without WithOutput,
+// the logger buffers the package's output at the time it is created.
+// Outputs implementing SeverityWriter are not buffered, since the buffer
+// would lose the severities of the entries.
+func WithBuffer(size int, interval time.Duration) Option {
+	return func(lg *Logger) {
+		o := lg.ownOutput()
+		o.buf = bufio.NewWriterSize(nil, size)
+		if interval > 0 {
+			o.done = make(chan struct{})
+			go o.flushEvery(interval, o.done)
+		}
+	}
+}
+
+// WithVerbosity makes the logger enable the V levels up to v. With this
+// option or WithVmodule, the logger honors neither the -v nor the -vmodule
+// setting of the package.
+func WithVerbosity(v Level) Option {
+	return func(lg *Logger) {
+		lg.ownLevels().verbosity = v
+	}
+}
+
+// WithVmodule makes the logger enable V levels by file as described for the
+// -vmodule flag, instead of honoring the settings of the package as
+// described for WithVerbosity. An invalid spec is reported as described for ConfigErrorPrefix and
+// ignored.
+func WithVmodule(spec string) Option {
+	return func(lg *Logger) {
+		pats, err := parseModulePats(spec)
+		if checkConfig("WithVmodule", spec, err) != nil {
+			return
+		}
+		var filter []modulePat
+		for _, pat := range pats {
+			if pat.level != 0 {
+				filter = append(filter, pat)
+			}
+		}
+		lg.ownLevels().sites = siteLevels{filter: filter}
+	}
+}
+
+// WithHook makes the logger call h with every entry it writes, after the
+// hooks added with AddHook. Hooks are described with Hook.
+func WithHook(h Hook) Option {
+	return func(lg *Logger) {
+		lg.hooks = append(lg.hooks, h)
+	}
+}
+
+// ownLevels returns the levels of the logger, creating them if needed.
+func (lg *Logger) ownLevels() *loggerLevels {
+	if lg.levels == nil {
+		lg.levels = &loggerLevels{}
+	}
+	return lg.levels
+}
+
+// ownOutput returns the output of the logger, creating it if needed.
+func (lg *Logger) ownOutput() *loggerOutput {
+	if lg.output == nil {
+		lg.output = &loggerOutput{}
+	}
+	return lg.output
+}
+
+// New returns a logger configured by opts.
+func New(opts ...Option) *Logger {
+	lg := &Logger{}
+	for _, opt := range opts {
+		opt(lg)
+	}
+	if o := lg.output; o != nil {
+		if o.out == nil {
+			o.out = GetOutput()
+		}
+		if _, ok := o.out.(SeverityWriter); ok {
+			o.buf = nil
+		}
+		if o.buf != nil {
+			o.buf.Reset(o.out)
+		}
+	}
+	return lg
+}
+
+// F returns a field with the given key and value, to pass to With:
+//
+//	flog.With(flog.F("user", name), flog.F("items", n)).Info("cart updated")
+func F(key string, value interface{}) Field {
+	return Field{key, value}
+}
+
+// With returns a logger writing to the package's output that attaches fields
+// to its entries. The fields are rendered as key=value pairs after the
+// message in the text format and as members of the object in JSON.
+func With(fields ...Field) *Logger {
+	return &Logger{fields: fields}
+}
+
+// With returns a logger sharing the output of lg that attaches fields to its
+// entries, after the fields of lg.
+func (lg *Logger) With(fields ...Field) *Logger {
+	all := make([]Field, 0, len(lg.fields)+len(fields))
+	all = append(all, lg.fields...)
+	all = append(all, fields...)
+	return &Logger{name: lg.name, fields: all, stack: lg.stack, output: lg.output, levels: lg.levels, hooks: lg.hooks, minV: lg.minV}
+}
+
+// Sync writes any buffered entries to the output.
+func (lg *Logger) Sync() error {
+	if lg.output == nil {
+		return nil
+	}
+	return lg.output.sync()
+}
+
+// Close stops the periodic flushes of the output of the logger and syncs it.
+// The logger remains usable, its buffer is then only written out when full
+// or on Sync.
+func (lg *Logger) Close() error {
+	o := lg.output
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	if o.done != nil {
+		close(o.done)
+		o.done = nil
+	}
+	o.mu.Unlock()
+	return o.sync()
+}
+
+// sync writes any buffered entries to out.
+func (o *loggerOutput) sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.buf == nil {
+		return nil
+	}
+	return o.buf.Flush()
+}
+
+// flushEvery syncs o every interval until done is closed.
+func (o *loggerOutput) flushEvery(interval time.Duration, done chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			o.sync()
+		case <-done:
+			return
+		}
+	}
+}
+
+// write writes data, a complete entry of severity s, to out.
+func (o *loggerOutput) write(s Severity, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.buf == nil {
+		writeSeverity(o.out, s, data)
+		return
+	}
+	if len(data) > o.buf.Available() {
+		o.buf.Flush()
+	}
+	if len(data) > o.buf.Available() {
+		o.out.Write(data)
+	} else {
+		o.buf.Write(data)
+	}
+	if s >= CriticalLog {
+		o.buf.Flush()
+	}
+}
+
+// print logs msg at severity s from the caller of the logger's method.
+func (lg *Logger) print(s Severity, msg string) {
+	lg.printDepth(s, 1, msg)
+}
+
+// printDepth logs msg at severity s from the call site depth frames above
+// the caller of the logger's method.
+func (lg *Logger) printDepth(s Severity, depth int, msg string) {
+	pc, file, line := caller(depth)
+	e := newEntry(s, pc, file, line, msg)
+	if len(lg.fields) > 0 {
+		// Processors may modify the fields of the entry.
+		e.Fields = append([]Field(nil), lg.fields...)
+	}
+	e.Stack = lg.stack
+	e.output = lg.output
+	e.hooks = lg.hooks
+	logging.output(e)
+}
+
+// Debug logs to the DEBUG log of the logger.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) Debug(args ...interface{}) {
+	lg.print(DebugLog, sprint(args))
+}
+
+// Debugln logs to the DEBUG log of the logger.
+// Arguments are handled in the manner of fmt.Println.
+func (lg *Logger) Debugln(args ...interface{}) {
+	lg.print(DebugLog, sprintln(args))
+}
+
+// Debugf logs to the DEBUG log of the logger.
+// Arguments are handled in the manner of fmt.Printf.
+func (lg *Logger) Debugf(format string, args ...interface{}) {
+	lg.print(DebugLog, sprintf(format, args))
+}
+
+// Info logs to the INFO log of the logger.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) Info(args ...interface{}) {
+	lg.print(InfoLog, sprint(args))
+}
+
+// Infoln logs to the INFO log of the logger.
+// Arguments are handled in the manner of fmt.Println.
+func (lg *Logger) Infoln(args ...interface{}) {
+	lg.print(InfoLog, sprintln(args))
+}
+
+// Infof logs to the INFO log of the logger.
+// Arguments are handled in the manner of fmt.Printf.
+func (lg *Logger) Infof(format string, args ...interface{}) {
+	lg.print(InfoLog, sprintf(format, args))
+}
+
+// Warning logs to the WARNING log of the logger.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) Warning(args ...interface{}) {
+	lg.print(WarningLog, sprint(args))
+}
+
+// Warningln logs to the WARNING log of the logger.
+// Arguments are handled in the manner of fmt.Println.
+func (lg *Logger) Warningln(args ...interface{}) {
+	lg.print(WarningLog, sprintln(args))
+}
+
+// Warningf logs to the WARNING log of the logger.
+// Arguments are handled in the manner of fmt.Printf.
+func (lg *Logger) Warningf(format string, args ...interface{}) {
+	lg.print(WarningLog, sprintf(format, args))
+}
+
+// Error logs to the ERROR log of the logger.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) Error(args ...interface{}) {
+	lg.print(ErrorLog, sprint(args))
+}
+
+// Errorln logs to the ERROR log of the logger.
+// Arguments are handled in the manner of fmt.Println.
+func (lg *Logger) Errorln(args ...interface{}) {
+	lg.print(ErrorLog, sprintln(args))
+}
+
+// Errorf logs to the ERROR log of the logger.
+// Arguments are handled in the manner of fmt.Printf.
+func (lg *Logger) Errorf(format string, args ...interface{}) {
+	lg.print(ErrorLog, sprintf(format, args))
+}
+
+// Critical logs to the CRITICAL log of the logger.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) Critical(args ...interface{}) {
+	lg.print(CriticalLog, sprint(args))
+}
+
+// Criticalln logs to the CRITICAL log of the logger.
+// Arguments are handled in the manner of fmt.Println.
+func (lg *Logger) Criticalln(args ...interface{}) {
+	lg.print(CriticalLog, sprintln(args))
+}
+
+// Criticalf logs to the CRITICAL log of the logger.
+// Arguments are handled in the manner of fmt.Printf.
+func (lg *Logger) Criticalf(format string, args ...interface{}) {
+	lg.print(CriticalLog, sprintf(format, args))
+}
+
+// Fatal logs to the FATAL log of the logger, including a stack trace of all
+// running goroutines, then calls os.Exit(255) unless the exit policy says
+// otherwise.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) Fatal(args ...interface{}) {
+	lg.print(FatalLog, sprint(args))
+}
+
+// Fatalln logs to the FATAL log of the logger like Fatal.
+// Arguments are handled in the manner of fmt.Println.
+func (lg *Logger) Fatalln(args ...interface{}) {
+	lg.print(FatalLog, sprintln(args))
+}
+
+// Fatalf logs to the FATAL log of the logger like Fatal.
+// Arguments are handled in the manner of fmt.Printf.
+func (lg *Logger) Fatalf(format string, args ...interface{}) {
+	lg.print(FatalLog, sprintf(format, args))
+}
+
+// LogDepth logs to the log of severity s of the logger, like the method of
+// that severity, identifying the call site depth frames above the caller of
+// LogDepth, as for InfoDepth. This lets helpers, such as the functions
+// generated by floggen, log on behalf of their callers.
+// Arguments are handled in the manner of fmt.Print.
+func (lg *Logger) LogDepth(s Severity, depth int, args ...interface{}) {
+	if s < DebugLog || s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	lg.printDepth(s, depth, sprint(args))
+}
+
+// VerboseLogger is returned by Logger.V. Its methods log to the INFO log of
+// the logger if the verbosity level was enabled at the call site.
+type VerboseLogger struct {
+	lg      *Logger
+	enabled bool
+}
+
+// V is the Logger equivalent of the package's V function. For named loggers
+// matched by -vlogger, the level set there applies instead of -v and
+// -vmodule. For loggers created with WithVerbosity or WithVmodule, their
+// own settings apply instead. Levels up to the one set with RaiseV are
+// enabled in any case.
+func (lg *Logger) V(level Level) VerboseLogger {
+	if level <= lg.minV {
+		return VerboseLogger{lg: lg, enabled: observeV(0, true)}
+	}
+	if lg.name != "" {
+		if v, ok := logging.vlogger.level(lg.name); ok {
+			return VerboseLogger{lg: lg, enabled: observeV(0, v >= level)}
+		}
+	}
+	if l := lg.levels; l != nil {
+		return VerboseLogger{lg: lg, enabled: observeV(0, l.verbosity >= level || len(l.sites.filter) > 0 && l.sites.level(2) >= level)}
+	}
+	return VerboseLogger{lg: lg, enabled: bool(VDepth(1, level))}
+}
+
+// Enabled reports whether the verbosity level was enabled at the call site.
+func (v VerboseLogger) Enabled() bool {
+	return v.enabled
+}
+
+// Info is equivalent to Logger.Info, guarded by the value of v.
+func (v VerboseLogger) Info(args ...interface{}) {
+	if v.enabled {
+		v.lg.print(InfoLog, sprint(args))
+	}
+}
+
+// Infoln is equivalent to Logger.Infoln, guarded by the value of v.
+func (v VerboseLogger) Infoln(args ...interface{}) {
+	if v.enabled {
+		v.lg.print(InfoLog, sprintln(args))
+	}
+}
+
+// Infof is equivalent to Logger.Infof, guarded by the value of v.
+func (v VerboseLogger) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		v.lg.print(InfoLog, sprintf(format, args))
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that is safe to use concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggerOutput(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var out bytes.Buffer
+	lg := New(WithOutput(&out))
+	lg.Infof("to the logger %d", 1)
+	lg.V(0).Info("verbose")
+	lg.V(1).Info("hidden")
+	if contents() != "" {
+		t.Errorf("logger wrote to the package's output: %q", contents())
+	}
+	for _, want := range []string{"logger_test.go:", "] to the logger 1\n", "] verbose\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in %q", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "hidden") {
+		t.Errorf("V(1) logged at verbosity 0: %q", out.String())
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var hooked []string
+	lg := New(WithVerbosity(2), WithHook(func(e Entry) { hooked = append(hooked, e.Message) }))
+	mod := New(WithVmodule("logger_test=3,other=5"))
+	logging.verbosity.Set("4")
+	defer logging.verbosity.Set("0")
+	lg.V(2).Info("own verbosity")
+	lg.With(F("k", 1)).V(3).Info("above own verbosity")
+	mod.V(3).Info("own vmodule")
+	mod.V(4).Info("above own vmodule")
+	V(4).Info("package verbosity")
+	for _, want := range []string{"] own verbosity\n", "] own vmodule\n", "] package verbosity\n"} {
+		if !contains(want) {
+			t.Errorf("missing %q in %q", want, contents())
+		}
+	}
+	if contains("above") {
+		t.Errorf("logger honored the package's verbosity: %q", contents())
+	}
+	if len(hooked) != 1 || hooked[0] != "own verbosity" {
+		t.Errorf("logger hook called with %q", hooked)
+	}
+}
+
+// logFor logs through LogDepth on behalf of its caller.
+func logFor(lg *Logger, msg string) {
+	lg.LogDepth(WarningLog, 1, msg)
+}
+
+func TestLoggerLogDepth(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	logFor(With(F("k", 1)), "helped")
+	_, _, line, _ := runtime.Caller(0)
+	if want := fmt.Sprintf("logger_test.go:%d] helped k=1\n", line-1); !strings.HasPrefix(contents(), "W") || !contains(want) {
+		t.Errorf("missing %q in %q", want, contents())
+	}
+}
+
+func TestLoggerBuffer(t *testing.T) {
+	var out syncBuffer
+	lg := New(WithOutput(&out), WithBuffer(4096, 0))
+	lg.Info("buffered")
+	if out.String() != "" {
+		t.Errorf("buffered entry written before Sync: %q", out.String())
+	}
+	if err := lg.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "] buffered\n") {
+		t.Errorf("buffered entry not written by Sync: %q", out.String())
+	}
+	lg.Critical("urgent")
+	if !strings.Contains(out.String(), "] urgent\n") {
+		t.Errorf("critical entry not written right away: %q", out.String())
+	}
+}
+
+func TestLoggerFlushInterval(t *testing.T) {
+	var out syncBuffer
+	lg := New(WithOutput(&out), WithBuffer(4096, time.Millisecond))
+	defer lg.Close()
+	lg.Info("eventually")
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "] eventually\n") {
+		if time.Now().After(deadline) {
+			t.Fatal("buffered entry never flushed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWith(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	lg := With(F("user", "bob"))
+	child := lg.With(F("items", 3))
+	lg.Info("parent")
+	child.Warningf("child %d", 1)
+	if !contains("] parent user=bob\n") || !contains("] child 1 user=bob items=3\n") {
+		t.Errorf("fields not rendered: %q", contents())
+	}
+
+	defer resetPipeline()
+	AddProcessor(PhaseRedact, "user", func(e *Entry) bool {
+		e.setField("user", "redacted")
+		return true
+	})
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	logging.newBuffers()
+	child.Info("json")
+	if !contains(`"message":"json","user":"redacted","items":3}`) {
+		t.Errorf("fields not serialized natively: %q", contents())
+	}
+	if lg.fields[0].Value != "bob" {
+		t.Errorf("processor modified the fields of the logger: %v", lg.fields)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// memoryBudget accounts for the memory held by the internal buffering of the
+// logging subsystem: encoded entries on their way to the output and anything
+// queued or retained after that. All fields are accessed using sync/atomic.
+type memoryBudget struct {
+	limit   int64 // zero means unlimited
+	used    int64
+	dropped [numSeverity]int64
+}
+
+// severityShare is the percentage of the budget that entries of each
+// severity may fill. As usage grows, the lowest severities are dropped first.
+// Critical and Fatal entries are never dropped.
+var severityShare = [numSeverity]int64{
+	DebugLog:    50,
+	InfoLog:     70,
+	WarningLog:  80,
+	ErrorLog:    90,
+	CriticalLog: 100,
+	FatalLog:    100,
+}
+
+// reserve accounts for n bytes held for an entry of severity s and reports
+// whether they fit in the budget. If they don't, the entry must be dropped
+// and nothing is accounted for.
+func (b *memoryBudget) reserve(s Severity, n int) bool {
+	limit := atomic.LoadInt64(&b.limit)
+	if limit <= 0 || s >= CriticalLog {
+		atomic.AddInt64(&b.used, int64(n))
+		return true
+	}
+	max := limit / 100 * severityShare[s]
+	for {
+		used := atomic.LoadInt64(&b.used)
+		if used+int64(n) > max {
+			atomic.AddInt64(&b.dropped[s], 1)
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&b.used, used, used+int64(n)) {
+			return true
+		}
+	}
+}
+
+// hold accounts for n bytes held whatever the budget, such as by a buffer
+// that is already allocated.
+func (b *memoryBudget) hold(n int) {
+	atomic.AddInt64(&b.used, int64(n))
+}
+
+// release returns n bytes reserved or held earlier.
+func (b *memoryBudget) release(n int) {
+	atomic.AddInt64(&b.used, -int64(n))
+}
+
+// MemoryStats describes the memory held by the internal buffering of the
+// logging subsystem.
+type MemoryStats struct {
+	// Limit is the budget set with SetMemoryBudget, zero if unlimited.
+	Limit int64
+	// Used is the number of bytes currently held.
+	Used int64
+	// Dropped is the number of entries dropped for lack of memory, indexed
+	// by severity.
+	Dropped []int64
+}
+
+// SetMemoryBudget limits the memory held by the internal buffering of the
+// logging subsystem to about limit bytes. Once usage grows past a share of the
+// budget that depends on their severity, entries are dropped instead of
+// buffered: Debug entries are dropped first, at half of the budget, and
+// Critical and Fatal entries are never dropped, so that their exit policy
+// applies. A limit of zero or less removes the budget.
+//
+// The budget covers the entries being encoded and written, the queue of the
+// asynchronous mode and the buffer of SetBuffer. Memory bounded by other
+// settings is not counted: the entries kept by KeepRecent, the buffers of
+// loggers created with WithBuffer, and the state of deduplication, sampling,
+// rollups and OTLP exporters.
+func SetMemoryBudget(limit int64) {
+	ensureInit()
+	if limit < 0 {
+		limit = 0
+	}
+	atomic.StoreInt64(&logging.mem.limit, limit)
+}
+
+// GetMemoryStats returns the current state of the memory budget.
+func GetMemoryStats() MemoryStats {
+	m := MemoryStats{
+		Limit:   atomic.LoadInt64(&logging.mem.limit),
+		Used:    atomic.LoadInt64(&logging.mem.used),
+		Dropped: make([]int64, numSeverity),
+	}
+	for i := range m.Dropped {
+		m.Dropped[i] = atomic.LoadInt64(&logging.mem.dropped[i])
+	}
+	return m
+}
+
+// parseByteSize parses a number of bytes with an optional K, M or G
+// suffix, as in FLOG_MEMORY_BUDGET=64M.
+func parseByteSize(value string) (int64, error) {
+	if value == "" {
+		return 0, strconv.ErrSyntax
+	}
+	mult := int64(1)
+	switch value[len(value)-1] {
+	case 'K', 'k':
+		mult = 1 << 10
+	case 'M', 'm':
+		mult = 1 << 20
+	case 'G', 'g':
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer SetMemoryBudget(0)
+	before := GetMemoryStats()
+
+	// An entry with this message needs a buffer of between 1000 and 1800
+	// bytes, so debug entries don't fit in half of this budget while errors
+	// fit in 90% of it.
+	SetMemoryBudget(2000)
+	msg := strings.Repeat("x", 1000)
+	Debug(msg)
+	if contents() != "" {
+		t.Errorf("debug entry over budget was written: %q", contents())
+	}
+	Error(msg)
+	if !contains(msg) {
+		t.Error("error entry within budget was dropped")
+	}
+
+	after := GetMemoryStats()
+	if got := after.Dropped[DebugLog] - before.Dropped[DebugLog]; got != 1 {
+		t.Errorf("got %d dropped debug entries, want 1", got)
+	}
+	if after.Dropped[ErrorLog] != before.Dropped[ErrorLog] {
+		t.Error("error entries were counted as dropped")
+	}
+	if after.Used != 0 {
+		t.Errorf("%d bytes still held after logging", after.Used)
+	}
+}
+
+func TestMemoryBudgetCritical(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer SetMemoryBudget(0)
+	SetExitPolicy(ExitPolicy{Critical: ActionPanic})
+	defer SetExitPolicy(ExitPolicy{})
+
+	// The buffer of SetBuffer counts, so that not even errors fit.
+	SetMemoryBudget(2000)
+	SetBuffer(1500, 0)
+	if used := GetMemoryStats().Used; used != 1500 {
+		t.Errorf("%d bytes held with the buffer, want 1500", used)
+	}
+	msg := strings.Repeat("x", 1000)
+	Error(msg)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("critical entry over budget didn't panic")
+			}
+		}()
+		Critical(msg)
+	}()
+	SetBuffer(0, 0)
+	if strings.Count(contents(), msg) != 1 || contents()[0] != 'C' {
+		t.Errorf("want only the critical entry written: %q", contents())
+	}
+	if used := GetMemoryStats().Used; used != 0 {
+		t.Errorf("%d bytes still held without the buffer", used)
+	}
+}
+
+func TestParseMemoryBudget(t *testing.T) {
+	for in, want := range map[string]int64{"512": 512, "4K": 4 << 10, "64M": 64 << 20, "1g": 1 << 30} {
+		if got, err := parseByteSize(in); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "M", "lots"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded", in)
+		}
+	}
+}
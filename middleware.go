@@ -0,0 +1,127 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+)
+
+// requestIDHeader is the header whose value, if any, is added to the
+// request-scoped logger as a request_id field.
+const requestIDHeader = "X-Request-Id"
+
+// HTTPMiddleware is HTTPMiddlewareV at verbosity level 1.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return HTTPMiddlewareV(1, next)
+}
+
+// HTTPMiddlewareV returns a handler serving requests with next, with a
+// request-scoped logger in the context of the requests, for FromContext.
+// The logger derives from the one in the context of the request, if any, and
+// carries method and path fields, and a request_id field if the request has
+// an X-Request-Id header. Once next returns, an Info entry guarded by
+// V(level) reports the status, latency, request_bytes, if known, and
+// response_bytes of the request. The entry of a request whose connection
+// was hijacked, such as for a WebSocket upgrade, reports hijacked=true in
+// place of the status and response_bytes, which flog cannot see:
+//
+//	http.ListenAndServe(addr, flog.HTTPMiddlewareV(2, mux))
+func HTTPMiddlewareV(level Level, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		fields := []Field{{"method", r.Method}, {"path", r.URL.Path}}
+		if id := r.Header.Get(requestIDHeader); id != "" {
+			fields = append(fields, Field{"request_id", id})
+		}
+		lg := FromContext(r.Context()).With(fields...)
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(NewContext(r.Context(), lg)))
+		if lg.V(level).Enabled() {
+			fields := []Field{{"status", rw.status}, {"latency", time.Since(start)}}
+			if rw.hijacked {
+				fields[0] = Field{"hijacked", true}
+			}
+			if r.ContentLength >= 0 {
+				fields = append(fields, Field{"request_bytes", ByteSize(r.ContentLength)})
+			}
+			if !rw.hijacked {
+				fields = append(fields, Field{"response_bytes", ByteSize(rw.bytes)})
+			}
+			lg.With(fields...).Info("http request")
+		}
+	})
+}
+
+// responseRecorder records the status and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+	hijacked    bool
+}
+
+// WriteHeader is part of the http.ResponseWriter interface.
+func (rw *responseRecorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status, rw.wroteHeader = status, true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write is part of the http.ResponseWriter interface.
+func (rw *responseRecorder) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Flush is part of the http.Flusher interface, for streaming handlers.
+func (rw *responseRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack is part of the http.Hijacker interface, for handlers taking over
+// the connection, such as for WebSocket upgrades.
+func (rw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+	return conn, brw, err
+}
+
+// Push is part of the http.Pusher interface, for HTTP/2 server push.
+func (rw *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
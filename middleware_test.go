@@ -0,0 +1,104 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "short and stout")
+	}))
+	serve := func() {
+		r := httptest.NewRequest("POST", "/brew?kind=earl", strings.NewReader("tea"))
+		r.Header.Set("X-Request-Id", "r42")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	serve()
+	if !contains("] handling method=POST path=/brew request_id=r42\n") {
+		t.Errorf("request-scoped logger not in the context: %q", contents())
+	}
+	if contains("http request") {
+		t.Errorf("request logged at verbosity 0: %q", contents())
+	}
+
+	logging.verbosity.Set("1")
+	defer logging.verbosity.Set("0")
+	logging.newBuffers()
+	serve()
+	want := "] http request method=POST path=/brew request_id=r42 status=418 latency="
+	if !contains(want) || !contains(" request_bytes=3B response_bytes=15B\n") {
+		t.Errorf("missing %q in %q", want, contents())
+	}
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	logging.verbosity.Set("1")
+	defer logging.verbosity.Set("0")
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		line, _ := brw.ReadString('\n')
+		io.WriteString(conn, line)
+	}))
+	done := make(chan bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /echo HTTP/1.1\r\nHost: flog\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d, want 101", resp.StatusCode)
+	}
+	io.WriteString(conn, "ping\n")
+	if line, _ := br.ReadString('\n'); line != "ping\n" {
+		t.Errorf("got %q after the upgrade, want ping", line)
+	}
+	<-done
+	want := "] http request method=GET path=/echo hijacked=true latency="
+	if !contains(want) || contains("status=") || contains("response_bytes=") {
+		t.Errorf("missing %q in %q", want, contents())
+	}
+}
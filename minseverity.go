@@ -0,0 +1,39 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync/atomic"
+)
+
+// SetMinSeverity drops the entries below severity s, so that production
+// deployments can silence Info and Debug entries, or everything below
+// Warning, without touching the call sites. Dropped entries are not
+// formatted at all and are not counted. Fatal entries, which end the
+// process, are always written. The default, DebugLog, drops nothing.
+// This function is safe to use concurrently.
+func SetMinSeverity(s Severity) {
+	ensureInit()
+	logging.minSeverity.set(s)
+}
+
+// belowMinSeverity reports whether entries of severity s are dropped.
+func (l *loggingT) belowMinSeverity(s Severity) bool {
+	ensureInit()
+	if min := atomic.LoadInt32(&l.hookMinSeverity); min != 0 {
+		return s < Severity(min-1)
+	}
+	return s < l.minSeverity.get()
+}
@@ -0,0 +1,78 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMinSeverity(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer SetMinSeverity(DebugLog)
+	if err := logging.minSeverity.Set("warning"); err != nil {
+		t.Fatal(err)
+	}
+	Info("info")
+	Infof("infof %d", 1)
+	Debug("debug")
+	With(F("k", 1)).Info("logger info")
+	Warning("warning")
+	Error("error")
+	if contains("info") || contains("debug") {
+		t.Errorf("entries below WARNING written: %q", contents())
+	}
+	if !contains("] warning\n") || !contains("] error\n") {
+		t.Errorf("entries at or above WARNING dropped: %q", contents())
+	}
+}
+
+func TestSeveritySet(t *testing.T) {
+	var s Severity
+	for value, want := range map[string]Severity{"ERROR": ErrorLog, "info": InfoLog, "4": CriticalLog, "": DebugLog} {
+		if err := s.Set(value); err != nil || s != want {
+			t.Errorf("Set(%q) = %v, severity %d, want %d", value, err, s, want)
+		}
+	}
+	for _, value := range []string{"LOUD", "6", "-1"} {
+		if err := s.Set(value); err == nil {
+			t.Errorf("Set(%q) succeeded", value)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{DebugLog, InfoLog, WarningLog, ErrorLog, CriticalLog, FatalLog} {
+		if got, err := ParseSeverity(strings.ToLower(s.String())); err != nil || got != s {
+			t.Errorf("ParseSeverity(%q) = %v, %v", s.String(), got, err)
+		}
+	}
+	if _, err := ParseSeverity(""); err == nil {
+		t.Error("parsed an empty severity")
+	}
+	if got := fmt.Sprint(ErrorLog, Severity(9)); got != "ERROR Severity(9)" {
+		t.Errorf("severities print as %q", got)
+	}
+	var c struct{ Min Severity }
+	if err := json.Unmarshal([]byte(`{"Min":"warning"}`), &c); err != nil || c.Min != WarningLog {
+		t.Errorf("unmarshaled %v, %v", c.Min, err)
+	}
+	if data, err := json.Marshal(c); err != nil || string(data) != `{"Min":"WARNING"}` {
+		t.Errorf("marshaled %s, %v", data, err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// loggerKey is the key of the field holding the name of a named logger.
+const loggerKey = "logger"
+
+// Named returns a logger writing to the package's output whose entries carry
+// a logger=name field. Unlike the package's V, the V method of a named
+// logger can be controlled by name with -vlogger, which suits subsystems
+// sharing files better than -vmodule.
+func Named(name string) *Logger {
+	return defaultLogger.Named(name)
+}
+
+// Named returns a logger sharing the output and fields of lg, named after
+// name, prefixed with the name of lg and a dot if lg is named.
+func (lg *Logger) Named(name string) *Logger {
+	if lg.name != "" {
+		name = lg.name + "." + name
+	}
+	child := lg.With()
+	child.name = name
+	if i := child.fieldIndex(loggerKey); i >= 0 {
+		child.fields[i].Value = name
+	} else {
+		child.fields = append(child.fields, Field{loggerKey, name})
+	}
+	return child
+}
+
+// fieldIndex returns the index of the field of lg with the given key, or -1.
+func (lg *Logger) fieldIndex(key string) int {
+	for i, f := range lg.fields {
+		if f.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// loggerSpec represents the setting of the -vlogger flag.
+type loggerSpec struct {
+	mu     sync.RWMutex
+	filter []modulePat
+	// levels caches the level of each logger name looked up, -1 for the
+	// names no pattern matches.
+	levels map[string]Level
+}
+
+// level returns the verbosity level set for the logger name, if any. The
+// first matching pattern wins.
+func (s *loggerSpec) level(name string) (Level, bool) {
+	ensureInit()
+	s.mu.RLock()
+	v, ok := s.levels[name]
+	n := len(s.filter)
+	s.mu.RUnlock()
+	if n == 0 {
+		return 0, false
+	}
+	if !ok {
+		s.mu.Lock()
+		v = -1
+		for i := range s.filter {
+			if s.filter[i].match(name) {
+				v = s.filter[i].level
+				break
+			}
+		}
+		s.levels[name] = v
+		s.mu.Unlock()
+	}
+	return v, v >= 0
+}
+
+func (s *loggerSpec) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var b bytes.Buffer
+	for i, f := range s.filter {
+		if i > 0 {
+			b.WriteRune(',')
+		}
+		fmt.Fprintf(&b, "%s=%d", f.pattern, f.level)
+	}
+	return b.String()
+}
+
+// Get is part of the flag.Getter interface. It always returns nil for this
+// flag type since the struct is not exported.
+func (s *loggerSpec) Get() interface{} {
+	return nil
+}
+
+var errVloggerSyntax = errors.New("syntax error: expect comma-separated list of name=N")
+
+// Syntax: -vlogger=db=2,http.*=3,chatty=0
+// Patterns are matched against the full names of loggers, as by
+// filepath.Match. Unlike with -vmodule, a level of 0 is meaningful: it
+// silences the V logs of the matching loggers whatever the -v level.
+func (s *loggerSpec) Set(value string) error {
+	ensureInit()
+	filter, err := parseModulePats(value)
+	if err == errVmoduleSyntax {
+		err = errVloggerSyntax
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+	s.levels = make(map[string]Level)
+	atomic.AddUint64(&vEpoch, 1)
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.vlogger.Set("")
+
+	db := Named("db")
+	pool := db.With(F("id", 1)).Named("pool")
+	db.Info("query")
+	pool.Info("exhausted")
+	if !contains("] query logger=db\n") || !contains("] exhausted logger=db.pool id=1\n") {
+		t.Errorf("wrong logger names: %q", contents())
+	}
+
+	if err := logging.vlogger.Set("db.*=2,db=0"); err != nil {
+		t.Fatal(err)
+	}
+	logging.newBuffers()
+	pool.V(2).Info("pool v2")
+	pool.V(3).Info("pool v3")
+	db.V(0).Info("db v0")
+	db.V(1).Info("db v1")
+	Named("http").V(0).Info("http v0")
+	Named("http").V(1).Info("http v1")
+	for _, want := range []string{"pool v2", "db v0", "http v0"} {
+		if !contains(want) {
+			t.Errorf("missing %q in %q", want, contents())
+		}
+	}
+	for _, unwanted := range []string{"pool v3", "db v1", "http v1"} {
+		if contains(unwanted) {
+			t.Errorf("unexpected %q in %q", unwanted, contents())
+		}
+	}
+	if s := logging.vlogger.String(); s != "db.*=2,db=0" {
+		t.Errorf("String() = %q", s)
+	}
+	if logging.vlogger.Set("db") != errVloggerSyntax {
+		t.Error("invalid setting accepted")
+	}
+}
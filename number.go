@@ -0,0 +1,105 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
+// SetFloatDecimals makes float32 and float64 field values be written with
+// exactly n decimals in both formats, never with an exponent, so that strict
+// schemas downstream see numbers of a stable shape. A negative n restores the
+// default, the fewest digits that represent the value, which switches to an
+// exponent for very large and very small values.
+//
+// Numbers are always written with a '.' decimal separator and no digit
+// grouping, whatever the locale of the process. NaN and infinities, which
+// JSON has no number for, are written as the strings "NaN", "+Inf" and
+// "-Inf". This function is safe to use concurrently.
+func SetFloatDecimals(n int) {
+	ensureInit()
+	if n < 0 {
+		n = -1
+	}
+	atomic.StoreInt32(&logging.floatDecimals, int32(n+1))
+}
+
+// Fixed is a float written with a fixed number of decimals, whatever
+// SetFloatDecimals is set to. Use FixedFloat to make one.
+type Fixed struct {
+	Value    float64
+	Decimals int
+}
+
+// FixedFloat returns v as a field value written with the given number of
+// decimals, as in flog.F("ratio", flog.FixedFloat(r, 3)).
+func FixedFloat(v float64, decimals int) Fixed {
+	return Fixed{v, decimals}
+}
+
+// String returns the value with its decimals.
+func (f Fixed) String() string {
+	return formatFloat(f.Value, 64, f.Decimals)
+}
+
+// MarshalJSON writes the value as a JSON number with its decimals, or a
+// string if it is NaN or infinite.
+func (f Fixed) MarshalJSON() ([]byte, error) {
+	s := f.String()
+	if math.IsNaN(f.Value) || math.IsInf(f.Value, 0) {
+		return []byte(`"` + s + `"`), nil
+	}
+	return []byte(s), nil
+}
+
+// floatField returns v formatted as described for SetFloatDecimals, and
+// whether it is finite, if it is a float that needs formatting by flog.
+func floatField(v interface{}) (s string, finite, ok bool) {
+	var f float64
+	bits := 64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case float32:
+		f, bits = float64(v), 32
+	default:
+		return "", false, false
+	}
+	decimals := int(atomic.LoadInt32(&logging.floatDecimals)) - 1
+	finite = !math.IsNaN(f) && !math.IsInf(f, 0)
+	if decimals < 0 && finite {
+		// Leave the shortest representation to the encoders.
+		return "", true, false
+	}
+	return formatFloat(f, bits, decimals), finite, true
+}
+
+// formatFloat formats f with the given number of decimals, or the fewest
+// digits needed if decimals is negative.
+func formatFloat(f float64, bits, decimals int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case decimals < 0:
+		return strconv.FormatFloat(f, 'g', -1, bits)
+	}
+	return strconv.FormatFloat(f, 'f', decimals, bits)
+}
@@ -0,0 +1,60 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloatDecimals(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	fields := []Field{
+		{"ratio", 0.5},
+		{"big", 1e21},
+		{"small", float32(0.25)},
+		{"bad", math.NaN()},
+		{"fixed", FixedFloat(2.0/3, 3)},
+	}
+	logging.printWithFileLine(InfoLog, "f.go", 1, fields, "shortest")
+	if !contains(" ratio=0.5 big=1e+21 small=0.25 bad=NaN fixed=0.667\n") {
+		t.Errorf("wrong default text: %q", contents())
+	}
+
+	SetFloatDecimals(2)
+	defer SetFloatDecimals(-1)
+	logging.newBuffers()
+	logging.printWithFileLine(InfoLog, "f.go", 1, fields, "fixed")
+	if !contains(" ratio=0.50 big=1000000000000000000000.00 small=0.25 bad=NaN fixed=0.667\n") {
+		t.Errorf("wrong fixed text: %q", contents())
+	}
+
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	logging.newBuffers()
+	logging.printWithFileLine(InfoLog, "f.go", 1, fields, "fixed")
+	if !contains(`"ratio":0.50,"big":1000000000000000000000.00,"small":0.25,"bad":"NaN","fixed":0.667}`) {
+		t.Errorf("wrong fixed JSON: %q", contents())
+	}
+
+	SetFloatDecimals(-1)
+	logging.newBuffers()
+	fields = append(fields, Field{"inf", FixedFloat(math.Inf(-1), 1)})
+	logging.printWithFileLine(InfoLog, "f.go", 1, fields, "shortest")
+	if !contains(`"ratio":0.5,"big":1e+21,"small":0.25,"bad":"NaN","fixed":0.667,"inf":"-Inf"}`) {
+		t.Errorf("wrong default JSON: %q", contents())
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// otlpSeverity maps severities to OpenTelemetry severity numbers.
+var otlpSeverity = [numSeverity]int{
+	DebugLog:    5,
+	InfoLog:     9,
+	WarningLog:  13,
+	ErrorLog:    17,
+	CriticalLog: 20,
+	FatalLog:    21,
+}
+
+// OTLPOptions configures an OTLPExporter. Zero members take their defaults.
+type OTLPOptions struct {
+	// ServiceName is the service.name attribute of the resource, the base
+	// name of the executable by default.
+	ServiceName string
+	// BatchSize is the number of entries sent per request, 512 by default.
+	BatchSize int
+	// Interval is the longest time an entry waits before being sent, one
+	// second by default.
+	Interval time.Duration
+	// Retries is the number of times a failed request is retried, with
+	// exponential backoff, 3 by default.
+	Retries int
+	// Headers are added to the requests, such as for authentication.
+	Headers map[string]string
+	// Acked is the sequence number up to which entries are known to have
+	// been received by the collector, as returned by OTLPExporter.Acked
+	// before a restart. Entries at or below it are skipped, see Acked.
+	Acked int64
+}
+
+// OTLPExporter exports entries to an OpenTelemetry collector through the
+// OTLP/HTTP protocol, with the JSON encoding, so flog can feed collectors
+// without a sidecar tailing stderr. Entries are sent in batches from a
+// goroutine of the exporter. Add it as a hook:
+//
+//	x := flog.NewOTLPExporter("http://collector:4318", flog.OTLPOptions{})
+//	flog.AddHook(x.Export)
+//	defer x.Close()
+//
+// Requests failing after the retries are dropped, and the first failure
+// after a success is logged as a Warning entry.
+//
+// Entries can also be replayed to the exporter after an outage, such as by
+// passing the lines of a log file written with SetSequenceNumbers through
+// ParseEntry and Export. The exporter then skips the entries the collector
+// already received, as described for Acked, rather than sending them twice.
+type OTLPExporter struct {
+	endpoint string
+	opts     OTLPOptions
+	resource []otlpAttr
+	client   *http.Client
+
+	mu      sync.Mutex
+	pending []otlpRecord
+	full    chan struct{} // signaled when a batch is ready
+	done    chan struct{} // closed by Close
+	exited  chan struct{} // closed when the sender exits
+	failing bool          // the latest request failed
+	dropped int64         // read and written using sync/atomic
+	// acked is the sequence number up to which every entry was sent
+	// successfully, and duplicates counts the entries skipped for being at
+	// or below it. lost is the lowest sequence number of the entries
+	// dropped, zero if none was; acked never reaches it. They are read and
+	// written using sync/atomic.
+	acked      int64
+	duplicates int64
+	lost       int64
+}
+
+// NewOTLPExporter returns an exporter sending entries to the collector at
+// endpoint, such as http://collector:4318. The standard /v1/logs path is
+// used if endpoint has no path.
+func NewOTLPExporter(endpoint string, opts OTLPOptions) *OTLPExporter {
+	if u, err := url.Parse(endpoint); err == nil && (u.Path == "" || u.Path == "/") {
+		u.Path = "/v1/logs"
+		endpoint = u.String()
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = filepath.Base(os.Args[0])
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = 3
+	}
+	x := &OTLPExporter{
+		endpoint: endpoint,
+		opts:     opts,
+		resource: []otlpAttr{{"service.name", otlpValue{String: &opts.ServiceName}}},
+		client:   &http.Client{Timeout: 10 * time.Second},
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+		acked:    opts.Acked,
+	}
+	go x.run()
+	return x
+}
+
+// Export queues e to be sent. It is a Hook. Entries are dropped while more
+// than 10 batches are queued, as when the collector is down. Entries with a
+// seq field at or below Acked are skipped.
+func (x *OTLPExporter) Export(e Entry) {
+	r := newOTLPRecord(&e)
+	if r.seq > 0 && r.seq <= atomic.LoadInt64(&x.acked) {
+		atomic.AddInt64(&x.duplicates, 1)
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if len(x.pending) >= 10*x.opts.BatchSize {
+		atomic.AddInt64(&x.dropped, 1)
+		x.lose(r.seq)
+		return
+	}
+	x.pending = append(x.pending, r)
+	if len(x.pending) >= x.opts.BatchSize {
+		select {
+		case x.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped, because too many were
+// queued or their request failed.
+func (x *OTLPExporter) Dropped() int64 {
+	return atomic.LoadInt64(&x.dropped)
+}
+
+// Acked returns the highest sequence number, as given by the seq field of
+// the entries, see SetSequenceNumbers, up to which every entry was sent
+// successfully. It only advances over successful requests: an entry dropped
+// or whose request failed, such as during an outage, stops it even once
+// later requests succeed. Replaying the entries above it thus sends every
+// entry the collector missed, along with some it may have received already.
+func (x *OTLPExporter) Acked() int64 {
+	return atomic.LoadInt64(&x.acked)
+}
+
+// Duplicates returns the number of entries skipped because the collector
+// already received them, see Acked.
+func (x *OTLPExporter) Duplicates() int64 {
+	return atomic.LoadInt64(&x.duplicates)
+}
+
+// Close sends the queued entries and stops the exporter. Entries exported
+// afterwards are dropped.
+func (x *OTLPExporter) Close() error {
+	x.mu.Lock()
+	select {
+	case <-x.done:
+		x.mu.Unlock()
+		return nil
+	default:
+	}
+	close(x.done)
+	x.mu.Unlock()
+	<-x.exited
+	return nil
+}
+
+// run sends the batches until the exporter is closed.
+func (x *OTLPExporter) run() {
+	defer close(x.exited)
+	t := time.NewTicker(x.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-x.full:
+		case <-x.done:
+			for x.sendBatch() {
+			}
+			x.mu.Lock()
+			x.pending = nil
+			x.opts.BatchSize = 0 // drop everything from now on
+			x.mu.Unlock()
+			return
+		}
+		for x.sendBatch() {
+		}
+	}
+}
+
+// sendBatch sends a batch of the queued entries, if any, and reports
+// whether more remain.
+func (x *OTLPExporter) sendBatch() bool {
+	x.mu.Lock()
+	n := len(x.pending)
+	if n > x.opts.BatchSize {
+		n = x.opts.BatchSize
+	}
+	batch := append([]otlpRecord(nil), x.pending[:n]...)
+	x.pending = x.pending[n:]
+	more := len(x.pending) > 0
+	x.mu.Unlock()
+	if n == 0 {
+		return false
+	}
+	err := x.send(batch)
+	x.mu.Lock()
+	report := err != nil && !x.failing
+	x.failing = err != nil
+	x.mu.Unlock()
+	if err != nil {
+		atomic.AddInt64(&x.dropped, int64(len(batch)))
+		for _, r := range batch {
+			x.lose(r.seq)
+		}
+	} else {
+		x.ack(batch)
+	}
+	if report {
+		logging.notice("OTLP export failed", Field{"endpoint", x.endpoint}, Field{"error", err.Error()})
+	}
+	return more
+}
+
+// lose records that the entry with sequence number seq was dropped.
+func (x *OTLPExporter) lose(seq int64) {
+	if seq <= 0 {
+		return
+	}
+	for {
+		lost := atomic.LoadInt64(&x.lost)
+		if lost != 0 && lost <= seq || atomic.CompareAndSwapInt64(&x.lost, lost, seq) {
+			return
+		}
+	}
+}
+
+// ack raises the acked sequence number to the highest of batch, but not up
+// to the lowest one lost.
+func (x *OTLPExporter) ack(batch []otlpRecord) {
+	var max int64
+	for _, r := range batch {
+		if r.seq > max {
+			max = r.seq
+		}
+	}
+	if lost := atomic.LoadInt64(&x.lost); lost != 0 && max >= lost {
+		max = lost - 1
+	}
+	for {
+		acked := atomic.LoadInt64(&x.acked)
+		if max <= acked || atomic.CompareAndSwapInt64(&x.acked, acked, max) {
+			return
+		}
+	}
+}
+
+// send posts batch to the collector, retrying on failure.
+func (x *OTLPExporter) send(batch []otlpRecord) error {
+	body, err := json.Marshal(otlpRequest{[]otlpResourceLogs{{
+		Resource:  otlpResource{x.resource},
+		ScopeLogs: []otlpScopeLogs{{otlpScope{"flog"}, batch}},
+	}}})
+	if err != nil {
+		return err
+	}
+	backoff := 100 * time.Millisecond
+	for i := 0; ; i++ {
+		if err = x.post(body); err == nil || i == x.opts.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-x.done:
+			// Closing: one more try without waiting.
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single request with body.
+func (x *OTLPExporter) post(body []byte) error {
+	req, err := http.NewRequest("POST", x.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range x.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of logs, as defined by the OpenTelemetry protocol.
+type (
+	otlpRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope    `json:"scope"`
+		LogRecords []otlpRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpRecord struct {
+		TimeUnixNano   string     `json:"timeUnixNano"`
+		SeverityNumber int        `json:"severityNumber"`
+		SeverityText   string     `json:"severityText"`
+		Body           otlpValue  `json:"body"`
+		Attributes     []otlpAttr `json:"attributes,omitempty"`
+		TraceID        string     `json:"traceId,omitempty"`
+		SpanID         string     `json:"spanId,omitempty"`
+		seq            int64      // the seq field of the entry, zero if none
+	}
+	otlpAttr struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		String *string  `json:"stringValue,omitempty"`
+		Bool   *bool    `json:"boolValue,omitempty"`
+		Int    *string  `json:"intValue,omitempty"`
+		Double *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// newOTLPRecord returns e as an OTLP log record. Its fields become
+// attributes, except trace_id and span_id, which become the trace context of
+// the record.
+func newOTLPRecord(e *Entry) otlpRecord {
+	s := e.Severity
+	if s < DebugLog || s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	msg := e.Message
+	r := otlpRecord{
+		TimeUnixNano:   strconv.FormatInt(e.Time.UnixNano(), 10),
+		SeverityNumber: otlpSeverity[s],
+		SeverityText:   severityName[s],
+		Body:           otlpValue{String: &msg},
+		Attributes: []otlpAttr{
+			{"code.filepath", otlpString(e.File)},
+			{"code.lineno", otlpInt(int64(e.Line))},
+		},
+	}
+	if len(e.Stack) > 0 {
+		r.Attributes = append(r.Attributes, otlpAttr{"exception.stacktrace", otlpString(string(e.Stack))})
+	}
+	for _, f := range e.Fields {
+		if id, ok := f.Value.(string); ok && (f.Key == traceIDKey || f.Key == spanIDKey) {
+			if f.Key == traceIDKey {
+				r.TraceID = id
+			} else {
+				r.SpanID = id
+			}
+			continue
+		}
+		if f.Key == seqKey {
+			r.seq = seqValue(f.Value)
+		}
+		r.Attributes = append(r.Attributes, otlpAttr{f.Key, otlpAny(f.Value)})
+	}
+	return r
+}
+
+// seqValue returns the sequence number v, as set by SetSequenceNumbers or
+// parsed by ParseEntry, zero if it isn't one.
+func seqValue(v interface{}) int64 {
+	switch v := v.(type) {
+	case uint64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	}
+	return 0
+}
+
+// otlpAny returns v as an OTLP value.
+func otlpAny(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case string:
+		return otlpString(v)
+	case bool:
+		return otlpValue{Bool: &v}
+	case int:
+		return otlpInt(int64(v))
+	case int32:
+		return otlpInt(int64(v))
+	case int64:
+		return otlpInt(v)
+	case uint32:
+		return otlpInt(int64(v))
+	case float64:
+		return otlpValue{Double: &v}
+	case float32:
+		f := float64(v)
+		return otlpValue{Double: &f}
+	case error:
+		return otlpString(v.Error())
+	}
+	return otlpString(fmt.Sprint(v))
+}
+
+func otlpString(s string) otlpValue {
+	return otlpValue{String: &s}
+}
+
+func otlpInt(n int64) otlpValue {
+	s := strconv.FormatInt(n, 10)
+	return otlpValue{Int: &s}
+}
+
+// configOTLP is the exporter set through Config or FLOG_OTLP_ENDPOINT.
+var configOTLP struct {
+	mu       sync.Mutex
+	endpoint string
+	x        *OTLPExporter
+	remove   func()
+}
+
+// setOTLPEndpoint exports entries to the collector at endpoint, or stops
+// exporting them if endpoint is empty.
+func setOTLPEndpoint(endpoint string) error {
+	configOTLP.mu.Lock()
+	defer configOTLP.mu.Unlock()
+	if endpoint == configOTLP.endpoint {
+		return nil
+	}
+	if endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("OTLP endpoint must be an http or https URL")
+		}
+	}
+	if configOTLP.x != nil {
+		configOTLP.remove()
+		configOTLP.x.Close()
+		configOTLP.x, configOTLP.remove = nil, nil
+	}
+	configOTLP.endpoint = endpoint
+	if endpoint != "" {
+		configOTLP.x = NewOTLPExporter(endpoint, OTLPOptions{})
+		configOTLP.remove = AddHook(configOTLP.x.Export)
+	}
+	return nil
+}
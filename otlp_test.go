@@ -0,0 +1,198 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var mu sync.Mutex
+	var records []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("request to %s with headers %v", r.URL.Path, r.Header)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		var raw struct {
+			ResourceLogs []struct {
+				ScopeLogs []struct {
+					LogRecords []map[string]interface{}
+				}
+			}
+		}
+		data, _ := json.Marshal(req)
+		json.Unmarshal(data, &raw)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rl := range raw.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}))
+	defer srv.Close()
+	x := NewOTLPExporter(srv.URL, OTLPOptions{BatchSize: 2, Interval: time.Hour, Headers: map[string]string{"Authorization": "Bearer k"}})
+	remove := AddHook(x.Export)
+	defer remove()
+	With(F("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"), F("n", 3), F("ok", true)).Warning("first")
+	Info("second")
+	Error("third")
+	x.Close()
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	r := records[0]
+	if r["severityText"] != "WARNING" || r["severityNumber"] != 13.0 || r["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("wrong record: %v", r)
+	}
+	if body := r["body"].(map[string]interface{}); body["stringValue"] != "first" {
+		t.Errorf("wrong body: %v", body)
+	}
+	attrs := map[string]interface{}{}
+	for _, a := range r["attributes"].([]interface{}) {
+		a := a.(map[string]interface{})
+		attrs[a["key"].(string)] = a["value"]
+	}
+	if n := attrs["n"].(map[string]interface{}); n["intValue"] != "3" {
+		t.Errorf("wrong n attribute: %v", attrs)
+	}
+	if ok := attrs["ok"].(map[string]interface{}); ok["boolValue"] != true {
+		t.Errorf("wrong ok attribute: %v", attrs)
+	}
+	if file := attrs["code.filepath"].(map[string]interface{}); file["stringValue"] != "otlp_test.go" {
+		t.Errorf("wrong file attribute: %v", attrs)
+	}
+	if x.Dropped() != 0 {
+		t.Errorf("dropped %d entries", x.Dropped())
+	}
+}
+
+func TestOTLPExporterFailure(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	x := NewOTLPExporter(srv.URL+"/logs", OTLPOptions{Retries: 1, Interval: time.Hour})
+	x.Export(Entry{Severity: InfoLog, Time: time.Now(), Message: "lost"})
+	x.Close()
+	if x.Dropped() != 1 {
+		t.Errorf("dropped %d entries, want 1", x.Dropped())
+	}
+	if !contains("] OTLP export failed endpoint=" + srv.URL + "/logs error=\"503 Service Unavailable\"") {
+		t.Errorf("failure not logged: %q", contents())
+	}
+	x.Export(Entry{Severity: InfoLog, Time: time.Now(), Message: "after close"})
+	if x.Dropped() != 2 {
+		t.Errorf("entry exported after Close")
+	}
+	if err := setOTLPEndpoint("collector:4318"); err == nil {
+		t.Error("endpoint without scheme accepted")
+	}
+}
+
+func TestOTLPExporterReplay(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var mu sync.Mutex
+	var sent int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				sent += len(sl.LogRecords)
+			}
+		}
+	}))
+	defer srv.Close()
+	entry := func(seq int) Entry {
+		n := json.Number(strconv.Itoa(seq))
+		return Entry{Severity: InfoLog, Time: time.Now(), Message: "replayed", Fields: []Field{F("seq", n)}}
+	}
+	x := NewOTLPExporter(srv.URL, OTLPOptions{Interval: time.Hour})
+	for seq := 1; seq <= 3; seq++ {
+		x.Export(entry(seq))
+	}
+	x.Close()
+	if x.Acked() != 3 {
+		t.Fatalf("acked %d, want 3", x.Acked())
+	}
+	// Replaying from the start after a reconnect only sends the new entries.
+	x = NewOTLPExporter(srv.URL, OTLPOptions{Interval: time.Hour, Acked: x.Acked()})
+	for seq := 1; seq <= 5; seq++ {
+		x.Export(entry(seq))
+	}
+	x.Close()
+	if sent != 5 || x.Duplicates() != 3 || x.Acked() != 5 {
+		t.Errorf("sent %d entries with %d duplicates and %d acked, want 5, 3 and 5", sent, x.Duplicates(), x.Acked())
+	}
+}
+
+func TestOTLPExporterAckedGap(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var failing int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	entry := func(seq int) Entry {
+		n := json.Number(strconv.Itoa(seq))
+		return Entry{Severity: InfoLog, Time: time.Now(), Message: "replayed", Fields: []Field{F("seq", n)}}
+	}
+	x := NewOTLPExporter(srv.URL, OTLPOptions{Interval: time.Hour, BatchSize: 2, Retries: 1})
+	defer x.Close()
+	waitFor := func(what string, ok func() bool) {
+		for deadline := time.Now().Add(5 * time.Second); !ok(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: acked %d, dropped %d", what, x.Acked(), x.Dropped())
+			}
+		}
+	}
+	x.Export(entry(1))
+	x.Export(entry(2))
+	waitFor("first batch", func() bool { return x.Acked() == 2 })
+	atomic.StoreInt32(&failing, 1)
+	x.Export(entry(3))
+	x.Export(entry(4))
+	waitFor("failed batch", func() bool { return x.Dropped() == 2 })
+	atomic.StoreInt32(&failing, 0)
+	x.Export(entry(5))
+	x.Export(entry(6))
+	x.Close()
+	// The entries of the failed batch must be replayed.
+	if x.Acked() != 2 {
+		t.Errorf("acked %d past the failed batch, want 2", x.Acked())
+	}
+}
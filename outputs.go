@@ -0,0 +1,91 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io"
+)
+
+// extraOutput is an output added with AddOutput.
+type extraOutput struct {
+	w           io.Writer
+	minSeverity Severity
+	lat         *latencyTracker
+}
+
+// SeverityWriter is implemented by outputs that need the severity of the
+// entries written to them, such as SyslogWriter. Such outputs get
+// WriteSeverity calls instead of Write calls.
+type SeverityWriter interface {
+	io.Writer
+	WriteSeverity(s Severity, p []byte) (n int, err error)
+}
+
+// writeSeverity writes data, an entry of severity s, to w.
+func writeSeverity(w io.Writer, s Severity, data []byte) (int, error) {
+	if sw, ok := w.(SeverityWriter); ok {
+		return sw.WriteSeverity(s, data)
+	}
+	return w.Write(data)
+}
+
+// AddOutput adds w as an output receiving the entries of at least
+// minSeverity, in addition to the output set by SetOutput, which receives
+// them all. For example, errors can go both to stderr and to a file while
+// info stays on stderr only:
+//
+//	flog.AddOutput(errorsFile, flog.ErrorLog)
+//
+// Entries of Loggers with an output of their own are not written to added
+// outputs.
+// This function is safe to use concurrently.
+func AddOutput(w io.Writer, minSeverity Severity) {
+	ensureInit()
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	// Copy on write, so the exit path can use the slice after unlocking.
+	outputs := make([]extraOutput, 0, len(logging.outputs)+1)
+	outputs = append(outputs, logging.outputs...)
+	logging.outputs = append(outputs, extraOutput{w, minSeverity, new(latencyTracker)})
+}
+
+// RemoveOutput removes w, which must be comparable, from the outputs added
+// with AddOutput and reports whether it was one.
+// This function is safe to use concurrently.
+func RemoveOutput(w io.Writer) bool {
+	ensureInit()
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	for i, o := range logging.outputs {
+		if o.w == w {
+			outputs := make([]extraOutput, 0, len(logging.outputs)-1)
+			outputs = append(outputs, logging.outputs[:i]...)
+			logging.outputs = append(outputs, logging.outputs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// writeExtra writes data, an entry of severity s, to the added outputs that
+// receive it.
+// l.mu is held.
+func (l *loggingT) writeExtra(s Severity, data []byte) {
+	for _, o := range l.outputs {
+		if s >= o.minSeverity {
+			writeSeverity(o.w, s, data)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAddOutput(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var errs bytes.Buffer
+	AddOutput(&errs, ErrorLog)
+	defer RemoveOutput(&errs)
+
+	Info("info")
+	Error("error")
+	if !contains("] info\n") || !contains("] error\n") {
+		t.Errorf("main output missed entries: %q", contents())
+	}
+	if strings.Contains(errs.String(), "info") || !strings.Contains(errs.String(), "] error\n") {
+		t.Errorf("added output didn't get errors only: %q", errs.String())
+	}
+
+	if !RemoveOutput(&errs) || RemoveOutput(&errs) {
+		t.Error("RemoveOutput didn't report the output it removed")
+	}
+	Error("after removal")
+	if strings.Contains(errs.String(), "after removal") {
+		t.Errorf("removed output still written: %q", errs.String())
+	}
+}
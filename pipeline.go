@@ -0,0 +1,376 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is a single log entry on its way through the pipeline.
+//
+// Every entry passes through the same stages, in this order:
+//
+//	filter → redact → enrich → sample → encode → output
+//
+// The first four are phases made of processors registered with
+// AddProcessor and friends. Within a phase, processors run in the order they
+// were placed in, so setups composed by different packages behave the same
+// no matter which package registered first.
+type Entry struct {
+	Severity Severity
+	Time     time.Time
+	// Mono is the monotonic time elapsed since the process started when the
+	// entry was logged.
+	Mono    time.Duration
+	File    string // base name of the source file
+	Line    int
+	Message string // without the trailing newline
+	Fields  []Field
+	// Tag identifies the goroutine that logged the entry, as set with SetTag
+	// or SetGoroutineIDs, empty if neither applies.
+	Tag string
+	// Stack is a stack trace written after the entry, if any.
+	Stack []byte
+
+	pc     uintptr       // the logging call site, zero when it is not known
+	output *loggerOutput // where the entry is written, nil for the package's output
+	raw    bool          // Message is written as is, see RawWrite
+	hooks  []Hook        // the hooks of the logger of the entry
+}
+
+// Field is a key/value pair attached to an entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Phase identifies a group of processors in the pipeline.
+type Phase int
+
+// The phases of the pipeline, in the order they run.
+const (
+	// PhaseFilter decides which entries are logged at all.
+	PhaseFilter Phase = iota
+	// PhaseRedact removes sensitive data from the entries that are logged.
+	PhaseRedact
+	// PhaseEnrich adds fields to entries.
+	PhaseEnrich
+	// PhaseSample thins out entries once they are complete.
+	PhaseSample
+	numPhases
+)
+
+var phaseName = [numPhases]string{
+	PhaseFilter: "filter",
+	PhaseRedact: "redact",
+	PhaseEnrich: "enrich",
+	PhaseSample: "sample",
+}
+
+// String returns the name of the phase.
+func (p Phase) String() string {
+	if p < 0 || p >= numPhases {
+		return "Phase(" + strconv.Itoa(int(p)) + ")"
+	}
+	return phaseName[p]
+}
+
+// A Processor inspects and may modify an entry. Returning false drops the
+// entry; later processors don't see it and it is not written. Critical and
+// Fatal entries are never dropped, so that the exit path runs: they go on
+// through the later processors instead.
+// Processors may be called concurrently and must not log through flog.
+type Processor func(e *Entry) bool
+
+// stage is a named processor in the pipeline.
+type stage struct {
+	name  string
+	phase Phase
+	p     Processor
+	// redact is the function of a redactor added with AddRedactor, which
+	// also scrubs the stack traces, nil for other processors.
+	redact func(s string) string
+}
+
+// pipeline is an immutable list of stages sorted by phase. Changes replace it
+// as a whole so the hot path can read it without locking.
+type pipeline struct {
+	stages []stage
+}
+
+// find returns the index of the stage with the given name, or -1.
+func (p *pipeline) find(name string) int {
+	for i, s := range p.stages {
+		if s.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// insert returns a copy of p with s inserted at index i.
+func (p *pipeline) insert(i int, s stage) *pipeline {
+	stages := make([]stage, 0, len(p.stages)+1)
+	stages = append(stages, p.stages[:i]...)
+	stages = append(stages, s)
+	stages = append(stages, p.stages[i:]...)
+	return &pipeline{stages: stages}
+}
+
+// loadPipeline returns the current pipeline, which may be empty.
+func (l *loggingT) loadPipeline() *pipeline {
+	if p, ok := l.pipeline.Load().(*pipeline); ok {
+		return p
+	}
+	return &pipeline{}
+}
+
+// updatePipeline replaces the pipeline with the result of f, unless f fails.
+func (l *loggingT) updatePipeline(f func(p *pipeline) (*pipeline, error)) error {
+	l.pipelineMu.Lock()
+	defer l.pipelineMu.Unlock()
+	p, err := f(l.loadPipeline())
+	if err != nil {
+		return err
+	}
+	l.pipeline.Store(p)
+	return nil
+}
+
+// process runs e through the processors and reports whether it survived.
+func (l *loggingT) process(e *Entry) bool {
+	p, ok := l.pipeline.Load().(*pipeline)
+	if !ok {
+		return true
+	}
+	dryRun := atomic.LoadInt32(&l.dryRun) != 0
+	for _, s := range p.stages {
+		if dryRun && s.phase == PhaseRedact {
+			redactStage(s, e)
+			continue
+		}
+		if dryRun && s.phase != PhaseEnrich {
+			dryRunStage(s, e)
+			continue
+		}
+		if !s.p(e) && e.Severity < CriticalLog {
+			return false
+		}
+	}
+	return true
+}
+
+// Fields added to entries by processors in dry-run mode.
+const (
+	wouldDropKey    = "would_drop"
+	wouldModifyKey  = "would_modify"
+	matchedRuleKey  = "matched_rule"
+	matchedRulesSep = ","
+)
+
+// dryRunStage runs s on a copy of e and annotates e with what s would have
+// done to it.
+func dryRunStage(s stage, e *Entry) {
+	c := *e
+	c.Fields = append([]Field(nil), e.Fields...)
+	switch {
+	case !s.p(&c):
+		e.setField(wouldDropKey, true)
+	case c.Message != e.Message || !reflect.DeepEqual(c.Fields, e.Fields):
+		e.setField(wouldModifyKey, true)
+	default:
+		return
+	}
+	e.matchRule(s.name)
+}
+
+// redactStage runs the redact stage s on e in dry-run mode: s acts, so that
+// no secret is written, but it can't drop e, and e is annotated as by
+// dryRunStage if s modified it.
+func redactStage(s stage, e *Entry) {
+	msg, fields := e.Message, append([]Field(nil), e.Fields...)
+	s.p(e)
+	if msg != e.Message || !reflect.DeepEqual(fields, e.Fields) {
+		e.setField(wouldModifyKey, true)
+		e.matchRule(s.name)
+	}
+}
+
+// matchRule adds the processor name to the matched_rule field of e.
+func (e *Entry) matchRule(name string) {
+	if i := e.field(matchedRuleKey); i >= 0 {
+		e.Fields[i].Value = fmt.Sprint(e.Fields[i].Value) + matchedRulesSep + name
+		return
+	}
+	e.Fields = append(e.Fields, Field{matchedRuleKey, name})
+}
+
+// field returns the index of the field with the given key, or -1.
+func (e *Entry) field(key string) int {
+	for i, f := range e.Fields {
+		if f.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// setField sets the value of the field with the given key, adding it if
+// needed.
+func (e *Entry) setField(key string, value interface{}) {
+	if i := e.field(key); i >= 0 {
+		e.Fields[i].Value = value
+		return
+	}
+	e.Fields = append(e.Fields, Field{key, value})
+}
+
+// SetDryRun turns dry-run mode on or off. In dry-run mode the processors of
+// the filter and sample phases don't act on entries. Instead, an entry they
+// would drop gets a would_drop=true field, an entry they would modify gets a
+// would_modify=true field, and either way the names of those processors are
+// listed in a matched_rule field. This allows trying new rules on real
+// traffic before enforcing them. Redact processors still act, so that dry
+// runs don't leak secrets, but can't drop entries; an entry they modified
+// gets the same fields. Enrich processors always act, and so do the sampling
+// of SetSampleRate, deduplication and suppressions, which are not
+// processors.
+func SetDryRun(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.dryRun, v)
+}
+
+// AddProcessor appends p under name to the end of the given phase.
+// Names must be unique across the pipeline.
+func AddProcessor(phase Phase, name string, p Processor) error {
+	ensureInit()
+	return addStage(stage{name: name, phase: phase, p: p})
+}
+
+// addStage appends s to the end of its phase.
+func addStage(s stage) error {
+	if s.phase < 0 || s.phase >= numPhases {
+		return fmt.Errorf("unknown phase %v", s.phase)
+	}
+	return logging.updatePipeline(func(pl *pipeline) (*pipeline, error) {
+		if pl.find(s.name) >= 0 {
+			return nil, fmt.Errorf("processor %q already exists", s.name)
+		}
+		i := 0
+		for i < len(pl.stages) && pl.stages[i].phase <= s.phase {
+			i++
+		}
+		return pl.insert(i, s), nil
+	})
+}
+
+// InsertProcessorBefore inserts p under name right before the processor
+// named before, in the same phase.
+func InsertProcessorBefore(before, name string, p Processor) error {
+	ensureInit()
+	return insertProcessorAt(before, 0, name, p)
+}
+
+// InsertProcessorAfter inserts p under name right after the processor named
+// after, in the same phase.
+func InsertProcessorAfter(after, name string, p Processor) error {
+	ensureInit()
+	return insertProcessorAt(after, 1, name, p)
+}
+
+// insertProcessorAt inserts p at offset from the processor named at.
+func insertProcessorAt(at string, offset int, name string, p Processor) error {
+	return logging.updatePipeline(func(pl *pipeline) (*pipeline, error) {
+		if pl.find(name) >= 0 {
+			return nil, fmt.Errorf("processor %q already exists", name)
+		}
+		i := pl.find(at)
+		if i < 0 {
+			return nil, fmt.Errorf("no processor named %q", at)
+		}
+		return pl.insert(i+offset, stage{name: name, phase: pl.stages[i].phase, p: p}), nil
+	})
+}
+
+// RemoveProcessor removes the processor with the given name and reports
+// whether there was one.
+func RemoveProcessor(name string) bool {
+	ensureInit()
+	err := logging.updatePipeline(func(pl *pipeline) (*pipeline, error) {
+		i := pl.find(name)
+		if i < 0 {
+			return nil, fmt.Errorf("no processor named %q", name)
+		}
+		stages := make([]stage, 0, len(pl.stages)-1)
+		stages = append(stages, pl.stages[:i]...)
+		stages = append(stages, pl.stages[i+1:]...)
+		return &pipeline{stages: stages}, nil
+	})
+	return err == nil
+}
+
+// redactStack returns stack scrubbed by the redactors added with
+// AddRedactor.
+func (l *loggingT) redactStack(stack []byte) []byte {
+	for _, s := range l.loadPipeline().stages {
+		if s.redact != nil {
+			stack = []byte(s.redact(string(stack)))
+		}
+	}
+	return stack
+}
+
+// Processors returns the processors in the order they run, as phase/name.
+func Processors() []string {
+	p := logging.loadPipeline()
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.phase.String() + "/" + s.name
+	}
+	return names
+}
+
+// writeFields appends the fields to buf as " key=value" pairs. Values that
+// would be ambiguous unquoted are quoted.
+func writeFields(buf *buffer, fields []Field) {
+	for _, f := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		writeTextValue(buf, f.Value)
+	}
+}
+
+// writeTextValue appends the value of a field to buf, quoted if it would be
+// ambiguous unquoted.
+func writeTextValue(buf *buffer, value interface{}) {
+	v, _, ok := floatField(value)
+	if !ok {
+		v = fmt.Sprint(value)
+	}
+	if v == "" || strings.ContainsAny(v, " =\"\t\r\n") {
+		v = strconv.Quote(v)
+	}
+	buf.WriteString(v)
+}
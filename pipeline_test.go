@@ -0,0 +1,161 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// resetPipeline removes all processors.
+func resetPipeline() {
+	logging.pipeline.Store(&pipeline{})
+}
+
+func TestProcessorOrder(t *testing.T) {
+	defer resetPipeline()
+	var order []string
+	record := func(name string) Processor {
+		return func(e *Entry) bool {
+			order = append(order, name)
+			return true
+		}
+	}
+	// Register out of order on purpose.
+	mustAdd := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustAdd(AddProcessor(PhaseSample, "sampler", record("sampler")))
+	mustAdd(AddProcessor(PhaseEnrich, "host", record("host")))
+	mustAdd(AddProcessor(PhaseFilter, "noise", record("noise")))
+	mustAdd(AddProcessor(PhaseRedact, "secrets", record("secrets")))
+	mustAdd(InsertProcessorBefore("host", "region", record("region")))
+	mustAdd(InsertProcessorAfter("noise", "health", record("health")))
+	if err := AddProcessor(PhaseFilter, "noise", record("noise")); err == nil {
+		t.Error("adding a duplicate processor succeeded")
+	}
+	if err := InsertProcessorAfter("missing", "x", record("x")); err == nil {
+		t.Error("inserting after a missing processor succeeded")
+	}
+
+	want := []string{"filter/noise", "filter/health", "redact/secrets", "enrich/region", "enrich/host", "sample/sampler"}
+	if got := Processors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Processors() = %v, want %v", got, want)
+	}
+
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Info("test")
+	want = []string{"noise", "health", "secrets", "region", "host", "sampler"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("processors ran in order %v, want %v", order, want)
+	}
+
+	if !RemoveProcessor("health") || RemoveProcessor("health") {
+		t.Error("RemoveProcessor did not remove exactly once")
+	}
+}
+
+func TestProcessorDropAndModify(t *testing.T) {
+	defer resetPipeline()
+	AddProcessor(PhaseFilter, "drop-health", func(e *Entry) bool {
+		return !strings.Contains(e.Message, "/healthz")
+	})
+	AddProcessor(PhaseEnrich, "region", func(e *Entry) bool {
+		e.Fields = append(e.Fields, Field{"region", "us east"}, Field{"shard", 7})
+		return true
+	})
+
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Info("GET /healthz")
+	if contents() != "" {
+		t.Errorf("dropped entry was written: %q", contents())
+	}
+	Info("GET /")
+	if !strings.HasSuffix(contents(), `] GET / region="us east" shard=7`+"\n") {
+		t.Errorf("fields not written: %q", contents())
+	}
+}
+
+func TestProcessorKeepsFatal(t *testing.T) {
+	defer resetPipeline()
+	AddProcessor(PhaseFilter, "drop-all", func(e *Entry) bool { return false })
+	AddProcessor(PhaseSample, "sample-all", func(e *Entry) bool { return false })
+	var exits []int
+	SetExitFunc(func(code int) { exits = append(exits, code) })
+	defer SetExitFunc(nil)
+
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Error("dropped")
+	Critical("critical")
+	Fatal("fatal")
+	if contains("dropped") || !contains("] critical\n") || !contains("] fatal\n") {
+		t.Errorf("wrong entries dropped: %q", contents())
+	}
+	if len(exits) == 0 {
+		t.Error("Fatal dropped by a processor didn't exit")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	defer resetPipeline()
+	defer SetDryRun(false)
+	AddProcessor(PhaseFilter, "drop-health", func(e *Entry) bool {
+		return !strings.Contains(e.Message, "/healthz")
+	})
+	AddProcessor(PhaseRedact, "tokens", func(e *Entry) bool {
+		e.Message = strings.Replace(e.Message, "s3cr3t", "xxx", -1)
+		return true
+	})
+	AddProcessor(PhaseSample, "drop-all", func(e *Entry) bool {
+		return false
+	})
+	AddProcessor(PhaseEnrich, "region", func(e *Entry) bool {
+		e.Fields = append(e.Fields, Field{"region", "us"})
+		return true
+	})
+	SetDryRun(true)
+
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Info("GET /healthz?token=s3cr3t")
+	want := "] GET /healthz?token=xxx would_drop=true matched_rule=drop-health,tokens,drop-all would_modify=true region=us\n"
+	if !strings.HasSuffix(contents(), want) {
+		t.Errorf("got %q, want suffix %q", contents(), want)
+	}
+}
+
+func TestDryRunRedact(t *testing.T) {
+	defer resetPipeline()
+	defer SetDryRun(false)
+	AddProcessor(PhaseRedact, "refuse", func(e *Entry) bool {
+		return false
+	})
+	RedactPattern("bearer", `Bearer [A-Za-z0-9._-]+`)
+	SetDryRun(true)
+
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	Info("sent Bearer abc.def")
+	Info("nothing secret")
+	if !contains("] sent <redacted> would_modify=true matched_rule=bearer\n") || !contains("] nothing secret\n") {
+		t.Errorf("wrong dry-run redaction: %q", contents())
+	}
+}
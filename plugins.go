@@ -0,0 +1,151 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// plugins holds the processors registered with RegisterPlugin.
+var plugins struct {
+	mu         sync.Mutex
+	registered map[string]plugin
+	// enabled lists the names of the enabled plugins, in order.
+	enabled []string
+	// pending holds the value of FLOG_PLUGINS if it named a plugin that
+	// wasn't registered yet.
+	pending string
+}
+
+// plugin is a processor registered with RegisterPlugin.
+type plugin struct {
+	phase Phase
+	p     Processor
+}
+
+// pluginStagePrefix prefixes the names of the processors of plugins in the
+// pipeline, so they don't clash with the names given to AddProcessor.
+const pluginStagePrefix = "plugin:"
+
+// unknownPluginError is returned when enabling a plugin that wasn't
+// registered.
+type unknownPluginError string
+
+func (e unknownPluginError) Error() string {
+	return "unknown plugin " + strconv.Quote(string(e))
+}
+
+// RegisterPlugin registers p under name as a processor of the given phase
+// that can be enabled by name, through the -log_plugins flag, FLOG_PLUGINS or
+// Config.Plugins, rather than by code. This allows shipping logging policy,
+// such as redactors or enrichers, as a package that registers its plugins
+// from init and is activated by configuration:
+//
+//	import _ "example.com/logpolicy" // registers "pii" and "region"
+//
+//	FLOG_PLUGINS=pii,region
+//
+// Since env vars are read before plugins can be registered, FLOG_PLUGINS is
+// applied again once the plugins it names are registered, unless the setting
+// has been changed since.
+func RegisterPlugin(name string, phase Phase, p Processor) error {
+	if name == "" || strings.ContainsAny(name, ", ") {
+		return errors.New("invalid plugin name: " + strconv.Quote(name))
+	}
+	if phase < 0 || phase >= numPhases {
+		return fmt.Errorf("unknown phase %v", phase)
+	}
+	plugins.mu.Lock()
+	if _, ok := plugins.registered[name]; ok {
+		plugins.mu.Unlock()
+		return fmt.Errorf("plugin %q already registered", name)
+	}
+	if plugins.registered == nil {
+		plugins.registered = make(map[string]plugin)
+	}
+	plugins.registered[name] = plugin{phase, p}
+	pending := plugins.pending
+	plugins.mu.Unlock()
+
+	if pending != "" {
+		enabledPlugins.Set(pending)
+	}
+	return nil
+}
+
+// pluginSpec is the state of the -log_plugins flag.
+type pluginSpec struct{}
+
+var enabledPlugins pluginSpec
+
+// String is part of the flag.Value interface.
+func (*pluginSpec) String() string {
+	plugins.mu.Lock()
+	defer plugins.mu.Unlock()
+	return strings.Join(plugins.enabled, ",")
+}
+
+// Set is part of the flag.Value interface. It enables the plugins in the
+// comma-separated list value and disables the others. The processors of the
+// plugins run at the end of their phase, in the order of the list.
+func (*pluginSpec) Set(value string) error {
+	ensureInit()
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	plugins.mu.Lock()
+	defer plugins.mu.Unlock()
+	for i, name := range names {
+		if _, ok := plugins.registered[name]; !ok {
+			return unknownPluginError(name)
+		}
+		for _, prev := range names[:i] {
+			if prev == name {
+				return fmt.Errorf("plugin %q enabled twice", name)
+			}
+		}
+	}
+	err := logging.updatePipeline(func(pl *pipeline) (*pipeline, error) {
+		stages := make([]stage, 0, len(pl.stages)+len(names))
+		for _, s := range pl.stages {
+			if !strings.HasPrefix(s.name, pluginStagePrefix) {
+				stages = append(stages, s)
+			}
+		}
+		pl = &pipeline{stages: stages}
+		for _, name := range names {
+			p := plugins.registered[name]
+			i := 0
+			for i < len(pl.stages) && pl.stages[i].phase <= p.phase {
+				i++
+			}
+			pl = pl.insert(i, stage{name: pluginStagePrefix + name, phase: p.phase, p: p.p})
+		}
+		return pl, nil
+	})
+	if err != nil {
+		return err
+	}
+	plugins.enabled = names
+	plugins.pending = ""
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlugins(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer resetPipeline()
+	defer func() { plugins.registered, plugins.enabled = nil, nil }()
+
+	enrich := func(key string) Processor {
+		return func(e *Entry) bool {
+			e.Fields = append(e.Fields, Field{key, "yes"})
+			return true
+		}
+	}
+	if err := RegisterPlugin("region", PhaseEnrich, enrich("region")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterPlugin("team", PhaseEnrich, enrich("team")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterPlugin("team", PhaseEnrich, enrich("team")); err == nil {
+		t.Error("registered a plugin twice")
+	}
+	if err := AddProcessor(PhaseRedact, "local", func(*Entry) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enabledPlugins.Set("team, region"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"redact/local", "enrich/plugin:team", "enrich/plugin:region"}
+	if got := Processors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Processors() = %q, want %q", got, want)
+	}
+	Info("hello")
+	if !contains("] hello team=yes region=yes\n") {
+		t.Errorf("plugins not run in order: %q", contents())
+	}
+
+	if err := enabledPlugins.Set("region,nope"); err == nil {
+		t.Error("enabled an unknown plugin")
+	}
+	if err := enabledPlugins.Set("region"); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"redact/local", "enrich/plugin:region"}
+	if got := Processors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Processors() = %q, want %q", got, want)
+	}
+	if got := enabledPlugins.String(); got != "region" {
+		t.Errorf("String() = %q, want region", got)
+	}
+}
+
+func TestPendingPlugins(t *testing.T) {
+	defer resetPipeline()
+	defer func() { plugins.registered, plugins.enabled = nil, nil }()
+	plugins.pending = "late"
+	if err := RegisterPlugin("late", PhaseFilter, func(*Entry) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Processors(), []string{"filter/plugin:late"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Processors() = %q, want %q", got, want)
+	}
+	if plugins.pending != "" {
+		t.Errorf("pending = %q after registration", plugins.pending)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// ExitAction is what happens once a Critical or Fatal log has been written.
+type ExitAction int
+
+const (
+	// ActionDefault keeps the built-in behavior for the severity: Critical
+	// logs return to the caller and Fatal logs exit the process.
+	ActionDefault ExitAction = iota
+	// ActionLog writes the log and returns to the caller.
+	ActionLog
+	// ActionPanic writes the log and then panics with the log line.
+	ActionPanic
+	// ActionExit writes the log and exits the process with status 255.
+	ActionExit
+	// ActionFlushExit writes the log, flushes the outputs for at most the
+	// policy's FlushTimeout and exits the process with status 255.
+	ActionFlushExit
+)
+
+// ExitPolicy controls what Critical and Fatal logs do after they have been
+// written. The zero value keeps the default behavior.
+// Exit and its relatives always exit the process, regardless of the policy.
+type ExitPolicy struct {
+	Critical ExitAction
+	Fatal    ExitAction
+	// FlushTimeout bounds the time ActionFlushExit waits for the output to
+	// be flushed. Zero means wait for as long as it takes.
+	FlushTimeout time.Duration
+}
+
+// SetExitPolicy sets the policy applied to Critical and Fatal logs.
+// This function is safe to use concurrently.
+func SetExitPolicy(p ExitPolicy) {
+	ensureInit()
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.exitPolicy = p
+}
+
+// GetExitPolicy gets the current exit policy.
+func GetExitPolicy() ExitPolicy {
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	return logging.exitPolicy
+}
+
+// exitAction resolves the action to take after a log of severity s.
+// l.mu is held.
+func (l *loggingT) exitAction(s Severity) ExitAction {
+	switch s {
+	case CriticalLog:
+		if l.exitPolicy.Critical != ActionDefault {
+			return l.exitPolicy.Critical
+		}
+	case FatalLog:
+		if l.exitPolicy.Fatal != ActionDefault {
+			return l.exitPolicy.Fatal
+		}
+		return ActionExit
+	}
+	return ActionLog
+}
+
+// exitHooks holds the functions set by OnFatal and SetExitFunc.
+var exitHooks struct {
+	mu      sync.Mutex
+	onFatal []func()
+	exit    func(code int) // nil for os.Exit
+	// exiting is set while the OnFatal functions run, so an exit they cause
+	// doesn't run them again.
+	exiting bool
+}
+
+// OnFatal registers f to be called before flog exits the process, after a
+// Fatal log or a log the exit policy exits on, so the application can flush
+// metrics or close databases, which deferred calls don't get to do. The
+// functions are called in the order they were registered, in the goroutine
+// that logged, with the log written but not yet flushed.
+// This function is safe to use concurrently.
+func OnFatal(f func()) {
+	exitHooks.mu.Lock()
+	defer exitHooks.mu.Unlock()
+	exitHooks.onFatal = append(exitHooks.onFatal, f)
+}
+
+// SetExitFunc makes flog call f instead of os.Exit when it exits the
+// process, such as to turn Fatal into a panic in tests. If f returns, the
+// logging call returns too. A nil f restores os.Exit.
+// This function is safe to use concurrently.
+func SetExitFunc(f func(code int)) {
+	ensureInit()
+	exitHooks.mu.Lock()
+	defer exitHooks.mu.Unlock()
+	exitHooks.exit = f
+}
+
+// exitProcess calls the OnFatal functions, then flush if it is not nil, and
+// exits with code.
+func exitProcess(code int, flush func()) {
+	exitHooks.mu.Lock()
+	onFatal, exit := exitHooks.onFatal, exitHooks.exit
+	if exitHooks.exiting {
+		onFatal = nil
+	}
+	exitHooks.exiting = true
+	exitHooks.mu.Unlock()
+	for _, f := range onFatal {
+		f()
+	}
+	saveCounters()
+	if flush != nil {
+		flush()
+	}
+	exitHooks.mu.Lock()
+	exitHooks.exiting = false
+	exitHooks.mu.Unlock()
+	if exit == nil {
+		exit = os.Exit
+	}
+	exit(code)
+}
+
+// syncer is implemented by outputs that buffer data, such as *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// flusher is implemented by outputs that buffer data, such as *bufio.Writer.
+type flusher interface {
+	Flush() error
+}
+
+// flushOutputs flushes the outputs that buffer data, concurrently, giving up
+// after timeout if it is positive.
+func flushOutputs(outs []interface{}, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for _, w := range outs {
+			wg.Add(1)
+			go func(w interface{}) {
+				defer wg.Done()
+				switch w := w.(type) {
+				case flusher:
+					w.Flush()
+				case syncer:
+					w.Sync()
+				}
+			}(w)
+		}
+		wg.Wait()
+	}()
+	if timeout <= 0 {
+		<-done
+		return
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+	case <-t.C:
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+)
+
+// profileKey is the key of the field holding the path of a profile captured
+// for an entry.
+const profileKey = "profile"
+
+// ProfileTrigger configures the capture of a profile when a Critical entry
+// is logged, tying the diagnostic artifact to the event that triggered it.
+type ProfileTrigger struct {
+	// Dir is the directory profiles are written to. Empty turns captures off.
+	Dir string
+	// Profile is the name of the runtime/pprof profile to capture, such as
+	// "heap" or "goroutine".
+	Profile string
+	// MinInterval is the minimum time between two captures. Critical
+	// entries logged in between don't trigger any.
+	MinInterval time.Duration
+}
+
+// profileState is a ProfileTrigger in effect.
+type profileState struct {
+	ProfileTrigger
+	// last is the time of the latest capture in Unix nanoseconds. It is read
+	// and written using sync/atomic.
+	last int64
+}
+
+// SetProfileTrigger sets the profile captured on Critical entries. The
+// profile is written to a file named after the profile, the pid and the time
+// in t.Dir, and the path of the file is added to the entry as a profile
+// field.
+// This function is safe to use concurrently.
+func SetProfileTrigger(t ProfileTrigger) error {
+	ensureInit()
+	if t.Dir == "" {
+		logging.profile.Store((*profileState)(nil))
+		return nil
+	}
+	if pprof.Lookup(t.Profile) == nil {
+		return errors.New("unknown profile " + t.Profile)
+	}
+	logging.profile.Store(&profileState{ProfileTrigger: t})
+	return nil
+}
+
+// captureProfile captures the configured profile for e, a Critical entry,
+// unless one was captured too recently.
+func (l *loggingT) captureProfile(e *Entry) {
+	p, _ := l.profile.Load().(*profileState)
+	if p == nil {
+		return
+	}
+	now := e.Time.UnixNano()
+	last := atomic.LoadInt64(&p.last)
+	if last != 0 && now-last < int64(p.MinInterval) || !atomic.CompareAndSwapInt64(&p.last, last, now) {
+		return
+	}
+	name := fmt.Sprintf("flog-%s-%d-%s.pb.gz", p.Profile, getPid(), e.Time.Format("20060102-150405.000000"))
+	path := filepath.Join(p.Dir, name)
+	if err := writeProfile(p.Profile, path); err != nil {
+		e.setField(profileKey, "error: "+err.Error())
+		return
+	}
+	e.setField(profileKey, path)
+}
+
+// writeProfile writes the named profile to the file at path.
+func writeProfile(profile, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = pprof.Lookup(profile).WriteTo(f, 0)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfileTrigger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if SetProfileTrigger(ProfileTrigger{Dir: dir, Profile: "nope"}) == nil {
+		t.Error("unknown profile accepted")
+	}
+	if err := SetProfileTrigger(ProfileTrigger{Dir: dir, Profile: "goroutine", MinInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProfileTrigger(ProfileTrigger{})
+
+	Error("not critical")
+	Critical("first")
+	Critical("second")
+	files, _ := filepath.Glob(filepath.Join(dir, "flog-goroutine-*.pb.gz"))
+	if len(files) != 1 {
+		t.Fatalf("got profiles %v, want one", files)
+	}
+	if !contains("] first profile=" + files[0] + "\n") {
+		t.Errorf("profile path not logged: %q", contents())
+	}
+	if strings.Count(contents(), "profile=") != 1 {
+		t.Errorf("capture not rate limited: %q", contents())
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+)
+
+// RaiseV returns a logger sharing the output and fields of lg whose V
+// enables the levels up to level, whatever -v, -vmodule and -vlogger say,
+// so the verbose entries of a single request or tenant can be turned on
+// without turning them on for all traffic. The settings still apply to the
+// higher levels, and a lower level than that of lg has no effect.
+func (lg *Logger) RaiseV(level Level) *Logger {
+	child := lg.With()
+	if level > child.minV {
+		child.minV = level
+	}
+	return child
+}
+
+// VerbosityContext returns a copy of ctx carrying its logger, as returned
+// by FromContext, raised to level as described for RaiseV, so the code
+// handling a request logs its verbose entries with
+//
+//	flog.FromContext(ctx).V(2).Info("cache lookup")
+//
+// from then on:
+//
+//	if r.Header.Get("X-Debug") != "" {
+//		ctx = flog.VerbosityContext(ctx, 3)
+//	}
+func VerbosityContext(ctx context.Context, level Level) context.Context {
+	return NewContext(ctx, FromContext(ctx).RaiseV(level))
+}
@@ -0,0 +1,44 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRaiseV(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.vlogger.Set("")
+	if err := logging.vlogger.Set("tenant=0"); err != nil {
+		t.Fatal(err)
+	}
+	lg := With(F("tenant", "a")).RaiseV(2).RaiseV(1)
+	lg.V(2).Info("raised")
+	lg.V(3).Info("above")
+	lg.Named("tenant").V(2).Info("named")
+	With(F("tenant", "b")).V(1).Info("other tenant")
+	ctx := VerbosityContext(NewContext(context.Background(), With(F("req", "r1"))), 1)
+	FromContext(ctx).V(1).Info("request")
+	for _, want := range []string{"] raised tenant=a\n", "] named tenant=a logger=tenant\n", "] request req=r1\n"} {
+		if !contains(want) {
+			t.Errorf("%q not logged: %q", want, contents())
+		}
+	}
+	if contains("above") || contains("other tenant") {
+		t.Errorf("levels enabled beyond the raised ones: %q", contents())
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+// RawWrite writes line, which the caller already formatted, as an entry of
+// severity s, such as when replaying captured logs. The line is written as
+// is, with a newline appended if missing: no header, fields or stack trace
+// are added, whatever the format. Everything else applies as for the other
+// entries: the minimum severity, processors, suppressions and sampling, which
+// see the line as the message of an entry logged at the call site of
+// RawWrite, as well as hooks, the memory budget, the outputs and Stats.
+// Critical and Fatal lines are written without taking the exit policy into
+// account, since they record events of another time or process.
+func RawWrite(s Severity, line []byte) {
+	logging.rawWrite(s, line)
+}
+
+func (l *loggingT) rawWrite(s Severity, line []byte) {
+	if s < DebugLog || s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	if l.belowMinSeverity(s) {
+		return
+	}
+	pc, file, n := caller(0)
+	e := newEntry(s, pc, file, n, string(line))
+	e.raw = true
+	l.output(e)
+}
@@ -0,0 +1,66 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRawWrite(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	lines := Stats.Warning.Lines()
+	RawWrite(WarningLog, []byte("W0301 10:00:00.000000 42 old.go:7] replayed"))
+	if got := contents(); got != "W0301 10:00:00.000000 42 old.go:7] replayed\n" {
+		t.Errorf("raw line written as %q", got)
+	}
+	if Stats.Warning.Lines() != lines+1 {
+		t.Error("raw line not counted")
+	}
+
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	logging.newBuffers()
+	RawWrite(ErrorLog, []byte(`{"message":"replayed"}`+"\n"))
+	if got := contents(); got != `{"message":"replayed"}`+"\n" {
+		t.Errorf("raw line written as %q in JSON", got)
+	}
+}
+
+func TestRawWriteFilters(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer resetPipeline()
+	AddProcessor(PhaseRedact, "secrets", func(e *Entry) bool {
+		e.Message = strings.Replace(e.Message, "hunter2", "<redacted>", -1)
+		return !strings.Contains(e.Message, "health")
+	})
+	RawWrite(InfoLog, []byte("I0301 x.go:1] health check"))
+	RawWrite(InfoLog, []byte("I0301 x.go:1] password=hunter2"))
+	if got := contents(); got != "I0301 x.go:1] password=<redacted>\n" {
+		t.Errorf("raw lines not filtered: %q", got)
+	}
+
+	SetMinSeverity(WarningLog)
+	defer SetMinSeverity(DebugLog)
+	logging.newBuffers()
+	RawWrite(InfoLog, []byte("I0301 x.go:1] dropped"))
+	// A replayed Fatal line doesn't make the process exit.
+	RawWrite(FatalLog, []byte("F0301 x.go:1] replayed"))
+	if got := contents(); got != "F0301 x.go:1] replayed\n" {
+		t.Errorf("wrong raw lines: %q", got)
+	}
+}
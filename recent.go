@@ -0,0 +1,285 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recent is a ring buffer of the last entries written.
+type recent struct {
+	// size is the capacity of the ring, zero if entries are not kept. It is
+	// read using sync/atomic, but is only modified under mu.
+	size int32
+
+	mu      sync.Mutex
+	entries []Entry
+	next    int // index of the slot for the next entry
+	full    bool
+}
+
+// KeepRecent keeps the last n entries written in memory, for TailRecent.
+// Zero, the default, discards them.
+// This function is safe to use concurrently.
+func KeepRecent(n int) {
+	if n < 0 {
+		n = 0
+	}
+	r := &logging.recent
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries, r.next, r.full = make([]Entry, n), 0, false
+	atomic.StoreInt32(&r.size, int32(n))
+}
+
+// add keeps a copy of e.
+func (r *recent) add(e *Entry) {
+	if atomic.LoadInt32(&r.size) == 0 {
+		return
+	}
+	c := *e
+	c.Fields = append([]Field(nil), e.Fields...)
+	c.pc, c.output = 0, nil
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.next] = c
+	r.next++
+	if r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+}
+
+// last returns up to n of the kept entries, oldest first.
+func (r *recent) last(n int) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	all := r.entries[:r.next]
+	if r.full {
+		all = append(append([]Entry(nil), r.entries[r.next:]...), all...)
+	}
+	if n < len(all) {
+		all = all[len(all)-n:]
+	}
+	return append([]Entry(nil), all...)
+}
+
+var errNoRecent = errors.New("no recent entries: call KeepRecent or set Config.LogFile")
+
+// TailRecent returns up to n of the last entries written, oldest first,
+// for pages showing recent logs. They come from memory if KeepRecent was
+// called, and are otherwise read back from the file set by Config.LogFile.
+// Entries read from the file in the text format have their fields in the
+// message, as they can't be told apart from it.
+func TailRecent(n int) ([]Entry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if atomic.LoadInt32(&logging.recent.size) > 0 {
+		return logging.recent.last(n), nil
+	}
+	configFile.mu.Lock()
+	var path string
+	if configFile.rf != nil {
+		path = configFile.rf.path
+	}
+	configFile.mu.Unlock()
+	if path == "" {
+		return nil, errNoRecent
+	}
+	return tailFile(path, n)
+}
+
+// tailChunk is the size of the chunks tailFile reads.
+const tailChunk = 64 << 10
+
+// tailFile parses up to n of the last entries of the file at path. Lines
+// that are not entries, such as stack traces, are skipped.
+func tailFile(path string, n int) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for off := info.Size(); ; {
+		size := int64(tailChunk)
+		if size > off {
+			size = off
+		}
+		off -= size
+		chunk := make([]byte, size, int(size)+len(data))
+		if _, err := f.ReadAt(chunk, off); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(chunk, data...)
+		lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+		if off > 0 {
+			lines = lines[1:] // possibly partial
+		}
+		var entries []Entry
+		for i := len(lines) - 1; i >= 0 && len(entries) < n; i-- {
+			if e, err := ParseEntry(lines[i]); err == nil {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == n || off == 0 {
+			for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+			return entries, nil
+		}
+	}
+}
+
+var errNotEntry = errors.New("not a log entry")
+
+// ParseEntry parses a line written in the text or JSON format, such as the
+// lines served by RecentHandler. Fields can only be told apart from the
+// message in the JSON format.
+func ParseEntry(line []byte) (Entry, error) {
+	if len(line) > 0 && line[0] == '{' {
+		return parseJSONEntry(line)
+	}
+	return parseTextEntry(string(line))
+}
+
+// parseTextEntry parses a line written in the text format, using the
+// current year, or the previous one for dates in the future.
+func parseTextEntry(line string) (Entry, error) {
+	if len(line) > 4 && line[0] == '|' && line[2] == '|' && line[3] == ' ' {
+		line = line[4:]
+	}
+	if len(line) < 30 {
+		return Entry{}, errNotEntry
+	}
+	s := strings.IndexByte(severityChar, line[0])
+	if s < 0 {
+		return Entry{}, errNotEntry
+	}
+	var t time.Time
+	var rest string
+	switch {
+	case line[5] == '-':
+		// Lyyyy-mm-ddThh:mm:ss.uuuuuu+hh:mm threadid file:line] msg
+		sp := strings.IndexByte(line, ' ')
+		if sp < 0 || len(line) < sp+9 {
+			return Entry{}, errNotEntry
+		}
+		var err error
+		if t, err = time.Parse(rfc3339Micro, line[1:sp]); err != nil {
+			return Entry{}, errNotEntry
+		}
+		rest = line[sp+9:]
+	case line[5] == ' ' && line[21] == ' ':
+		// Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg
+		now := timeNow()
+		var err error
+		t, err = time.ParseInLocation("2006 0102 15:04:05.000000", strconv.Itoa(now.Year())+" "+line[1:21], time.Local)
+		if err != nil {
+			return Entry{}, errNotEntry
+		}
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		rest = line[30:]
+	default:
+		return Entry{}, errNotEntry
+	}
+	end := strings.Index(rest, "] ")
+	if end < 0 {
+		return Entry{}, errNotEntry
+	}
+	var tag string
+	if sp := strings.IndexByte(rest[:end], ' '); sp >= 0 {
+		tag, rest, end = rest[:sp], rest[sp+1:], end-sp-1
+	}
+	colon := strings.LastIndexByte(rest[:end], ':')
+	if colon < 0 {
+		return Entry{}, errNotEntry
+	}
+	lineNo, err := strconv.Atoi(rest[colon+1 : end])
+	if err != nil {
+		return Entry{}, errNotEntry
+	}
+	return Entry{
+		Severity: Severity(s),
+		Time:     t,
+		Tag:      tag,
+		File:     rest[:colon],
+		Line:     lineNo,
+		Message:  rest[end+2:],
+	}, nil
+}
+
+// parseJSONEntry parses a line written in the JSON format. Members other
+// than the ones encodeJSON writes become fields, sorted by key.
+func parseJSONEntry(line []byte) (Entry, error) {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return Entry{}, errNotEntry
+	}
+	var e Entry
+	name, _ := m["severity"].(string)
+	e.Severity = -1
+	for s, sn := range severityName {
+		if sn == name {
+			e.Severity = Severity(s)
+		}
+	}
+	ts, _ := m["timestamp"].(string)
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if e.Severity < 0 || err != nil {
+		return Entry{}, errNotEntry
+	}
+	e.Time = t
+	e.Tag, _ = m["tag"].(string)
+	e.File, _ = m["file"].(string)
+	if n, ok := m["line"].(json.Number); ok {
+		line, _ := n.Int64()
+		e.Line = int(line)
+	}
+	e.Message, _ = m["message"].(string)
+	if stack, ok := m["stack"].(string); ok {
+		e.Stack = []byte(stack)
+	}
+	levels := logging.loadJSONLevels() != nil
+	for k, v := range m {
+		if jsonReserved[k] || levels && k == levelKey {
+			continue
+		}
+		e.Fields = append(e.Fields, Field{strings.TrimPrefix(k, "field."), v})
+	}
+	sort.Slice(e.Fields, func(i, j int) bool { return e.Fields[i].Key < e.Fields[j].Key })
+	return e, nil
+}
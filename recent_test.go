@@ -0,0 +1,83 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeepRecent(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	KeepRecent(2)
+	defer KeepRecent(0)
+	Info("one")
+	With(F("k", 1)).Warning("two")
+	Error("three")
+	entries, err := TailRecent(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Message != "two" || entries[1].Message != "three" {
+		t.Fatalf("TailRecent(5) = %+v, want two and three", entries)
+	}
+	if e := entries[0]; e.Severity != WarningLog || len(e.Fields) != 1 || e.Fields[0].Key != "k" {
+		t.Errorf("entry %+v, want a Warning with field k", e)
+	}
+}
+
+func TestTailRecentFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := setLogFile(filepath.Join(dir, "app.log"), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer setLogFile("", 0, 0)
+	Info("first")
+	Warning("second")
+	SetFormat(FormatJSON)
+	With(F("user", "ann"), F("line", 3)).Error("third")
+	SetFormat(FormatText)
+
+	entries, err := TailRecent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("TailRecent(2) = %+v, want 2 entries", entries)
+	}
+	if e := entries[0]; e.Severity != WarningLog || e.Message != "second" || e.File != "recent_test.go" || e.Line == 0 {
+		t.Errorf("text entry parsed as %+v", e)
+	}
+	e := entries[1]
+	if e.Severity != ErrorLog || e.Message != "third" || len(e.Fields) != 2 || e.Fields[0].Key != "line" || e.Fields[1] != (Field{"user", "ann"}) {
+		t.Errorf("JSON entry parsed as %+v", e)
+	}
+	if entries[0].Time.IsZero() || entries[0].Time.After(e.Time.Add(1e9)) {
+		t.Errorf("bad times %v and %v", entries[0].Time, e.Time)
+	}
+}
+
+func TestTailRecentNoSource(t *testing.T) {
+	if _, err := TailRecent(1); err != errNoRecent {
+		t.Errorf("TailRecent without a source: %v, want %v", err, errNoRecent)
+	}
+}
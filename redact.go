@@ -0,0 +1,116 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// AddRedactor adds f under name to the redact phase of the pipeline, so that
+// secrets, tokens and personal data can be scrubbed centrally before entries
+// are written. f is called with the message of every entry and the string
+// value of its fields that are strings, errors, fmt.Stringers or Lazy
+// values, and returns them scrubbed; values f leaves unchanged keep their
+// type. Values of other types, such as numbers, are not scrubbed. f is also
+// called with the stack traces written with entries or on Fatal. Redactors
+// act in dry-run mode too, see SetDryRun.
+// This function is safe to use concurrently.
+func AddRedactor(name string, f func(s string) string) error {
+	ensureInit()
+	return addStage(stage{name: name, phase: PhaseRedact, redact: f, p: func(e *Entry) bool {
+		e.Message = f(e.Message)
+		for i, field := range e.Fields {
+			if v, ok := redactValue(field.Value, f); ok {
+				e.Fields[i].Value = v
+			}
+		}
+		return true
+	}})
+}
+
+// redactValue returns v scrubbed by f, and whether f changed it.
+func redactValue(v interface{}, f func(string) string) (interface{}, bool) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case *LazyValue:
+		if r, ok := redactValue(v.Value(), f); ok {
+			return r, true
+		}
+		return v.Value(), true
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		return nil, false
+	}
+	if r := f(s); r != s {
+		return r, true
+	}
+	return nil, false
+}
+
+// RedactPattern adds a redactor under name replacing the matches of the
+// regular expression expr with "<redacted>", as described for AddRedactor:
+//
+//	flog.RedactPattern("bearer", `Bearer [A-Za-z0-9._-]+`)
+//
+// This function is safe to use concurrently.
+func RedactPattern(name, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	return AddRedactor(name, func(s string) string {
+		return re.ReplaceAllString(s, redacted)
+	})
+}
+
+// RedactFields adds a processor under name to the redact phase of the
+// pipeline replacing the value of the fields whose key matches any of
+// patterns, ignoring case, with "<redacted>". The patterns are matched as
+// by filepath.Match, such as "*token*"; DefaultRedact holds a common set.
+// This function is safe to use concurrently.
+func RedactFields(name string, patterns ...string) error {
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %v", p, err)
+		}
+	}
+	patterns = append([]string(nil), patterns...)
+	return AddProcessor(PhaseRedact, name, func(e *Entry) bool {
+		for i, field := range e.Fields {
+			if matchName(patterns, field.Key) {
+				e.Fields[i].Value = redacted
+			}
+		}
+		return true
+	})
+}
+
+// setRedactFields redacts the fields matching the comma-separated list of
+// patterns in value, or the DefaultRedact patterns if value is "default".
+func setRedactFields(value string) error {
+	patterns := DefaultRedact
+	if value != "default" {
+		patterns = strings.Split(value, ",")
+	}
+	return RedactFields("redact_fields", patterns...)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedactPattern(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if err := RedactPattern("bearer", `Bearer [A-Za-z0-9._-]+`); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveProcessor("bearer")
+	With(F("header", "Bearer abc.def"), F("err", errors.New("rejected Bearer xyz")), F("n", 3), F("size", ByteSize(2048))).
+		Warning("sent Bearer abc.def")
+	RawWrite(InfoLog, []byte("raw Bearer abc.def"))
+	if strings.Contains(contents(), "abc.def") || strings.Contains(contents(), "xyz") {
+		t.Errorf("token written: %q", contents())
+	}
+	if !contains(`] sent <redacted> header=<redacted> err="rejected <redacted>" n=3 size=2KiB`) {
+		t.Errorf("wrong redaction: %q", contents())
+	}
+	if err := RedactPattern("bad", "("); err == nil {
+		t.Error("invalid expression accepted")
+	}
+}
+
+func TestRedactStack(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if err := RedactPattern("func", `TestRedactStack`); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveProcessor("func")
+	if err := logging.stackTraceLevel.Set("ERROR"); err != nil {
+		t.Fatal(err)
+	}
+	defer logging.stackTraceLevel.Set("")
+	Error("failed")
+	if !contains("goroutine ") || !contains("<redacted>") || contains("TestRedactStack") {
+		t.Errorf("stack not redacted: %q", contents())
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if err := setRedactFields("default"); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveProcessor("redact_fields")
+	lg := With(F("api_token", "s3cr3t"), F("user", "ann"))
+	lg.Info("login")
+	lg.Info("again")
+	if strings.Contains(contents(), "s3cr3t") || strings.Count(contents(), "api_token=<redacted> user=ann") != 2 {
+		t.Errorf("wrong redaction: %q", contents())
+	}
+	if err := RedactFields("bad", "[x"); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
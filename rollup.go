@@ -0,0 +1,155 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rollupMaxKeys is the number of keys listed in a roll-up entry.
+const rollupMaxKeys = 10
+
+// Rollup aggregates high-cardinality events, such as failures per peer,
+// into one entry per interval instead of one per event:
+//
+//	failures := flog.NewRollup("peer failures", flog.WarningLog, time.Minute)
+//	defer failures.Close()
+//	...
+//	failures.Add(peer, err)
+//
+// logs, once a minute if there were any failures,
+//
+//	W0102 15:04:05.067890    1234 main.go:42] peer failures: 7 events for 2 keys rollup="peer failures" events=7 keys=2 10.0.0.1=5 10.0.0.1.last_error="connection refused" 10.0.0.2=2
+//
+// listing the keys with the most events, at most 10 of them, with their
+// count and last error. The entries are logged from where NewRollup was
+// called.
+type Rollup struct {
+	name     string
+	severity Severity
+	file     string
+	line     int
+	done     chan struct{}
+
+	mu     sync.Mutex
+	counts map[string]*rollupCount
+	events int64
+}
+
+// rollupCount is the count and last error of a key.
+type rollupCount struct {
+	n       int64
+	lastErr error
+}
+
+// NewRollup returns a Rollup logging its entries at severity s every
+// interval. A non-positive interval only logs them on Flush and Close.
+func NewRollup(name string, s Severity, interval time.Duration) *Rollup {
+	_, file, line := caller(-1)
+	r := &Rollup{
+		name:     name,
+		severity: s,
+		file:     file,
+		line:     line,
+		counts:   make(map[string]*rollupCount),
+	}
+	if interval > 0 {
+		r.done = make(chan struct{})
+		go r.flushEvery(interval, r.done)
+	}
+	return r
+}
+
+// Add counts an event for key, with err, if not nil, as the last error of
+// the key.
+// This method is safe to use concurrently.
+func (r *Rollup) Add(key string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.counts[key]
+	if c == nil {
+		c = &rollupCount{}
+		r.counts[key] = c
+	}
+	c.n++
+	if err != nil {
+		c.lastErr = err
+	}
+	r.events++
+}
+
+// Flush logs the events counted since the last entry, if any, and resets
+// the counts.
+func (r *Rollup) Flush() {
+	r.mu.Lock()
+	counts, events := r.counts, r.events
+	r.counts, r.events = make(map[string]*rollupCount), 0
+	r.mu.Unlock()
+	if events == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ni, nj := counts[keys[i]].n, counts[keys[j]].n; ni != nj {
+			return ni > nj
+		}
+		return keys[i] < keys[j]
+	})
+	fields := []Field{{"rollup", r.name}, {"events", events}, {"keys", len(keys)}}
+	if len(keys) > rollupMaxKeys {
+		keys = keys[:rollupMaxKeys]
+	}
+	for _, k := range keys {
+		c := counts[k]
+		fields = append(fields, Field{k, c.n})
+		if c.lastErr != nil {
+			fields = append(fields, Field{k + ".last_error", c.lastErr.Error()})
+		}
+	}
+	msg := r.name + ": " + strconv.FormatInt(events, 10) + " events for " + strconv.Itoa(len(counts)) + " keys"
+	logging.printWithFileLine(r.severity, r.file, r.line, fields, msg)
+}
+
+// Close stops the periodic entries and logs the events counted since the
+// last one.
+func (r *Rollup) Close() {
+	r.mu.Lock()
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+	r.mu.Unlock()
+	r.Flush()
+}
+
+// flushEvery flushes r every interval until done is closed.
+func (r *Rollup) flushEvery(interval time.Duration, done chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.Flush()
+		case <-done:
+			return
+		}
+	}
+}
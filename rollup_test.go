@@ -0,0 +1,47 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRollup(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	r := NewRollup("peer failures", WarningLog, 0)
+	for i := 0; i < 12; i++ {
+		r.Add("peer"+strconv.Itoa(i), nil)
+	}
+	r.Add("peer5", errors.New("timeout"))
+	r.Add("peer5", errors.New("connection refused"))
+	r.Add("peer7", nil)
+	r.Close()
+
+	want := `rollup_test.go:27] peer failures: 15 events for 12 keys rollup="peer failures" events=15 keys=12 peer5=3 peer5.last_error="connection refused" peer7=2 peer0=1`
+	if !contains(want) {
+		t.Fatalf("roll-up entry %q, want it to contain %q", contents(), want)
+	}
+	if strings.Contains(contents(), "peer9=") || strings.Count(contents(), "\n") != 1 {
+		t.Errorf("more than the top keys or entries logged: %q", contents())
+	}
+	r.Flush()
+	if strings.Count(contents(), "\n") != 1 {
+		t.Errorf("empty roll-up logged: %q", contents())
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+// RotatingFile is an output writing to a file that is rotated when it would
+// exceed a maximum size: path is renamed to path.1, path.1 to path.2 and so
+// on, keeping a maximum number of backups, and a new file is started.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	// mirrorCrash is set if the runtime's crash output is mirrored to the
+	// file, see MirrorCrashOutput.
+	mirrorCrash bool
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// OpenRotatingFile opens the file at path for appending, creating it if
+// needed, and returns a writer rotating it once it would exceed maxSize
+// bytes, keeping maxBackups of the previous files. A maxSize of zero or less
+// turns rotation off; with no backups, a rotated file is removed.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open opens the file at rf.path.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size = f, info.Size()
+	if rf.mirrorCrash {
+		setCrashOutput(f)
+	}
+	return nil
+}
+
+// Write is part of the io.Writer interface. Writes are never split across
+// files. If rotating fails, p is still written to the current file and the
+// rotation error is returned.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return 0, os.ErrClosed
+	}
+	var rerr error
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if rerr = rf.rotate(); rf.f == nil {
+			return 0, rerr
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	if err == nil {
+		err = rerr
+	}
+	return n, err
+}
+
+// rotate moves the current file to the first backup and starts a new one.
+// If the file can't be moved, it is reopened so that writing goes on to it.
+// rf.mu is held.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	rf.f = nil
+	var err error
+	if rf.maxBackups > 0 {
+		for i := rf.maxBackups - 1; i > 0; i-- {
+			os.Rename(rf.backup(i), rf.backup(i+1))
+		}
+		err = os.Rename(rf.path, rf.backup(1))
+	} else {
+		err = os.Remove(rf.path)
+	}
+	if oerr := rf.open(); err == nil {
+		err = oerr
+	}
+	return err
+}
+
+// backup returns the path of the i-th backup.
+func (rf *RotatingFile) backup(i int) string {
+	return rf.path + "." + strconv.Itoa(i)
+}
+
+// Sync commits the contents of the current file to stable storage.
+func (rf *RotatingFile) Sync() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return os.ErrClosed
+	}
+	return rf.f.Sync()
+}
+
+// Close closes the current file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return os.ErrClosed
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+// configFile is the file output set through Config.
+var configFile struct {
+	mu sync.Mutex
+	rf *RotatingFile
+}
+
+// setLogFile makes the file at path, rotated as described for Config, the
+// output, or restores stderr if path is empty and a file was the output.
+func setLogFile(path string, maxSizeMB, maxBackups int) error {
+	configFile.mu.Lock()
+	defer configFile.mu.Unlock()
+	old := configFile.rf
+	if old != nil && old.path == path && old.maxSize == int64(maxSizeMB)<<20 && old.maxBackups == maxBackups {
+		return nil
+	}
+	if path == "" {
+		if old != nil {
+			setCrashOutput(nil)
+			SetOutput(os.Stderr)
+			old.Close()
+			configFile.rf = nil
+		}
+		return nil
+	}
+	rf := &RotatingFile{path: path, maxSize: int64(maxSizeMB) << 20, maxBackups: maxBackups, mirrorCrash: true}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	SetOutput(rf)
+	if old != nil {
+		old.Close()
+	}
+	configFile.rf = rf
+	return nil
+}
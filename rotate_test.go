@@ -0,0 +1,122 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	rf, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeeeeeeeeeee\n", "ffff\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"app.log":   "ffff\n",
+		"app.log.1": "eeeeeeeeeeee\n",
+		"app.log.2": "cccc\ndddd\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more backups than configured: %v", err)
+	}
+}
+
+func TestRotatingFileRenameFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	// A non-empty directory in place of the backup makes the rename fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := OpenRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	if _, err := rf.Write([]byte("aaaaaaaa\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("bbbb\n")); err == nil {
+		t.Error("no error when the rotation failed")
+	}
+	if _, err := rf.Write([]byte("cccc\n")); err == nil {
+		t.Error("no error when the rotation failed again")
+	}
+	os.RemoveAll(path + ".1")
+	if _, err := rf.Write([]byte("dddd\n")); err != nil {
+		t.Errorf("write after the rotation recovered: %v", err)
+	}
+	for name, want := range map[string]string{
+		"app.log":   "dddd\n",
+		"app.log.1": "aaaaaaaa\nbbbb\ncccc\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestConfigLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	cfg := &Config{Verbosity: "0", LogFile: path, MaxSizeMB: 1}
+	if err := cfg.Set(); err != nil {
+		t.Fatal(err)
+	}
+	Info("to the file")
+	cfg.LogFile = ""
+	if err := cfg.Set(); err != nil {
+		t.Fatal(err)
+	}
+	if GetOutput() != os.Stderr {
+		t.Error("stderr not restored")
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil || !strings.HasSuffix(string(got), "] to the file\n") {
+		t.Errorf("file contents = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampler limits the number of entries logged per second from each call
+// site.
+type sampler struct {
+	// rate is the number of entries logged per second and call site, zero
+	// if entries are not sampled. It is read and written using sync/atomic.
+	rate int32
+
+	mu      sync.Mutex
+	windows map[sampleSite]*sampleWindow
+}
+
+// sampleSite identifies a call site.
+type sampleSite struct {
+	file string
+	line int
+}
+
+// sampleWindow counts the entries of a call site during a second.
+type sampleWindow struct {
+	start   time.Time
+	logged  int32
+	dropped int64
+	// rate is the share of the entries logged during the previous second,
+	// as kept/total, or empty if none were dropped.
+	rate string
+	// last is the entry the summary derives from, with the call site and
+	// severity of the entries dropped, nil if none was.
+	last *Entry
+	// timer writes the summary once the second is over, if entries were
+	// dropped.
+	timer *time.Timer
+	// run counts the windows of the site, so that a timer firing late is
+	// ignored.
+	run int64
+}
+
+// Fields describing the sampling of entries, so that downstream volume
+// estimates can be corrected.
+const (
+	sampledKey    = "sampled"
+	sampleRateKey = "sample_rate"
+)
+
+// SetSampleRate makes each call site log at most n entries per second, so
+// that hot loops can't flood the output. Further entries are dropped and
+// counted in Stats.Sampled, and once the second is over, or by Flush, a
+// "suppressed N messages" entry of the same severity is written. Critical and Fatal entries are never dropped. Zero, the
+// default, disables sampling.
+//
+// The summary carries sampled=true and sample_rate=kept/total fields giving
+// the share of the entries of the site logged during that second. Entries
+// logged from a site that had entries dropped during the previous second
+// carry the same fields with the share of that second, as an estimate.
+// This function is safe to use concurrently.
+func SetSampleRate(n int) {
+	ensureInit()
+	if n < 0 {
+		n = 0
+	}
+	s := &logging.sampler
+	s.mu.Lock()
+	summaries := s.summarizeAll()
+	s.windows = nil
+	atomic.StoreInt32(&s.rate, int32(n))
+	s.mu.Unlock()
+	for _, e := range summaries {
+		logging.emit(e)
+	}
+}
+
+// sample reports whether e is to be logged and, if entries of its call site
+// were dropped before it, returns an entry summarizing them, to be logged
+// first.
+func (s *sampler) sample(e *Entry) (bool, *Entry) {
+	if e.Severity >= CriticalLog {
+		return true, nil
+	}
+	site := sampleSite{e.File, e.Line}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rate := atomic.LoadInt32(&s.rate)
+	if rate <= 0 {
+		return true, nil
+	}
+	if s.windows == nil {
+		s.windows = make(map[sampleSite]*sampleWindow)
+	}
+	w := s.windows[site]
+	if w == nil {
+		w = &sampleWindow{start: e.Time}
+		s.windows[site] = w
+	}
+	var summary *Entry
+	if e.Time.Sub(w.start) >= time.Second || e.Time.Before(w.start) {
+		summary = w.restart(e.Time)
+	}
+	if w.logged >= rate {
+		w.dropped++
+		if w.last == nil {
+			w.last = &Entry{
+				Severity: e.Severity,
+				Mono:     e.Mono,
+				File:     e.File,
+				Line:     e.Line,
+				pc:       e.pc,
+				output:   e.output,
+			}
+			run := w.run
+			w.timer = time.AfterFunc(w.start.Add(time.Second).Sub(e.Time), func() { s.expire(site, run) })
+		}
+		return false, nil
+	}
+	w.logged++
+	if w.rate != "" {
+		e.setField(sampledKey, true)
+		e.setField(sampleRateKey, w.rate)
+	}
+	return true, summary
+}
+
+// expire writes the summary of the entries of site dropped during window
+// run once the second is over.
+func (s *sampler) expire(site sampleSite, run int64) {
+	s.mu.Lock()
+	var summary *Entry
+	if w := s.windows[site]; w != nil && w.run == run {
+		now := timeNow()
+		if now.Before(w.start) {
+			now = w.start
+		}
+		summary = w.restart(now)
+	}
+	s.mu.Unlock()
+	if summary != nil {
+		logging.emit(summary)
+	}
+}
+
+// flush writes the summaries of the entries dropped so far.
+func (s *sampler) flush() {
+	s.mu.Lock()
+	summaries := s.summarizeAll()
+	s.mu.Unlock()
+	for _, e := range summaries {
+		logging.emit(e)
+	}
+}
+
+// summarizeAll returns the summaries of the entries dropped so far from
+// all the sites, which are then forgotten.
+// s.mu is held.
+func (s *sampler) summarizeAll() []*Entry {
+	var summaries []*Entry
+	now := timeNow()
+	for _, w := range s.windows {
+		if summary, _ := w.summarize(now); summary != nil {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries
+}
+
+// restart starts a new window at now and returns the summary of the
+// entries dropped during w, if any.
+// s.mu is held.
+func (w *sampleWindow) restart(now time.Time) *Entry {
+	summary, share := w.summarize(now)
+	*w = sampleWindow{start: now, rate: share, run: w.run}
+	return summary
+}
+
+// summarize returns an entry summarizing the entries dropped during w, if
+// any, and their share as kept/total, and forgets them.
+// s.mu is held.
+func (w *sampleWindow) summarize(now time.Time) (*Entry, string) {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.run++
+	if w.dropped == 0 {
+		return nil, ""
+	}
+	l, dropped := w.last, w.dropped
+	total := int64(w.logged) + dropped
+	share := strconv.Itoa(int(w.logged)) + "/" + strconv.FormatInt(total, 10)
+	w.dropped, w.last = 0, nil
+	return &Entry{
+		Severity: l.Severity,
+		Time:     now,
+		Mono:     l.Mono,
+		File:     l.File,
+		Line:     l.Line,
+		Message:  "suppressed " + strconv.FormatInt(dropped, 10) + " messages",
+		Fields:   []Field{{sampledKey, true}, {sampleRateKey, share}},
+		pc:       l.pc,
+		output:   l.output,
+	}, share
+}
@@ -0,0 +1,104 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSampleRate(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSampleRate(2)
+	defer SetSampleRate(0)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	hot := func() {
+		for i := 0; i < 5; i++ {
+			Infof("hot %d", i)
+		}
+	}
+	before := atomic.LoadInt64(&Stats.Sampled)
+	hot()
+	Critical("never sampled")
+	if got := strings.Count(contents(), "] hot "); got != 2 {
+		t.Errorf("%d entries logged within a second, want 2:\n%s", got, contents())
+	}
+	if got := atomic.LoadInt64(&Stats.Sampled) - before; got != 3 {
+		t.Errorf("Stats.Sampled grew by %d, want 3", got)
+	}
+	if !contains("never sampled") {
+		t.Error("Critical entry was sampled")
+	}
+
+	now = now.Add(time.Second)
+	hot()
+	if !contains("] suppressed 3 messages sampled=true sample_rate=2/5\n") {
+		t.Errorf("no summary of the dropped entries:\n%s", contents())
+	}
+	if i, j := strings.Index(contents(), "suppressed 3"), strings.LastIndex(contents(), "hot 0"); i < 0 || i > j {
+		t.Error("summary not logged before the next entry")
+	}
+	if !contains("] hot 0 sampled=true sample_rate=2/5\n") {
+		t.Errorf("entry of a sampled site without sampling fields:\n%s", contents())
+	}
+}
+
+func TestSampleSummaryTimer(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSampleRate(1)
+	defer SetSampleRate(0)
+	// The burst stops: the summary is written once the second is over.
+	for i := 0; i < 3; i++ {
+		Info("burst")
+	}
+	want := "] suppressed 2 messages sampled=true sample_rate=1/3\n"
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		logging.mu.Lock()
+		ok := contains(want)
+		logging.mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no summary after the burst:\n%s", contents())
+		}
+	}
+}
+
+func TestSampleFlush(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSampleRate(1)
+	defer SetSampleRate(0)
+	SetDedupWindow(time.Hour)
+	defer SetDedupWindow(0)
+	for i := 0; i < 3; i++ {
+		Infof("burst %d", i)
+	}
+	for i := 0; i < 3; i++ {
+		Warning("repeated")
+	}
+	Flush()
+	if !contains("] suppressed 2 messages sampled=true sample_rate=1/3\n") || !contains("] last message repeated 2 times repeated=2\n") {
+		t.Errorf("summaries not written by Flush:\n%s", contents())
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// minFreeSpace is the free disk space below which SelfTest reports a file
+// output, unless the file rotates at a larger size.
+const minFreeSpace = 64 << 20
+
+// SelfTestReport is the result of SelfTest.
+type SelfTestReport struct {
+	// OK is set if no problem was found.
+	OK bool `json:"ok"`
+	// Config lists the problems of the configuration itself, such as
+	// plugins that are enabled but were never registered.
+	Config []string `json:"config,omitempty"`
+	// Outputs lists the output set by SetOutput, the outputs set by
+	// SetSeverityOutput, then the outputs added with AddOutput.
+	Outputs []OutputReport `json:"outputs"`
+}
+
+// OutputReport is the part of a SelfTestReport about one output.
+type OutputReport struct {
+	// Kind is stderr, file, syslog or, for other writers, writer.
+	Kind string `json:"kind"`
+	// Path is the path of file outputs.
+	Path string `json:"path,omitempty"`
+	// ReadBack is set if the probe entry was read back from the file.
+	ReadBack bool `json:"read_back"`
+	// FreeBytes is the free disk space for file outputs, -1 if the platform
+	// can't tell.
+	FreeBytes int64 `json:"free_bytes,omitempty"`
+	// Problems lists what is wrong with the output.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Err returns an error summing up the problems of r, or nil if it is OK.
+func (r *SelfTestReport) Err() error {
+	if r.OK {
+		return nil
+	}
+	problems := append([]string(nil), r.Config...)
+	for _, o := range r.Outputs {
+		for _, p := range o.Problems {
+			name := o.Kind
+			if o.Path != "" {
+				name += " " + o.Path
+			}
+			problems = append(problems, name+": "+p)
+		}
+	}
+	return errors.New("flog: self-test failed: " + strings.Join(problems, "; "))
+}
+
+// SelfTest checks the logging configuration, so that a program can fail
+// fast at startup rather than run without its logs:
+//
+//	if err := flog.SelfTest().Err(); err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		os.Exit(1)
+//	}
+//
+// It reports settings that never took effect, such as level names and
+// plugins that were not registered, then writes a probe entry, an Info
+// entry from the caller with a random token, to the output, the outputs
+// set by SetSeverityOutput and the outputs added with AddOutput, bypassing
+// the pipeline and the minimum severity, and flushes them, which connects
+// network outputs. The probe is read back from file outputs, whose
+// directory must be writable for rotation and whose disk must have room to
+// spare.
+func SelfTest() *SelfTestReport {
+	r := &SelfTestReport{}
+	r.Config = configProblems()
+
+	var token [8]byte
+	rand.Read(token[:])
+	_, file, line := caller(-1)
+	probe := newEntry(InfoLog, 0, file, line, "flog self-test probe "+hex.EncodeToString(token[:]))
+	buf := logging.getBuffer()
+	defer logging.putBuffer(buf)
+	logging.encode(buf, probe)
+	data := buf.Bytes()
+
+	logging.mu.Lock()
+	if logging.async.active() {
+		logging.async.drain()
+	}
+	outs := []interface{}{logging.out}
+	errs := []error{writeProbe(logging.out, logging.loadFieldMap().apply(data))}
+	for _, w := range logging.severityOut.writers() {
+		outs = append(outs, w)
+		errs = append(errs, writeProbe(w.(io.Writer), logging.loadFieldMap().apply(data)))
+	}
+	for _, o := range logging.outputs {
+		outs = append(outs, o.w)
+		errs = append(errs, writeProbe(o.w, data))
+	}
+	logging.mu.Unlock()
+	flushOutputs(outs, 0)
+
+	for i, w := range outs {
+		o := OutputReport{}
+		o.Kind, o.Path = describeOutput(w)
+		if errs[i] != nil {
+			o.Problems = append(o.Problems, "writing the probe: "+errs[i].Error())
+		} else if o.Path != "" {
+			o.checkFile(w, probe.Message)
+		}
+		r.Outputs = append(r.Outputs, o)
+	}
+
+	r.OK = len(r.Config) == 0
+	for _, o := range r.Outputs {
+		r.OK = r.OK && len(o.Problems) == 0
+	}
+	return r
+}
+
+// configProblems returns the problems of the configuration.
+func configProblems() []string {
+	var problems []string
+	levelNames.mu.RLock()
+	v, vmodule := levelNames.pendingV, levelNames.pendingVmodule
+	levelNames.mu.RUnlock()
+	if v != "" {
+		problems = append(problems, fmt.Sprintf("verbosity %q names an unregistered level", v))
+	}
+	if vmodule != "" {
+		problems = append(problems, fmt.Sprintf("vmodule %q names an unregistered level", vmodule))
+	}
+	plugins.mu.Lock()
+	pending := plugins.pending
+	plugins.mu.Unlock()
+	if pending != "" {
+		problems = append(problems, fmt.Sprintf("plugins %q are not all registered", pending))
+	}
+	return problems
+}
+
+// writeProbe writes the probe entry data to w.
+// logging.mu is held.
+func writeProbe(w io.Writer, data []byte) error {
+	n, err := writeSeverity(w, InfoLog, data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// describeOutput returns the kind of output w is and its path, if it is a
+// file.
+func describeOutput(w interface{}) (kind, path string) {
+	switch w := w.(type) {
+	case *bufferedOutput:
+		w.mu.Lock()
+		out := w.out
+		w.mu.Unlock()
+		return describeOutput(out)
+	case *RotatingFile:
+		return "file", w.path
+	case *SyslogWriter:
+		return "syslog", ""
+	case *os.File:
+		if info, err := w.Stat(); err == nil && info.Mode().IsRegular() {
+			return "file", w.Name()
+		}
+		if w == os.Stderr {
+			return "stderr", ""
+		}
+	}
+	return "writer", ""
+}
+
+// checkFile reads the probe holding msg back from the file output w at
+// o.Path and checks its directory and disk space.
+func (o *OutputReport) checkFile(w interface{}, msg string) {
+	tail, err := readTail(o.Path, 64<<10)
+	if err != nil {
+		o.Problems = append(o.Problems, "reading the probe back: "+err.Error())
+	} else if o.ReadBack = bytes.Contains(tail, []byte(msg)); !o.ReadBack {
+		o.Problems = append(o.Problems, "the probe is missing from the file")
+	}
+
+	need := int64(minFreeSpace)
+	if b, ok := w.(*bufferedOutput); ok {
+		b.mu.Lock()
+		w = b.out
+		b.mu.Unlock()
+	}
+	if rf, ok := w.(*RotatingFile); ok && rf.maxSize > 0 {
+		if f, err := ioutil.TempFile(filepath.Dir(o.Path), ".flog-selftest"); err != nil {
+			o.Problems = append(o.Problems, "the directory doesn't allow rotation: "+err.Error())
+		} else {
+			f.Close()
+			os.Remove(f.Name())
+		}
+		if rf.maxSize > need {
+			need = rf.maxSize
+		}
+	}
+
+	o.FreeBytes = -1
+	if free, ok := diskFree(o.Path); ok {
+		o.FreeBytes = free
+		if free < need {
+			o.Problems = append(o.Problems, fmt.Sprintf("only %v of free disk space", ByteSize(free)))
+		}
+	}
+}
+
+// readTail returns at most the last n bytes of the file at path.
+func readTail(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if off := info.Size() - n; off > 0 {
+		if _, err := f.Seek(off, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.ReadAll(f)
+}
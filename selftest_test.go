@@ -0,0 +1,74 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// brokenWriter fails every write.
+type brokenWriter struct{}
+
+func (brokenWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestSelfTest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.log")
+	rf, err := OpenRotatingFile(path, 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	defer SetOutput(GetOutput())
+	SetOutput(rf)
+	SetMinSeverity(ErrorLog)
+	defer SetMinSeverity(DebugLog)
+
+	r := SelfTest()
+	if !r.OK || r.Err() != nil || len(r.Outputs) != 1 {
+		t.Fatalf("report %+v, error %v", r, r.Err())
+	}
+	if o := r.Outputs[0]; o.Kind != "file" || o.Path != path || !o.ReadBack {
+		t.Errorf("output report %+v", o)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), " selftest_test.go:") || !strings.Contains(string(data), "] flog self-test probe ") {
+		t.Errorf("file holds %q, want the probe", data)
+	}
+
+	AddOutput(brokenWriter{}, InfoLog)
+	defer RemoveOutput(brokenWriter{})
+	r = SelfTest()
+	if r.OK || len(r.Outputs) != 2 || len(r.Outputs[1].Problems) != 1 {
+		t.Fatalf("report %+v with a broken output", r)
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "writer: writing the probe: connection refused") {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync/atomic"
+)
+
+// seqKey is the key of the field holding the sequence number of an entry.
+const seqKey = "seq"
+
+// SetSequenceNumbers turns sequence numbers on or off. When on, entries
+// carry a seq field numbering them in the order they are written, across
+// all severities, the outputs added with AddOutput and the outputs of
+// Loggers, so that the streams of different outputs can be merged back
+// into the exact order of emission. Timestamps, taken when the logging
+// function is called, can't give that order when goroutines race.
+// Entries are then encoded while holding the lock they are written under,
+// which reduces the throughput of parallel logging, and hooks see them
+// before they are numbered.
+// This function is safe to use concurrently.
+func SetSequenceNumbers(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.sequenced, v)
+}
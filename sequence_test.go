@@ -0,0 +1,76 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSequenceNumbers(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var errs bytes.Buffer
+	AddOutput(&errs, ErrorLog)
+	defer RemoveOutput(&errs)
+	SetSequenceNumbers(true)
+	defer SetSequenceNumbers(false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if j%5 == 0 {
+					Error("error")
+				} else {
+					Info("info")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seqs := func(out string) []uint64 {
+		var res []uint64
+		for _, m := range regexp.MustCompile(` seq=(\d+)\n`).FindAllStringSubmatch(out, -1) {
+			n, _ := strconv.ParseUint(m[1], 10, 64)
+			res = append(res, n)
+		}
+		return res
+	}
+	all := seqs(contents())
+	if len(all) != 400 {
+		t.Fatalf("%d numbered entries, want 400", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i] != all[i-1]+1 {
+			t.Fatalf("sequence numbers out of order: %d after %d", all[i], all[i-1])
+		}
+	}
+	errSeqs := seqs(errs.String())
+	if len(errSeqs) != 80 {
+		t.Fatalf("%d numbered errors, want 80", len(errSeqs))
+	}
+	for i := 1; i < len(errSeqs); i++ {
+		if errSeqs[i] <= errSeqs[i-1] {
+			t.Fatalf("error output out of order: %d after %d", errSeqs[i], errSeqs[i-1])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"io"
+)
+
+// severityOutputs holds the outputs set by SetSeverityOutput, by severity,
+// nil for the severities written to the output set by SetOutput.
+type severityOutputs struct {
+	w [numSeverity]io.Writer
+	// lat holds the latency trackers of the outputs in w, shared by the
+	// severities with the same output.
+	lat [numSeverity]*latencyTracker
+}
+
+// SetSeverityOutput makes the entries of severity s go to w rather than to
+// the output set by SetOutput, or back to that output if w is nil. This
+// splits the streams as twelve-factor deployments expect, for example:
+//
+//	flog.SetSeverityOutput(flog.DebugLog, os.Stdout)
+//	flog.SetSeverityOutput(flog.InfoLog, os.Stdout)
+//
+// leaves warnings and above on stderr. The outputs added with AddOutput
+// still receive the entries, and SetBuffer doesn't buffer w. Entries of
+// Loggers with an output of their own are not affected.
+// This function is safe to use concurrently.
+func SetSeverityOutput(s Severity, w io.Writer) {
+	ensureInit()
+	if s < 0 || s >= numSeverity {
+		return
+	}
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	so := &logging.severityOut
+	so.w[s], so.lat[s] = w, nil
+	if w == nil {
+		return
+	}
+	lat := new(latencyTracker)
+	for i, other := range so.w {
+		if Severity(i) != s && other == w {
+			lat = so.lat[i]
+			break
+		}
+	}
+	so.lat[s] = lat
+}
+
+// pick returns the output of the entries of severity s and its latency
+// tracker, given out, the output set by SetOutput, and its tracker.
+func (so *severityOutputs) pick(out io.Writer, lat *latencyTracker, s Severity) (io.Writer, *latencyTracker) {
+	if s >= 0 && s < numSeverity && so.w[s] != nil {
+		return so.w[s], so.lat[s]
+	}
+	return out, lat
+}
+
+// writers returns the outputs set by SetSeverityOutput, without duplicates.
+func (so *severityOutputs) writers() []interface{} {
+	var ws []interface{}
+	for _, w := range so.w {
+		if w == nil {
+			continue
+		}
+		dup := false
+		for _, seen := range ws {
+			dup = dup || seen == w
+		}
+		if !dup {
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
@@ -0,0 +1,48 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetSeverityOutput(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var stdout bytes.Buffer
+	SetSeverityOutput(DebugLog, &stdout)
+	SetSeverityOutput(InfoLog, &stdout)
+	defer SetSeverityOutput(DebugLog, nil)
+	defer SetSeverityOutput(InfoLog, nil)
+	var all bytes.Buffer
+	AddOutput(&all, DebugLog)
+	defer RemoveOutput(&all)
+
+	Debug("debug")
+	Info("info")
+	Warning("warning")
+	Error("error")
+	if got := stdout.String(); !strings.Contains(got, "] debug\n") || !strings.Contains(got, "] info\n") || strings.Contains(got, "warning") {
+		t.Errorf("stdout got %q", got)
+	}
+	if got := contents(); strings.Contains(got, "info") || !strings.Contains(got, "] warning\n") || !strings.Contains(got, "] error\n") {
+		t.Errorf("stderr got %q", got)
+	}
+	if n := strings.Count(all.String(), "\n"); n != 4 {
+		t.Errorf("added output got %d entries, want 4", n)
+	}
+}
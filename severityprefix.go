@@ -0,0 +1,61 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// SetSeverityPrefix turns severity prefixes on or off. When on, every line
+// of the entries written in the text format, including the continuation
+// lines of multi-line messages and stack traces, starts with a token
+// naming the severity of the entry, such as |E| for errors, followed by a
+// space. The token doesn't depend on the header, which SetHeaderFormatter
+// and SetTimeFormat may change, so shell pipelines can filter severities
+// reliably, as with grep '^|E|'. The JSON format is not affected.
+// This function is safe to use concurrently.
+func SetSeverityPrefix(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.severityPrefix, v)
+}
+
+// prefixLines inserts prefix at the start of every line written to buf past
+// start.
+func prefixLines(buf *buffer, start int, prefix []byte) {
+	text := append([]byte(nil), buf.Bytes()[start:]...)
+	buf.Truncate(start)
+	for len(text) > 0 {
+		line := text
+		if i := bytes.IndexByte(text, '\n'); i >= 0 {
+			line = text[:i+1]
+		}
+		buf.Write(prefix)
+		buf.Write(line)
+		text = text[len(line):]
+	}
+}
+
+// severityToken returns the token naming s written by SetSeverityPrefix.
+func severityToken(s Severity) [4]byte {
+	if s > FatalLog {
+		s = InfoLog // for safety.
+	}
+	return [4]byte{'|', severityChar[s], '|', ' '}
+}
@@ -0,0 +1,57 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeverityPrefix(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSeverityPrefix(true)
+	defer SetSeverityPrefix(false)
+	defer SetHeaderFormatter(nil)
+	SetHeaderFormatter(func(buf *bytes.Buffer, s Severity, file string, line int, _ time.Time) {
+		buf.WriteString(s.Name() + ": ")
+	})
+	Info("first\nsecond")
+	Error("oops")
+	want := "|I| INFO: first\n|I| second\n|E| ERROR: oops\n"
+	if got := contents(); got != want {
+		t.Errorf("output %q, want %q", got, want)
+	}
+}
+
+func TestSeverityPrefixParse(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSeverityPrefix(true)
+	defer SetSeverityPrefix(false)
+	Warning("test")
+	if !strings.HasPrefix(contents(), "|W| W") {
+		t.Fatalf("output %q lacks the prefix", contents())
+	}
+	e, err := ParseEntry([]byte(strings.TrimSuffix(contents(), "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Severity != WarningLog || e.Message != "test" {
+		t.Errorf("parsed entry %+v", e)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"syscall"
+)
+
+// WriteSignalSafe writes p, a fully formatted log line, directly to the
+// standard error file descriptor. Unlike every other function of this package
+// it takes no locks, does not allocate and bypasses the pipeline, the output
+// writer and the stats, so it can't deadlock or fail halfway through when the
+// process is in a bad state: in crash paths, in code that runs while flog's
+// own lock may be held, such as an output writer, or in a handler invoked
+// from a signal.
+//
+// Format the line ahead of time, while the process is healthy:
+//
+//	crashMsg := []byte("F crash: unrecoverable state, aborting\n")
+//	...
+//	flog.WriteSignalSafe(crashMsg)
+func WriteSignalSafe(p []byte) {
+	for len(p) > 0 {
+		n, err := syscall.Write(syscall.Stderr, p)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			return
+		}
+		p = p[n:]
+	}
+}
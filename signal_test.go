@@ -0,0 +1,58 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// signalSafeLines returns the lines TestWriteSignalSafeHelper writes: a short
+// one and one longer than a pipe buffer, which the reader must drain before
+// the write can complete.
+func signalSafeLines() [][]byte {
+	long := bytes.Repeat([]byte("0123456789abcdef"), 1<<14)
+	long[len(long)-1] = '\n'
+	return [][]byte{[]byte("F crash: unrecoverable state, aborting\n"), long}
+}
+
+// TestWriteSignalSafeHelper is not a real test. It writes the lines of
+// signalSafeLines with WriteSignalSafe and exits when run by
+// TestWriteSignalSafe.
+func TestWriteSignalSafeHelper(t *testing.T) {
+	if os.Getenv("FLOG_TEST_CHILD") != "4" {
+		return
+	}
+	for _, p := range signalSafeLines() {
+		WriteSignalSafe(p)
+	}
+	os.Exit(0)
+}
+
+func TestWriteSignalSafe(t *testing.T) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=TestWriteSignalSafeHelper")
+	cmd.Env = append(os.Environ(), "FLOG_TEST_CHILD=4")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("helper failed: %v: %s", err, stderr.Bytes())
+	}
+	want := bytes.Join(signalSafeLines(), nil)
+	if !bytes.Equal(stderr.Bytes(), want) {
+		t.Errorf("got %d bytes on stderr, want %d: %.100q", stderr.Len(), len(want), stderr.Bytes())
+	}
+}
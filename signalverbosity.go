@@ -0,0 +1,68 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"os"
+	"os/signal"
+)
+
+// EnableSignalVerbosity makes the process raise the verbosity by one when it
+// receives the signal up and lower it by one, down to zero, when it receives
+// down, so operators can turn on debug logging on a live process with kill:
+//
+//	flog.EnableSignalVerbosity(syscall.SIGUSR1, syscall.SIGUSR2)
+//
+// Every change is logged as an Info entry. The returned function stops the
+// handling of the signals.
+func EnableSignalVerbosity(up, down os.Signal) (stop func()) {
+	ensureInit()
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, up, down)
+	go func() {
+		for {
+			select {
+			case sig := <-c:
+				delta := Level(1)
+				if sig == down {
+					delta = -1
+				}
+				v := addVerbosity(delta)
+				Infof("verbosity set to %d by signal %v", v, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// addVerbosity adds delta to the verbosity, without going below zero, and
+// returns the new verbosity.
+func addVerbosity(delta Level) Level {
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	v := logging.verbosity.get() + delta
+	if v < 0 {
+		v = 0
+	}
+	logging.setVState(v, logging.vmodule.filter, false)
+	clearPending(&levelNames.pendingV)
+	return v
+}
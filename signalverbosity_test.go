@@ -0,0 +1,48 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalVerbosity(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.verbosity.Set("0")
+	stop := EnableSignalVerbosity(syscall.SIGUSR1, syscall.SIGUSR2)
+	defer stop()
+
+	wait := func(want Level) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); GetVerbosity() != want; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("verbosity = %d, want %d", GetVerbosity(), want)
+			}
+		}
+	}
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	wait(1)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	wait(0)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	time.Sleep(10 * time.Millisecond)
+	wait(0)
+}
@@ -0,0 +1,148 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// maxSiteValues bounds the distinct values counted per field and call site,
+// to bound the memory used by SetSiteStats.
+const maxSiteValues = 1000
+
+// siteStatsTop is the number of call sites included in GetStats.
+const siteStatsTop = 20
+
+// SiteStat holds the size and cardinality of the entries written from a call
+// site, see SetSiteStats.
+type SiteStat struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Entries  int64  `json:"entries"`
+	Bytes    int64  `json:"bytes"`
+	AvgBytes int64  `json:"avg_bytes"`
+	// Cardinality is the number of distinct values of each field, counted up
+	// to 1000.
+	Cardinality map[string]int `json:"cardinality,omitempty"`
+}
+
+// siteStats holds the call sites observed by SetSiteStats.
+var siteStats struct {
+	// on is nonzero when entries are observed. It is read and written using
+	// sync/atomic.
+	on int32
+
+	mu    sync.Mutex
+	sites map[vSiteKey]*siteState
+}
+
+// siteState is the state of a call site observed by SetSiteStats.
+type siteState struct {
+	entries, bytes int64
+	values         map[string]map[uint64]struct{} // hashes of the values per field
+}
+
+// SetSiteStats turns on or off the observation of the entries written. For
+// each call site, the number of entries, their size in bytes and the number
+// of distinct values of each of their fields are kept, as returned by
+// SiteStats, which finds the log statements dominating the storage bill.
+// GetStats, and so StatsHandler, include the top call sites. Turning it on
+// again starts over.
+// This function is safe to use concurrently.
+func SetSiteStats(on bool) {
+	ensureInit()
+	siteStats.mu.Lock()
+	defer siteStats.mu.Unlock()
+	siteStats.sites = nil
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&siteStats.on, v)
+}
+
+// SiteStats returns the call sites observed since SetSiteStats was called,
+// the most bytes written first.
+func SiteStats() []SiteStat {
+	siteStats.mu.Lock()
+	sites := make([]SiteStat, 0, len(siteStats.sites))
+	for key, s := range siteStats.sites {
+		stat := SiteStat{File: key.file, Line: key.line, Entries: s.entries, Bytes: s.bytes, AvgBytes: s.bytes / s.entries}
+		if len(s.values) > 0 {
+			stat.Cardinality = make(map[string]int, len(s.values))
+			for k, values := range s.values {
+				stat.Cardinality[k] = len(values)
+			}
+		}
+		sites = append(sites, stat)
+	}
+	siteStats.mu.Unlock()
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Bytes != sites[j].Bytes {
+			return sites[i].Bytes > sites[j].Bytes
+		}
+		if sites[i].File != sites[j].File {
+			return sites[i].File < sites[j].File
+		}
+		return sites[i].Line < sites[j].Line
+	})
+	return sites
+}
+
+// topSiteStats returns the first siteStatsTop call sites of SiteStats.
+func topSiteStats() []SiteStat {
+	sites := SiteStats()
+	if len(sites) > siteStatsTop {
+		sites = sites[:siteStatsTop]
+	}
+	return sites
+}
+
+// observeSite records e, written as n bytes, for its call site if
+// SetSiteStats is on.
+func observeSite(e *Entry, n int) {
+	if atomic.LoadInt32(&siteStats.on) == 0 {
+		return
+	}
+	siteStats.mu.Lock()
+	defer siteStats.mu.Unlock()
+	if siteStats.sites == nil {
+		siteStats.sites = make(map[vSiteKey]*siteState)
+	}
+	key := vSiteKey{e.File, e.Line}
+	s := siteStats.sites[key]
+	if s == nil {
+		s = &siteState{values: make(map[string]map[uint64]struct{})}
+		siteStats.sites[key] = s
+	}
+	s.entries++
+	s.bytes += int64(n)
+	for _, f := range e.Fields {
+		values := s.values[f.Key]
+		if values == nil {
+			values = make(map[uint64]struct{})
+			s.values[f.Key] = values
+		}
+		if len(values) < maxSiteValues {
+			h := fnv.New64a()
+			fmt.Fprint(h, f.Value)
+			values[h.Sum64()] = struct{}{}
+		}
+	}
+}
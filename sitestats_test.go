@@ -0,0 +1,53 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestSiteStats(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSiteStats(true)
+	defer SetSiteStats(false)
+	for i := 0; i < 4; i++ {
+		logging.printWithFileLine(InfoLog, "big.go", 1, []Field{{"user", i % 2}, {"op", "get"}}, "a rather long message")
+	}
+	logging.printWithFileLine(InfoLog, "small.go", 2, nil, "short")
+	sites := SiteStats()
+	if len(sites) != 2 {
+		t.Fatalf("got %d sites, want 2: %+v", len(sites), sites)
+	}
+	big := sites[0]
+	if big.File != "big.go" || big.Line != 1 || big.Entries != 4 || big.AvgBytes != big.Bytes/4 {
+		t.Errorf("wrong top site: %+v", big)
+	}
+	if big.Cardinality["user"] != 2 || big.Cardinality["op"] != 1 {
+		t.Errorf("wrong cardinality: %v", big.Cardinality)
+	}
+	if sites[1].File != "small.go" || sites[1].Cardinality != nil {
+		t.Errorf("wrong second site: %+v", sites[1])
+	}
+	if got := GetStats().Sites; len(got) != 2 {
+		t.Errorf("stats have %d sites, want 2", len(got))
+	}
+
+	SetSiteStats(false)
+	logging.printWithFileLine(InfoLog, "big.go", 1, nil, "not observed")
+	if len(SiteStats()) != 0 {
+		t.Error("sites kept after turning stats off")
+	}
+}
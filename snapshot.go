@@ -0,0 +1,96 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultRedact holds the name patterns whose values LogSnapshot redacts when
+// Snapshot.Redact is nil.
+var DefaultRedact = []string{"*PASSWORD*", "*PASSWD*", "*SECRET*", "*TOKEN*", "*KEY*", "*CREDENTIAL*"}
+
+// redacted replaces the values of redacted variables and flags.
+const redacted = "<redacted>"
+
+// Snapshot selects the configuration recorded by LogSnapshot.
+// Names are matched against patterns with filepath.Match, ignoring case.
+type Snapshot struct {
+	// Env holds the patterns of the environment variables to record.
+	// If nil, the FLOG_* variables are recorded.
+	Env []string
+	// Flags, if not nil, is the flag set whose flags are recorded.
+	Flags *flag.FlagSet
+	// Redact holds the patterns of the variables and flags whose values are
+	// replaced with "<redacted>". If nil, DefaultRedact is used.
+	Redact []string
+}
+
+// LogSnapshot logs the environment variables and flags selected by s as the
+// fields of a single "startup snapshot" Info entry, with an env. or flag.
+// prefix, so the configuration a process actually started with can be found
+// in its log. Call it once flags have been parsed.
+func LogSnapshot(s Snapshot) {
+	env := s.Env
+	if env == nil {
+		env = []string{"FLOG_*"}
+	}
+	deny := s.Redact
+	if deny == nil {
+		deny = DefaultRedact
+	}
+
+	var fields []Field
+	for _, kv := range os.Environ() {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 || !matchName(env, kv[:i]) {
+			continue
+		}
+		fields = append(fields, snapshotField("env."+kv[:i], kv[i+1:], matchName(deny, kv[:i])))
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	if s.Flags != nil {
+		s.Flags.VisitAll(func(f *flag.Flag) {
+			fields = append(fields, snapshotField("flag."+f.Name, f.Value.String(), matchName(deny, f.Name)))
+		})
+	}
+
+	pc, file, line := caller(-1)
+	e := newEntry(InfoLog, pc, file, line, "startup snapshot")
+	e.Fields = fields
+	logging.output(e)
+}
+
+func snapshotField(key, value string, redact bool) Field {
+	if redact && value != "" {
+		value = redacted
+	}
+	return Field{key, value}
+}
+
+// matchName reports whether name matches any of patterns, ignoring case.
+func matchName(patterns []string, name string) bool {
+	name = strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(strings.ToUpper(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+func TestLogSnapshot(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	os.Setenv("FLOG_SNAPSHOT_TEST", "on")
+	os.Setenv("SNAPSHOT_TEST_TOKEN", "hunter2")
+	os.Setenv("SNAPSHOT_TEST_OTHER", "x")
+	defer os.Unsetenv("FLOG_SNAPSHOT_TEST")
+	defer os.Unsetenv("SNAPSHOT_TEST_TOKEN")
+	defer os.Unsetenv("SNAPSHOT_TEST_OTHER")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db_password", "", "")
+	fs.Int("port", 0, "")
+	fs.Parse([]string{"-db_password=hunter2", "-port=8080"})
+
+	LogSnapshot(Snapshot{Env: []string{"flog_snapshot_*", "SNAPSHOT_TEST_TOKEN"}, Flags: fs})
+	if !contains("snapshot_test.go:") {
+		t.Errorf("wrong call site: %q", contents())
+	}
+	for _, want := range []string{
+		"] startup snapshot",
+		" env.FLOG_SNAPSHOT_TEST=on",
+		" env.SNAPSHOT_TEST_TOKEN=<redacted>",
+		" flag.db_password=<redacted>",
+		" flag.port=8080",
+	} {
+		if !contains(want) {
+			t.Errorf("missing %q in %q", want, contents())
+		}
+	}
+	for _, unwanted := range []string{"hunter2", "SNAPSHOT_TEST_OTHER"} {
+		if contains(unwanted) {
+			t.Errorf("unexpected %q in %q", unwanted, contents())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+// stackTraceLevel represents the setting of the -stack_trace_level flag.
+// It is protected by logging.mu.
+type stackTraceLevel struct {
+	on bool
+	s  Severity
+}
+
+// match reports whether entries of severity s get a stack trace. Fatal
+// entries are left out, as the stacks of all goroutines follow them anyway.
+// logging.mu is held.
+func (t *stackTraceLevel) match(s Severity) bool {
+	return t.on && s >= t.s && s != FatalLog
+}
+
+// String is part of the flag.Value interface.
+func (t *stackTraceLevel) String() string {
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	if !t.on {
+		return ""
+	}
+	return t.s.Name()
+}
+
+// Get is part of the (Go 1.2) flag.Getter interface. It always returns nil for this flag type since the
+// struct is not exported
+func (t *stackTraceLevel) Get() interface{} {
+	return nil
+}
+
+// Set is part of the flag.Value interface.
+// The value is a severity name, such as CRITICAL, or number; an empty value
+// turns stack traces off.
+func (t *stackTraceLevel) Set(value string) error {
+	ensureInit()
+	var s Severity
+	if value != "" {
+		if err := s.Set(value); err != nil {
+			return err
+		}
+	}
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	t.on, t.s = value != "", s
+	logging.updateStackTriggers()
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackTraceLevel(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer logging.stackTraceLevel.Set("")
+	if err := logging.stackTraceLevel.Set("error"); err != nil {
+		t.Fatal(err)
+	}
+	Warning("no trace")
+	Error("trace")
+	Error("trace again")
+	if n := strings.Count(contents(), "[running]"); n != 2 {
+		t.Fatalf("got %d stack traces, want 2; log is %s", n, contents())
+	}
+	if i := strings.Index(contents(), "[running]"); i < strings.Index(contents(), "] trace\n") {
+		t.Errorf("stack trace before the entry: %s", contents())
+	}
+	if got := logging.stackTraceLevel.String(); got != "ERROR" {
+		t.Errorf("String() is %q, want ERROR", got)
+	}
+
+	logging.newBuffers()
+	logging.stackTraceLevel.Set("")
+	Error("no trace")
+	if contains("[running]") {
+		t.Errorf("stack trace after turning them off: %s", contents())
+	}
+	if err := logging.stackTraceLevel.Set("LOUD"); err == nil {
+		t.Error("accepted an unknown severity")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// savedState is the content of the state file, see SetStateFile.
+type savedState struct {
+	Verbosity Level  `json:"verbosity"`
+	Vmodule   string `json:"vmodule"`
+}
+
+// SetStateFile makes the verbosity and vmodule settings survive restarts:
+// the settings saved in the file at path, if it exists, are restored, then
+// every change of the settings, whether through VerbosityHandler,
+// EnableSignalVerbosity, flags or Config, is saved to the file. This keeps
+// the debug settings of an operator while a process goes through a
+// crash-restart loop. Remove the file and restart to go back to the
+// configured settings; an empty path stops saving them.
+// Invalid files and failures to save the settings are reported as described
+// for ConfigErrorPrefix.
+// This function is safe to use concurrently.
+func SetStateFile(path string) error {
+	ensureInit()
+	if path != "" {
+		if err := restoreState(path); err != nil && !os.IsNotExist(err) {
+			return checkConfig("StateFile", path, err)
+		}
+	}
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.statePath = path
+	if path != "" {
+		logging.saveState(logging.verbosity.get(), logging.vmodule.filter)
+	}
+	return nil
+}
+
+// restoreState sets the verbosity and vmodule saved in the file at path.
+func restoreState(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var s savedState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if err := logging.vmodule.Set(s.Vmodule); err != nil {
+		return err
+	}
+	return logging.verbosity.Set(strconv.Itoa(int(s.Verbosity)))
+}
+
+// saveState writes verbosity and filter to the state file, if any. The file
+// is replaced as a whole, so a crash never leaves it half written.
+// l.mu is held.
+func (l *loggingT) saveState(verbosity Level, filter []modulePat) {
+	if l.statePath == "" {
+		return
+	}
+	data, _ := json.Marshal(savedState{verbosity, formatModulePats(filter)})
+	tmp := l.statePath + ".tmp"
+	err := ioutil.WriteFile(tmp, append(data, '\n'), 0644)
+	if err == nil {
+		err = os.Rename(tmp, l.statePath)
+	}
+	// Report the error without logging, since l.mu is held.
+	checkConfig("StateFile", l.statePath, err)
+}
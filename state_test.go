@@ -0,0 +1,81 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer logging.verbosity.Set("0")
+	defer logging.vmodule.Set("")
+	defer SetStateFile("")
+	path := filepath.Join(dir, "flog.state")
+
+	if err := SetStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	logging.vmodule.Set("gfs*=3")
+	addVerbosity(2)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != `{"verbosity":2,"vmodule":"gfs*=3"}` {
+		t.Errorf("saved state is %s", got)
+	}
+
+	// Pretend the process restarted.
+	SetStateFile("")
+	logging.verbosity.Set("0")
+	logging.vmodule.Set("")
+	if err := SetStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if v := logging.verbosity.get(); v != 2 || logging.vmodule.String() != "gfs*=3" {
+		t.Errorf("restored verbosity %d and vmodule %q", v, logging.vmodule.String())
+	}
+}
+
+func TestStateFileError(t *testing.T) {
+	var out bytes.Buffer
+	configErrorOutput = &out
+	defer func() { configErrorOutput = os.Stderr }()
+	dir, err := ioutil.TempDir("", "flog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "flog.state")
+	if err := ioutil.WriteFile(path, []byte(`{"vmodule":"gfs*"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStateFile(path); err == nil {
+		t.Error("invalid state file accepted")
+	}
+	if !strings.Contains(out.String(), `"setting":"StateFile"`) {
+		t.Errorf("state file error not reported: %q", out.String())
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriberBuffer is the number of entries a subscriber may lag behind
+// before entries are dropped for it.
+const subscriberBuffer = 256
+
+// subscriber is a channel entries are mirrored to, see Subscribe.
+type subscriber struct {
+	filter func(Entry) bool
+
+	mu     sync.Mutex
+	ch     chan Entry
+	closed bool
+}
+
+// Subscribe returns a channel receiving a copy of every entry written that
+// filter, if not nil, returns true for, such as for admin pages or anomaly
+// detectors watching the live stream in-process. Entries are never waited
+// for: when the subscriber falls more than a few hundred entries behind,
+// further entries are dropped for it and counted in Stats.SubscriberDropped.
+// cancel stops the mirroring and closes the channel. filter is called in the
+// goroutine logging, and must not log through flog.
+// This function is safe to use concurrently.
+func Subscribe(filter func(Entry) bool) (entries <-chan Entry, cancel func()) {
+	s := &subscriber{filter: filter, ch: make(chan Entry, subscriberBuffer)}
+	remove := AddHook(s.send)
+	var once sync.Once
+	return s.ch, func() {
+		once.Do(func() {
+			remove()
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.closed = true
+			close(s.ch)
+		})
+	}
+}
+
+// send mirrors e to the channel unless it is full.
+func (s *subscriber) send(e Entry) {
+	if s.filter != nil && !s.filter(e) {
+		return
+	}
+	e.Fields = append([]Field(nil), e.Fields...)
+	e.pc, e.output, e.hooks = 0, nil, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- e:
+	default:
+		atomic.AddInt64(&Stats.SubscriberDropped, 1)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	entries, cancel := Subscribe(func(e Entry) bool { return e.Severity >= WarningLog })
+	Info("ignored")
+	With(F("user", "ann")).Error("denied")
+	e := <-entries
+	if e.Severity != ErrorLog || e.Message != "denied" || e.File != "subscribe_test.go" {
+		t.Errorf("subscriber got %+v", e)
+	}
+	if len(e.Fields) != 1 || e.Fields[0] != (Field{"user", "ann"}) {
+		t.Errorf("subscriber got fields %v", e.Fields)
+	}
+	cancel()
+	cancel()
+	Error("after cancel")
+	if e, ok := <-entries; ok {
+		t.Errorf("subscriber got %+v after cancel", e)
+	}
+}
+
+func TestSubscribeSlowConsumer(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	entries, cancel := Subscribe(nil)
+	defer cancel()
+	before := atomic.LoadInt64(&Stats.SubscriberDropped)
+	for i := 0; i < subscriberBuffer+10; i++ {
+		Info("busy")
+	}
+	if dropped := atomic.LoadInt64(&Stats.SubscriberDropped) - before; dropped != 10 {
+		t.Errorf("dropped %d entries, want 10", dropped)
+	}
+	if len(entries) != subscriberBuffer {
+		t.Errorf("%d entries buffered, want %d", len(entries), subscriberBuffer)
+	}
+	if !contains("busy") {
+		t.Error("logging blocked by slow subscriber")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"sync/atomic"
+)
+
+// SetSummaryThreshold makes strings, byte slices, maps, slices and arrays
+// passed as arguments to the logging functions be summarized as
+//
+//	<type len=N hash=xxxxxxxx>
+//
+// instead of being rendered, when they would take more than n bytes. This
+// prevents accidental dumps of whole payloads while the hash, an FNV-1a hash
+// of the content, still allows correlating them. A value of zero or less,
+// the default, turns summarizing off.
+// This function is safe to use concurrently.
+func SetSummaryThreshold(n int) {
+	ensureInit()
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&logging.summaryThreshold, int64(n))
+}
+
+// summary is a summarized argument. It prints the same whatever the verb.
+type summary string
+
+// Format is part of the fmt.Formatter interface.
+func (s summary) Format(f fmt.State, verb rune) {
+	io.WriteString(f, string(s))
+}
+
+// sprint is fmt.Sprint on args, summarized if needed.
+func sprint(args []interface{}) string {
+	return fmt.Sprint(summarizeArgs(args)...)
+}
+
+// sprintln is fmt.Sprintln on args, summarized if needed.
+func sprintln(args []interface{}) string {
+	return fmt.Sprintln(summarizeArgs(args)...)
+}
+
+// sprintf is fmt.Sprintf on args, summarized if needed.
+func sprintf(format string, args []interface{}) string {
+	return fmt.Sprintf(format, summarizeArgs(args)...)
+}
+
+// summarizeArgs returns args with the arguments over the summary threshold
+// replaced by their summary. args is returned as is if there are none.
+func summarizeArgs(args []interface{}) []interface{} {
+	threshold := int(atomic.LoadInt64(&logging.summaryThreshold))
+	if threshold <= 0 {
+		return args
+	}
+	var summarized []interface{}
+	for i, arg := range args {
+		s, ok := summarize(arg, threshold)
+		if !ok {
+			continue
+		}
+		if summarized == nil {
+			summarized = append([]interface{}(nil), args...)
+		}
+		summarized[i] = s
+	}
+	if summarized == nil {
+		return args
+	}
+	return summarized
+}
+
+// summarize returns the summary of arg if it takes more than threshold bytes
+// to render.
+func summarize(arg interface{}, threshold int) (summary, bool) {
+	var content []byte
+	var n int
+	switch v := arg.(type) {
+	case string:
+		if len(v) <= threshold {
+			return "", false
+		}
+		content, n = []byte(v), len(v)
+	case []byte:
+		if len(v) <= threshold {
+			return "", false
+		}
+		content, n = v, len(v)
+	default:
+		rv := reflect.ValueOf(arg)
+		switch rv.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array:
+		default:
+			return "", false
+		}
+		if content = []byte(fmt.Sprint(arg)); len(content) <= threshold {
+			return "", false
+		}
+		n = rv.Len()
+	}
+	h := fnv.New32a()
+	h.Write(content)
+	return summary(fmt.Sprintf("<%T len=%d hash=%08x>", arg, n, h.Sum32())), true
+}
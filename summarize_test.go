@@ -0,0 +1,47 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummaryThreshold(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetSummaryThreshold(16)
+	defer SetSummaryThreshold(0)
+
+	long := strings.Repeat("x", 17)
+	Infof("%s %d %x", long, 7, []byte(long))
+	want := "] <string len=17 hash=e9b2d8c7> 7 <[]uint8 len=17 hash=e9b2d8c7>\n"
+	if !contains(want) {
+		t.Errorf("missing %q in %q", want, contents())
+	}
+
+	logging.newBuffers()
+	Info(strings.Repeat("y", 16), map[int]int{1: 1, 2: 2, 3: 3, 4: 4})
+	if !contains("] yyyyyyyyyyyyyyyy<map[int]int len=4 hash=") {
+		t.Errorf("wrong summary of short string and big map: %q", contents())
+	}
+
+	SetSummaryThreshold(0)
+	logging.newBuffers()
+	Info(long)
+	if !contains("] " + long + "\n") {
+		t.Errorf("argument summarized with summarizing off: %q", contents())
+	}
+}
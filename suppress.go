@@ -0,0 +1,169 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// suppressions holds the call sites silenced with Suppress.
+type suppressions struct {
+	// active is the number of suppressions, which may have expired. It may be
+	// read safely using sync.LoadInt32, but is only modified under mu.
+	active int32
+
+	mu    sync.Mutex
+	until map[suppressKey]time.Time
+}
+
+// suppressKey identifies a suppressed call site, by file and line or by
+// event code.
+type suppressKey struct {
+	file  string
+	line  int
+	event string
+}
+
+// eventKey is the key of the field holding the event code of an entry.
+const eventKey = "event"
+
+// parseSuppressKey parses a call site as given to Suppress.
+func parseSuppressKey(site string) (suppressKey, error) {
+	if site == "" {
+		return suppressKey{}, errors.New("empty call site")
+	}
+	if i := strings.LastIndexByte(site, ':'); i > 0 && strings.HasSuffix(site[:i], ".go") {
+		line, err := strconv.Atoi(site[i+1:])
+		if err != nil || line <= 0 {
+			return suppressKey{}, errors.New("bad line number in call site " + strconv.Quote(site))
+		}
+		return suppressKey{file: site[:i], line: line}, nil
+	}
+	return suppressKey{event: site}, nil
+}
+
+// String returns the call site as given to Suppress.
+func (k suppressKey) String() string {
+	if k.event != "" {
+		return k.event
+	}
+	return k.file + ":" + strconv.Itoa(k.line)
+}
+
+// Suppress drops the entries logged from site for the duration d, as a
+// surgical alternative to lowering the verbosity, for instance to silence a
+// known noisy line during an incident. The site is either file.go:N, using
+// the base name of the file, or an event code, matched against the value of
+// the event field of entries. Suppressing a site again replaces its expiry.
+// Suppressed entries are counted in Stats.Suppressed. Critical and Fatal
+// entries are never suppressed.
+// This function is safe to use concurrently.
+func Suppress(site string, d time.Duration) error {
+	if d <= 0 {
+		return errors.New("non-positive suppression duration")
+	}
+	k, err := parseSuppressKey(site)
+	if err != nil {
+		return err
+	}
+	s := &logging.suppressions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.until == nil {
+		s.until = make(map[suppressKey]time.Time)
+	}
+	s.until[k] = timeNow().Add(d)
+	atomic.StoreInt32(&s.active, int32(len(s.until)))
+	return nil
+}
+
+// Unsuppress lifts the suppression of site before it expires and reports
+// whether there was one.
+func Unsuppress(site string) bool {
+	k, err := parseSuppressKey(site)
+	if err != nil {
+		return false
+	}
+	s := &logging.suppressions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.until[k]
+	s.remove(k)
+	return ok
+}
+
+// Suppression describes a suppressed call site.
+type Suppression struct {
+	Site  string
+	Until time.Time
+}
+
+// Suppressions returns the call sites currently suppressed, sorted by site.
+func Suppressions() []Suppression {
+	s := &logging.suppressions
+	now := timeNow()
+	s.mu.Lock()
+	var res []Suppression
+	for k, until := range s.until {
+		if until.After(now) {
+			res = append(res, Suppression{k.String(), until})
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Site < res[j].Site })
+	return res
+}
+
+// remove forgets about the suppression of k.
+// s.mu is held.
+func (s *suppressions) remove(k suppressKey) {
+	delete(s.until, k)
+	atomic.StoreInt32(&s.active, int32(len(s.until)))
+}
+
+// match reports whether e was logged from a suppressed call site. Critical
+// and Fatal entries never match, so that the exit path runs.
+func (s *suppressions) match(e *Entry) bool {
+	if atomic.LoadInt32(&s.active) == 0 || e.Severity >= CriticalLog {
+		return false
+	}
+	keys := [2]suppressKey{{file: e.File, line: e.Line}}
+	n := 1
+	if i := e.field(eventKey); i >= 0 {
+		if event, ok := e.Fields[i].Value.(string); ok && event != "" {
+			keys[1] = suppressKey{event: event}
+			n++
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range keys[:n] {
+		until, ok := s.until[k]
+		if !ok {
+			continue
+		}
+		if e.Time.Before(until) {
+			return true
+		}
+		s.remove(k)
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSuppress(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	_, _, line, _ := runtime.Caller(0)
+	site := "suppress_test.go:" + strconv.Itoa(line+5)
+	if err := Suppress(site, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	Info("noisy")
+	if contents() != "" {
+		t.Errorf("suppressed call site logged: %q", contents())
+	}
+	if got := Suppressions(); len(got) != 1 || got[0].Site != site || !got[0].Until.Equal(now.Add(time.Minute)) {
+		t.Errorf("Suppressions() = %v", got)
+	}
+
+	suppressed := atomic.LoadInt64(&Stats.Suppressed)
+	if err := Suppress("cache-miss", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	logging.printWithFileLine(InfoLog, "x.go", 1, []Field{{"event", "cache-miss"}}, "miss")
+	if contents() != "" {
+		t.Errorf("suppressed event logged: %q", contents())
+	}
+	if n := atomic.LoadInt64(&Stats.Suppressed) - suppressed; n != 1 {
+		t.Errorf("Stats.Suppressed increased by %d, want 1", n)
+	}
+	if !Unsuppress("cache-miss") || Unsuppress("cache-miss") {
+		t.Error("Unsuppress didn't report the suppression it lifted")
+	}
+
+	now = now.Add(time.Minute)
+	logging.printWithFileLine(InfoLog, "suppress_test.go", line+5, nil, "expired")
+	if !contains("expired") {
+		t.Errorf("expired suppression still applied: %q", contents())
+	}
+	if got := Suppressions(); len(got) != 0 {
+		t.Errorf("Suppressions() = %v after expiry", got)
+	}
+
+	for _, bad := range []string{"", "a.go:x", "a.go:0"} {
+		if Suppress(bad, time.Minute) == nil {
+			t.Errorf("Suppress(%q) succeeded", bad)
+		}
+	}
+	if Suppress("a.go:1", 0) == nil {
+		t.Error("Suppress with a zero duration succeeded")
+	}
+}
+
+func TestSuppressKeepsFatal(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var exits []int
+	SetExitFunc(func(code int) { exits = append(exits, code) })
+	defer SetExitFunc(nil)
+	if err := Suppress("x.go:1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	defer Unsuppress("x.go:1")
+	logging.printWithFileLine(CriticalLog, "x.go", 1, nil, "critical")
+	logging.printWithFileLine(FatalLog, "x.go", 1, nil, "fatal")
+	if !contains("] critical\n") || !contains("] fatal\n") {
+		t.Errorf("Critical or Fatal entry suppressed: %q", contents())
+	}
+	if len(exits) == 0 {
+		t.Error("suppressed Fatal didn't exit")
+	}
+}
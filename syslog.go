@@ -0,0 +1,125 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SyslogWriter is an output writing entries to syslog, with priorities
+// mapped from their severities: Debug, Info, Warning, Error, Critical and
+// Fatal entries become LOG_DEBUG, LOG_INFO, LOG_WARNING, LOG_ERR, LOG_CRIT
+// and LOG_ALERT messages of the LOG_USER facility. Bytes written with Write
+// rather than through flog are sent at LOG_INFO.
+type SyslogWriter struct {
+	mu   sync.Mutex
+	conn syslogConn
+}
+
+// NewSyslogWriter connects to the local syslog daemon and returns a writer
+// sending messages tagged with tag, or with the program name if tag is
+// empty. On platforms without log/syslog, it sends them to localhost:514
+// over UDP.
+func NewSyslogWriter(tag string) (*SyslogWriter, error) {
+	return DialSyslog("", "", tag)
+}
+
+// DialSyslog is like NewSyslogWriter but connects to the syslog daemon at
+// raddr on the named network, such as "udp" or "unixgram".
+func DialSyslog(network, raddr, tag string) (*SyslogWriter, error) {
+	conn, err := dialSyslog(network, raddr, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogWriter{conn: conn}, nil
+}
+
+// Write sends p as a LOG_INFO message.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteSeverity(InfoLog, p)
+}
+
+// WriteSeverity sends p, an entry of severity s, as a message with the
+// matching priority.
+func (w *SyslogWriter) WriteSeverity(s Severity, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.conn.write(s, strings.TrimSuffix(string(p), "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.close()
+}
+
+// configSyslog is the syslog output set through Config or FLOG_OUTPUT.
+var configSyslog struct {
+	mu sync.Mutex
+	w  *SyslogWriter
+}
+
+// setSyslog makes a SyslogWriter connected to the local daemon the output
+// if on, or restores stderr if not and syslog was the output.
+func setSyslog(on bool) error {
+	configSyslog.mu.Lock()
+	defer configSyslog.mu.Unlock()
+	old := configSyslog.w
+	if on == (old != nil) {
+		return nil
+	}
+	if !on {
+		SetOutput(os.Stderr)
+		old.Close()
+		configSyslog.w = nil
+		return nil
+	}
+	w, err := NewSyslogWriter("")
+	if err != nil {
+		return err
+	}
+	SetOutput(w)
+	configSyslog.w = w
+	return nil
+}
+
+// setOutputName sets the output named by FLOG_OUTPUT.
+func setOutputName(name string) error {
+	switch name {
+	case "", "stderr":
+		if err := setJournal(false); err != nil {
+			return err
+		}
+		return setSyslog(false)
+	case "syslog":
+		if err := setJournal(false); err != nil {
+			return err
+		}
+		return setSyslog(true)
+	case "journald":
+		if err := setSyslog(false); err != nil {
+			return err
+		}
+		return setJournal(true)
+	}
+	return errors.New("unknown output " + name + ", want stderr, syslog or journald")
+}
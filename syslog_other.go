@@ -0,0 +1,67 @@
+//go:build windows || plan9
+// +build windows plan9
+
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// syslogPriorities maps severities to syslog priorities of the LOG_USER
+// facility.
+var syslogPriorities = [numSeverity]int{
+	DebugLog:    1<<3 | 7,
+	InfoLog:     1<<3 | 6,
+	WarningLog:  1<<3 | 4,
+	ErrorLog:    1<<3 | 3,
+	CriticalLog: 1<<3 | 2,
+	FatalLog:    1<<3 | 1,
+}
+
+// syslogConn sends messages in the BSD syslog format over a socket, as
+// log/syslog is not available.
+type syslogConn struct {
+	conn     net.Conn
+	hostname string
+	tag      string
+}
+
+func dialSyslog(network, raddr, tag string) (syslogConn, error) {
+	if network == "" {
+		network, raddr = "udp", "localhost:514"
+	}
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	conn, err := net.Dial(network, raddr)
+	return syslogConn{conn, hostname, tag}, err
+}
+
+func (c syslogConn) write(s Severity, msg string) error {
+	_, err := fmt.Fprintf(c.conn, "<%d>%s %s %s[%d]: %s\n",
+		syslogPriorities[s], time.Now().Format(time.Stamp), c.hostname, c.tag, os.Getpid(), msg)
+	return err
+}
+
+func (c syslogConn) close() error {
+	return c.conn.Close()
+}
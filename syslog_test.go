@@ -0,0 +1,67 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogWriter(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+	w, err := DialSyslog("udp", pc.LocalAddr().String(), "flogtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	SetOutput(w)
+	defer SetOutput(os.Stderr)
+
+	Warning("disk almost full")
+	Critical("disk full")
+	buf := make([]byte, 1024)
+	for _, want := range []string{"<12>", "<10>"} {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := string(buf[:n])
+		if !strings.HasPrefix(msg, want) || !strings.Contains(msg, "flogtest[") {
+			t.Errorf("message %q, want priority %s and tag flogtest", msg, want)
+		}
+	}
+}
+
+func TestConfigSyslogWithLogFile(t *testing.T) {
+	var out bytes.Buffer
+	configErrorOutput = &out
+	defer func() { configErrorOutput = os.Stderr }()
+	c := Config{Syslog: true, LogFile: "app.log"}
+	if err := c.Set(); err == nil {
+		t.Fatal("Set succeeded with both Syslog and LogFile")
+	}
+	if !strings.Contains(out.String(), `"setting":"Config.Syslog"`) {
+		t.Errorf("config error %q, want one for Config.Syslog", out.String())
+	}
+}
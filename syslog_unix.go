@@ -0,0 +1,52 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"log/syslog"
+)
+
+// syslogConn sends messages through log/syslog.
+type syslogConn struct {
+	w *syslog.Writer
+}
+
+func dialSyslog(network, raddr, tag string) (syslogConn, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_USER|syslog.LOG_INFO, tag)
+	return syslogConn{w}, err
+}
+
+func (c syslogConn) write(s Severity, msg string) error {
+	switch s {
+	case DebugLog:
+		return c.w.Debug(msg)
+	case InfoLog:
+		return c.w.Info(msg)
+	case WarningLog:
+		return c.w.Warning(msg)
+	case ErrorLog:
+		return c.w.Err(msg)
+	case CriticalLog:
+		return c.w.Crit(msg)
+	}
+	return c.w.Alert(msg)
+}
+
+func (c syslogConn) close() error {
+	return c.w.Close()
+}
@@ -0,0 +1,122 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// SetGoroutineIDs turns goroutine IDs on or off. When on, entries logged by
+// a goroutine without a tag of its own, see SetTag, are tagged with the ID
+// of the goroutine, such as g42, so the interleaved entries of concurrent
+// workers can be told apart. Getting the ID costs a runtime.Stack call per
+// entry.
+// This function is safe to use concurrently.
+func SetGoroutineIDs(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.goroutineIDs, v)
+}
+
+// SetTag tags the entries the calling goroutine logs with tag, which the
+// text format writes in the header right before file:line and the JSON
+// format as the tag member. It returns a function restoring the previous
+// tag of the goroutine, meant to be deferred:
+//
+//	defer flog.SetTag("worker-" + strconv.Itoa(i))()
+//
+// The tag should not contain spaces. An empty tag removes the tag of the
+// goroutine. Tags are not inherited by the goroutines a goroutine starts.
+// This function is safe to use concurrently.
+func SetTag(tag string) (restore func()) {
+	ensureInit()
+	id := goroutineID()
+	prev, had := logging.tags.load(id)
+	logging.tags.store(id, tag)
+	return func() {
+		if had {
+			logging.tags.store(id, prev)
+		} else {
+			logging.tags.store(id, "")
+		}
+	}
+}
+
+// tagSet holds the tags set with SetTag, by goroutine ID.
+type tagSet struct {
+	mu sync.RWMutex
+	m  map[uint64]string
+	// n is the number of tags in m. It is read and written using
+	// sync/atomic, so entries skip the goroutine ID lookup when it is zero.
+	n int32
+}
+
+// load returns the tag of goroutine id.
+func (t *tagSet) load(id uint64) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	tag, ok := t.m[id]
+	return tag, ok
+}
+
+// store sets the tag of goroutine id, removing it when tag is empty.
+func (t *tagSet) store(id uint64, tag string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tag == "" {
+		delete(t.m, id)
+	} else {
+		if t.m == nil {
+			t.m = make(map[uint64]string)
+		}
+		t.m[id] = tag
+	}
+	atomic.StoreInt32(&t.n, int32(len(t.m)))
+}
+
+// stampTag sets the tag of e, logged by the calling goroutine, if it has
+// one or goroutine IDs are on.
+func (l *loggingT) stampTag(e *Entry) {
+	ids := atomic.LoadInt32(&l.goroutineIDs) != 0
+	if !ids && atomic.LoadInt32(&l.tags.n) == 0 {
+		return
+	}
+	id := goroutineID()
+	if tag, ok := l.tags.load(id); ok {
+		e.Tag = tag
+	} else if ids {
+		e.Tag = "g" + strconv.FormatUint(id, 10)
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from the
+// first line of its stack trace, "goroutine 42 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
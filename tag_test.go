@@ -0,0 +1,90 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetTag(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var wg sync.WaitGroup
+	for _, tag := range []string{"worker-1", "worker-2"} {
+		wg.Add(1)
+		go func(tag string) {
+			defer wg.Done()
+			defer SetTag(tag)()
+			Info("from " + tag)
+		}(tag)
+	}
+	wg.Wait()
+	Info("untagged")
+	for _, tag := range []string{"worker-1", "worker-2"} {
+		if !contains(" "+tag+" tag_test.go:") || !contains("] from "+tag) {
+			t.Errorf("no entry tagged %s in %q", tag, contents())
+		}
+	}
+	if !regexp.MustCompile(`\d tag_test.go:\d+\] untagged`).MatchString(contents()) {
+		t.Errorf("untagged entry has a tag: %q", contents())
+	}
+	if n := len(logging.tags.m); n != 0 {
+		t.Errorf("%d tags left after restoring", n)
+	}
+}
+
+func TestGoroutineIDs(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetGoroutineIDs(true)
+	defer SetGoroutineIDs(false)
+	Info("test")
+	restore := SetTag("main")
+	Info("tagged")
+	restore()
+	want := regexp.MustCompile(` g[1-9]\d* tag_test.go:\d+\] test\n.* main tag_test.go:\d+\] tagged\n`)
+	if !want.MatchString(contents()) {
+		t.Fatalf("entries %q don't match %s", contents(), want)
+	}
+	e, err := ParseEntry([]byte(strings.SplitN(contents(), "\n", 2)[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(e.Tag, "g") || e.File != "tag_test.go" || e.Message != "test" {
+		t.Errorf("parsed entry %+v", e)
+	}
+}
+
+func TestTagJSON(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetFormat(FormatJSON)
+	defer SetFormat(FormatText)
+	defer SetTag("worker")()
+	With(F("tag", "field")).Info("test")
+	if !contains(`,"tag":"worker","file":`) || !contains(`"field.tag":"field"`) {
+		t.Fatalf("entry %q lacks the tag", contents())
+	}
+	e, err := ParseEntry([]byte(strings.TrimSuffix(contents(), "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Tag != "worker" {
+		t.Errorf("parsed tag %q, want worker", e.Tag)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// TimeFormat is the format of the timestamp in the header of the text
+// format. *TimeFormat implements flag.Value; the -log_time_format flag is of
+// type TimeFormat.
+type TimeFormat int32
+
+const (
+	// TimeGlog writes timestamps as mmdd hh:mm:ss.uuuuuu, the default.
+	TimeGlog TimeFormat = iota
+	// TimeRFC3339 writes timestamps as RFC 3339 with microseconds and the
+	// zone offset, such as 2006-01-02T15:04:05.000000-07:00, which includes
+	// the year and the zone the glog format leaves out.
+	TimeRFC3339
+	numTimeFormat
+)
+
+var timeFormatName = [numTimeFormat]string{
+	TimeGlog:    "glog",
+	TimeRFC3339: "rfc3339",
+}
+
+// rfc3339Micro is the layout of TimeRFC3339.
+const rfc3339Micro = "2006-01-02T15:04:05.000000Z07:00"
+
+// SetTimeFormat sets the format of the timestamp in the header of the text
+// format.
+// This function is safe to use concurrently.
+func SetTimeFormat(f TimeFormat) {
+	ensureInit()
+	logging.timeFormat.set(f)
+}
+
+// SetUTC makes entries carry their time in UTC rather than in the local
+// time zone, in both the text and JSON formats, so logs from different
+// regions can be correlated without guessing their zones.
+// This function is safe to use concurrently.
+func SetUTC(on bool) {
+	ensureInit()
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&logging.utc, v)
+}
+
+// get returns the value of the TimeFormat.
+func (f *TimeFormat) get() TimeFormat {
+	return TimeFormat(atomic.LoadInt32((*int32)(f)))
+}
+
+// set sets the value of the TimeFormat.
+func (f *TimeFormat) set(val TimeFormat) {
+	atomic.StoreInt32((*int32)(f), int32(val))
+}
+
+// String is part of the flag.Value interface.
+func (f *TimeFormat) String() string {
+	if v := f.get(); v >= 0 && v < numTimeFormat {
+		return timeFormatName[v]
+	}
+	return "TimeFormat(" + strconv.Itoa(int(*f)) + ")"
+}
+
+// Get is part of the flag.Value interface.
+func (f *TimeFormat) Get() interface{} {
+	return f.get()
+}
+
+// Set is part of the flag.Value interface. The value is "glog" or
+// "rfc3339"; an empty value selects glog.
+func (f *TimeFormat) Set(value string) error {
+	ensureInit()
+	if value == "" {
+		f.set(TimeGlog)
+		return nil
+	}
+	for i, name := range timeFormatName {
+		if strings.EqualFold(value, name) {
+			f.set(TimeFormat(i))
+			return nil
+		}
+	}
+	return errors.New("unknown time format: expect glog or rfc3339")
+}
@@ -0,0 +1,62 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeFormatRFC3339(t *testing.T) {
+	SetTimeFormat(TimeRFC3339)
+	defer SetTimeFormat(TimeGlog)
+	SetUTC(true)
+	defer SetUTC(false)
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	defer func(previous func() time.Time) { timeNow = previous }(timeNow)
+	timeNow = func() time.Time {
+		return time.Date(2006, 1, 2, 15, 4, 5, 123456789, time.FixedZone("MST", -7*3600))
+	}
+	Info("test")
+	want := fmt.Sprintf("I2006-01-02T22:04:05.123456Z %7d timeformat_test.go:", getPid())
+	if got := contents(); !strings.HasPrefix(got, want) {
+		t.Fatalf("header is %q, want prefix %q", got, want)
+	}
+	e, err := ParseEntry([]byte(strings.TrimSuffix(contents(), "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Message != "test" || e.File != "timeformat_test.go" || !e.Time.Equal(timeNow().Truncate(time.Microsecond)) {
+		t.Errorf("parsed entry %+v", e)
+	}
+}
+
+func TestTimeFormatSet(t *testing.T) {
+	var f TimeFormat
+	for _, s := range []string{"rfc3339", "RFC3339", "glog", ""} {
+		if err := f.Set(s); err != nil {
+			t.Errorf("Set(%q): %v", s, err)
+		}
+	}
+	if f.String() != "glog" {
+		t.Errorf("String() is %q, want glog", f.String())
+	}
+	if err := f.Set("iso"); err == nil {
+		t.Error("Set(iso) succeeded")
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// The keys of the fields holding the trace and span IDs of an entry.
+const (
+	traceIDKey = "trace_id"
+	spanIDKey  = "span_id"
+)
+
+// TraceExtractor returns the IDs of the trace and span in ctx, empty if ctx
+// carries none.
+type TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+// traceExtractor holds the TraceExtractor set by SetTraceExtractor.
+var traceExtractor atomic.Value
+
+// SetTraceExtractor sets the function extracting the trace and span IDs of
+// the contexts passed to InfoCtx and the other Ctx functions, which add them
+// to their entries as trace_id and span_id fields. flog doesn't depend on a
+// tracing library; for OpenTelemetry, use:
+//
+//	flog.SetTraceExtractor(func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	})
+//
+// A nil f removes the extractor.
+// This function is safe to use concurrently.
+func SetTraceExtractor(f TraceExtractor) {
+	ensureInit()
+	traceExtractor.Store(f)
+}
+
+// ctxLogger returns the logger of ctx, as returned by FromContext, with the
+// trace and span IDs of ctx, its cause ID as set with CauseContext, and its
+// status as described for SetContextStatus, as fields. It returns nil if an
+// entry of severity s is dropped, for being below the minimum severity or
+// over the line budget of ctx, see LimitContext. Critical and Fatal entries
+// are never over the budget.
+func ctxLogger(ctx context.Context, s Severity) *Logger {
+	if logging.belowMinSeverity(s) {
+		return nil
+	}
+	lg := FromContext(ctx)
+	if s < CriticalLog && !withinBudget(ctx, lg) {
+		return nil
+	}
+	var fields []Field
+	if f, _ := traceExtractor.Load().(TraceExtractor); f != nil {
+		traceID, spanID := f(ctx)
+		if traceID != "" {
+			fields = append(fields, Field{traceIDKey, traceID})
+			if spanID != "" {
+				fields = append(fields, Field{spanIDKey, spanID})
+			}
+		}
+	}
+	if id := CauseFromContext(ctx); id != "" {
+		fields = append(fields, Field{causeIDKey, id})
+	}
+	if f, ok := ctxStatus(ctx); ok {
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return lg
+	}
+	return lg.With(fields...)
+}
+
+// DebugCtx logs to the DEBUG log through the logger of ctx, as returned by
+// FromContext, adding the trace and span IDs of ctx as described for
+// SetTraceExtractor.
+// Arguments are handled in the manner of fmt.Print.
+func DebugCtx(ctx context.Context, args ...interface{}) {
+	if lg := ctxLogger(ctx, DebugLog); lg != nil {
+		lg.print(DebugLog, sprint(args))
+	}
+}
+
+// DebugCtxf is DebugCtx with arguments handled in the manner of fmt.Printf.
+func DebugCtxf(ctx context.Context, format string, args ...interface{}) {
+	if lg := ctxLogger(ctx, DebugLog); lg != nil {
+		lg.print(DebugLog, sprintf(format, args))
+	}
+}
+
+// InfoCtx logs to the INFO log like DebugCtx.
+// Arguments are handled in the manner of fmt.Print.
+func InfoCtx(ctx context.Context, args ...interface{}) {
+	if lg := ctxLogger(ctx, InfoLog); lg != nil {
+		lg.print(InfoLog, sprint(args))
+	}
+}
+
+// InfoCtxf is InfoCtx with arguments handled in the manner of fmt.Printf.
+func InfoCtxf(ctx context.Context, format string, args ...interface{}) {
+	if lg := ctxLogger(ctx, InfoLog); lg != nil {
+		lg.print(InfoLog, sprintf(format, args))
+	}
+}
+
+// WarningCtx logs to the WARNING log like DebugCtx.
+// Arguments are handled in the manner of fmt.Print.
+func WarningCtx(ctx context.Context, args ...interface{}) {
+	if lg := ctxLogger(ctx, WarningLog); lg != nil {
+		lg.print(WarningLog, sprint(args))
+	}
+}
+
+// WarningCtxf is WarningCtx with arguments handled in the manner of
+// fmt.Printf.
+func WarningCtxf(ctx context.Context, format string, args ...interface{}) {
+	if lg := ctxLogger(ctx, WarningLog); lg != nil {
+		lg.print(WarningLog, sprintf(format, args))
+	}
+}
+
+// ErrorCtx logs to the ERROR log like DebugCtx.
+// Arguments are handled in the manner of fmt.Print.
+func ErrorCtx(ctx context.Context, args ...interface{}) {
+	if lg := ctxLogger(ctx, ErrorLog); lg != nil {
+		lg.print(ErrorLog, sprint(args))
+	}
+}
+
+// ErrorCtxf is ErrorCtx with arguments handled in the manner of fmt.Printf.
+func ErrorCtxf(ctx context.Context, format string, args ...interface{}) {
+	if lg := ctxLogger(ctx, ErrorLog); lg != nil {
+		lg.print(ErrorLog, sprintf(format, args))
+	}
+}
+
+// CriticalCtx logs to the CRITICAL log like DebugCtx.
+// Arguments are handled in the manner of fmt.Print.
+func CriticalCtx(ctx context.Context, args ...interface{}) {
+	if lg := ctxLogger(ctx, CriticalLog); lg != nil {
+		lg.print(CriticalLog, sprint(args))
+	}
+}
+
+// CriticalCtxf is CriticalCtx with arguments handled in the manner of
+// fmt.Printf.
+func CriticalCtxf(ctx context.Context, format string, args ...interface{}) {
+	if lg := ctxLogger(ctx, CriticalLog); lg != nil {
+		lg.print(CriticalLog, sprintf(format, args))
+	}
+}
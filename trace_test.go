@@ -0,0 +1,53 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"context"
+	"testing"
+)
+
+// spanKey is the key of a fake span in a context.
+type spanKey struct{}
+
+func TestTraceExtractor(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	ctx := context.WithValue(context.Background(), spanKey{}, "4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7")
+	InfoCtx(ctx, "no extractor")
+	if !contains("] no extractor\n") {
+		t.Errorf("wrong entry without extractor: %q", contents())
+	}
+
+	SetTraceExtractor(func(ctx context.Context) (string, string) {
+		ids, _ := ctx.Value(spanKey{}).(string)
+		if ids == "" {
+			return "", ""
+		}
+		return ids[:32], ids[33:]
+	})
+	defer SetTraceExtractor(nil)
+	logging.newBuffers()
+	ctx = NewContext(ctx, With(F("user", "ann")))
+	WarningCtxf(ctx, "cache %s", "miss")
+	if !contains("trace_test.go:") || !contains("] cache miss user=ann trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7\n") {
+		t.Errorf("trace IDs not added: %q", contents())
+	}
+	logging.newBuffers()
+	ErrorCtx(context.Background(), "no span")
+	if !contains("] no span\n") {
+		t.Errorf("wrong entry without span: %q", contents())
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"time"
+)
+
+// tsCache is the text of the date and time of the header, mmdd hh:mm:ss,
+// for the second starting at sec.
+type tsCache struct {
+	sec  int64
+	loc  *time.Location
+	text [13]byte
+}
+
+// SetTimestampCache turns the caching of the formatted timestamp on or off.
+// When on, the date and time of the header are formatted once per second
+// and reused for the entries of that second, which cuts the cost of the
+// header at very high line rates. The microseconds are still formatted for
+// every entry, and timestamps stay exact since the cache is keyed by the
+// second of each entry, not by a clock read once a second.
+// This function is safe to use concurrently.
+func SetTimestampCache(on bool) {
+	ensureInit()
+	var c *tsCache
+	if on {
+		c = &tsCache{sec: -1}
+	}
+	logging.tsCache.Store(c)
+}
+
+// cachedTimestamp writes the date and time of t, mmdd hh:mm:ss, to
+// buf.tmp[1:14], from the cache if caching is on and t falls in its second.
+// It reports whether caching is on.
+func (l *loggingT) cachedTimestamp(buf *buffer, t time.Time) bool {
+	c, _ := l.tsCache.Load().(*tsCache)
+	if c == nil {
+		return false
+	}
+	if sec := t.Unix(); c.sec != sec || c.loc != t.Location() {
+		formatTimestamp(buf, t)
+		c = &tsCache{sec: sec, loc: t.Location()}
+		copy(c.text[:], buf.tmp[1:14])
+		l.tsCache.Store(c)
+		return true
+	}
+	copy(buf.tmp[1:14], c.text[:])
+	return true
+}
+
+// formatTimestamp writes the date and time of t, mmdd hh:mm:ss, to
+// buf.tmp[1:14].
+func formatTimestamp(buf *buffer, t time.Time) {
+	_, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	buf.twoDigits(1, int(month))
+	buf.twoDigits(3, day)
+	buf.tmp[5] = ' '
+	buf.twoDigits(6, hour)
+	buf.tmp[8] = ':'
+	buf.twoDigits(9, minute)
+	buf.tmp[11] = ':'
+	buf.twoDigits(12, second)
+}
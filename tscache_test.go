@@ -0,0 +1,50 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampCache(t *testing.T) {
+	defer SetTimestampCache(false)
+	start := time.Date(2020, 12, 31, 23, 59, 58, 999999000, time.Local)
+	for _, d := range []time.Duration{0, 1, time.Microsecond, time.Second, 1500 * time.Millisecond, time.Second, 3 * time.Second} {
+		e := newEntry(InfoLog, 0, "tscache_test.go", 1, "")
+		e.Time = start.Add(d)
+		SetTimestampCache(false)
+		want := logging.getBuffer()
+		logging.formatHeader(want, e)
+		SetTimestampCache(true)
+		for i := 0; i < 2; i++ {
+			got := logging.getBuffer()
+			logging.formatHeader(got, e)
+			if got.String() != want.String() {
+				t.Errorf("cached header %q, want %q", got.String(), want.String())
+			}
+		}
+	}
+}
+
+func BenchmarkHeaderCached(b *testing.B) {
+	SetTimestampCache(true)
+	defer SetTimestampCache(false)
+	for i := 0; i < b.N; i++ {
+		buf := logging.getBuffer()
+		logging.formatHeader(buf, newEntry(InfoLog, 0, "flog_test.go", 1, ""))
+		logging.putBuffer(buf)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// vEpoch counts the changes of the settings V, VDepth and Logger.V depend
+// on. It is read and written using sync/atomic.
+var vEpoch uint64
+
+// VEpoch returns a number that changes whenever a setting V, VDepth or
+// Logger.V depend on changes, such as -v, -vmodule or -vlogger. Wrappers
+// may cache the decision of V at a call site along with the epoch, and
+// reuse it as long as VEpoch returns the same number. VCache does that.
+// This function is safe to use concurrently.
+func VEpoch() uint64 {
+	return atomic.LoadUint64(&vEpoch)
+}
+
+// vState is a consistent snapshot of the settings V depends on. A new one
+// is stored in logging.vstate, with the next epoch, whenever they change,
+// so V needs a single atomic load to tell whether it is enabled.
+type vState struct {
+	epoch     uint64
+	verbosity Level
+	// max is the highest level enabled anywhere, by -v or -vmodule. V
+	// calls above it are disabled without finding their call site.
+	max Level
+	// observe is true if V calls are observed, see SetVStats.
+	observe bool
+	sites   siteLevels
+}
+
+// maxLevel is the highest Level.
+const maxLevel = Level(1<<31 - 1)
+
+// initialVState is used until the settings are first set.
+var initialVState vState
+
+// loadV returns the current state of V logging. The first call applies the
+// env vars, see Init.
+func (l *loggingT) loadV() *vState {
+	if s, ok := l.vstate.Load().(*vState); ok {
+		return s
+	}
+	ensureInit()
+	if s, ok := l.vstate.Load().(*vState); ok {
+		return s
+	}
+	return &initialVState
+}
+
+// publishV stores a new state of V logging made of the current settings,
+// which drops the levels cached for the call sites.
+// l.mu is held.
+func (l *loggingT) publishV() {
+	s := &vState{
+		epoch:     atomic.AddUint64(&vEpoch, 1),
+		verbosity: l.verbosity.get(),
+		observe:   atomic.LoadInt64(&vStats.every) > 0,
+		sites:     siteLevels{filter: l.vmodule.filter, hook: l.loadLevelHook()},
+	}
+	s.max = s.verbosity
+	for _, pat := range s.sites.filter {
+		if pat.level > s.max {
+			s.max = pat.level
+		}
+	}
+	if s.sites.hook != nil {
+		s.max = maxLevel
+	}
+	l.vstate.Store(s)
+}
+
+// siteLevels caches the -vmodule level of call sites, by PC. Lookups take no
+// lock; the map is copied when a new call site is added, which only happens
+// a bounded number of times.
+type siteLevels struct {
+	filter []modulePat
+	// hook, if not nil, may raise the levels, see SetLevelHook.
+	hook LevelHook
+	// m holds the map[uintptr]Level of the call sites seen. It is
+	// replaced as a whole under mu.
+	m  atomic.Value
+	mu sync.Mutex
+}
+
+// level returns the level of the call frame identified by skip, which is
+// counted as for runtime.Callers.
+func (c *siteLevels) level(skip int) Level {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return 0
+	}
+	pc := pcs[0]
+	m, _ := c.m.Load().(map[uintptr]Level)
+	if v, ok := m[pc]; ok {
+		return v
+	}
+	v := filterLevel(c.filter, pc)
+	if c.hook != nil {
+		if hv, ok := hookLevel(c.hook, pc); ok && hv > v {
+			v = hv
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, _ = c.m.Load().(map[uintptr]Level)
+	next := make(map[uintptr]Level, len(m)+1)
+	for k, lv := range m {
+		next[k] = lv
+	}
+	next[pc] = v
+	c.m.Store(next)
+	return v
+}
+
+// VCache caches the decision of V for one call site and level, such as in
+// generated logging functions or wrappers that check the verbosity on
+// every call. The zero value is ready for use. A VCache must always be used
+// with the same depth and level. V calls answered from the cache are not
+// observed by SetVStats.
+type VCache struct {
+	// state holds the uint64 returned by c.state, which is one more than
+	// the epoch of the decision, shifted left by one, with the decision in
+	// the low bit, zero when there is none. It is read and written using
+	// sync/atomic. It is made of three words so that it contains an aligned
+	// uint64 even where a VCache embedded in a struct is only 32-bit
+	// aligned, such as on 386 and ARM.
+	state [3]uint32
+}
+
+// statePtr returns the 64-bit aligned uint64 within c.state.
+func (c *VCache) statePtr() *uint64 {
+	if uintptr(unsafe.Pointer(&c.state))%8 == 0 {
+		return (*uint64)(unsafe.Pointer(&c.state[0]))
+	}
+	return (*uint64)(unsafe.Pointer(&c.state[1]))
+}
+
+// VDepth acts as the package's VDepth, reusing the last decision as long as
+// VEpoch is unchanged.
+// This function is safe to use concurrently.
+func (c *VCache) VDepth(depth int, level Level) Verbose {
+	state := c.statePtr()
+	epoch := atomic.LoadUint64(&vEpoch)
+	if s := atomic.LoadUint64(state); s>>1 == epoch+1 {
+		return s&1 != 0
+	}
+	v := VDepth(depth+1, level)
+	s := (epoch + 1) << 1
+	if v {
+		s |= 1
+	}
+	atomic.StoreUint64(state, s)
+	return v
+}
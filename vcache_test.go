@@ -0,0 +1,111 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestVDisabledAllocs(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	if n := testing.AllocsPerRun(100, func() { V(2).Info("off") }); n != 0 {
+		t.Errorf("disabled V allocates %v times", n)
+	}
+	logging.vmodule.Set("vcache_test=1,nomatch=3")
+	defer logging.vmodule.Set("")
+	if n := testing.AllocsPerRun(100, func() { V(2).Info("off") }); n != 0 {
+		t.Errorf("disabled V allocates %v times with vmodule", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { V(4).Info("off") }); n != 0 {
+		t.Errorf("V above vmodule allocates %v times", n)
+	}
+	if !V(1) || V(2) {
+		t.Error("vmodule not honored")
+	}
+}
+
+func TestVEpoch(t *testing.T) {
+	epoch := VEpoch()
+	logging.verbosity.Set("1")
+	defer logging.verbosity.Set("0")
+	if VEpoch() == epoch {
+		t.Error("epoch unchanged by -v")
+	}
+	epoch = VEpoch()
+	logging.vlogger.Set("db=2")
+	defer logging.vlogger.Set("")
+	if VEpoch() == epoch {
+		t.Error("epoch unchanged by -vlogger")
+	}
+	epoch = VEpoch()
+	if V(1); VEpoch() != epoch {
+		t.Error("epoch changed by V")
+	}
+}
+
+func TestVCache(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	var c VCache
+	log := func() { c.VDepth(0, 2).Info("cached") }
+	log()
+	if contains("cached") {
+		t.Error("disabled level logged")
+	}
+	logging.verbosity.Set("2")
+	defer logging.verbosity.Set("0")
+	log()
+	if !contains("cached") {
+		t.Error("cache not invalidated by -v")
+	}
+	if n := testing.AllocsPerRun(100, func() { c.VDepth(0, 2) }); n != 0 {
+		t.Errorf("cached V allocates %v times", n)
+	}
+}
+
+func TestVCacheEmbedded(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	// On 32-bit platforms, the cache is only 32-bit aligned after a bool.
+	var s struct {
+		b bool
+		c VCache
+	}
+	if s.c.VDepth(0, 2) || s.c.VDepth(0, 2) {
+		t.Error("disabled level enabled")
+	}
+	logging.verbosity.Set("2")
+	defer logging.verbosity.Set("0")
+	if !s.c.VDepth(0, 2) {
+		t.Error("cache not invalidated by -v")
+	}
+}
+
+func BenchmarkVDisabled(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		V(2).Info("off")
+	}
+}
+
+func BenchmarkVDisabledVmodule(b *testing.B) {
+	logging.vmodule.Set("vcache_test=1")
+	defer logging.vmodule.Set("")
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			V(1)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// VSite holds the V calls observed at a call site, see SetVStats.
+type VSite struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Checks int64  `json:"checks"` // sampled V calls
+	Passes int64  `json:"passes"` // sampled V calls that were enabled
+}
+
+// vStats holds the V calls observed by SetVStats.
+var vStats struct {
+	// every is the sampling interval, zero when off. It and n are read and
+	// written using sync/atomic.
+	every int64
+	n     int64
+
+	mu    sync.Mutex
+	sites map[vSiteKey]*VSite
+}
+
+// vSiteKey identifies a call site.
+type vSiteKey struct {
+	file string
+	line int
+}
+
+// SetVStats observes one in every calls to V, VDepth and Logger.V, or none
+// if every is zero or less, the default. For each call site, the number of
+// observed calls and of those that were enabled are kept, as returned by
+// VStats, and added to Stats.VChecks and Stats.VPasses. This finds the
+// verbose call sites that never log and shows what vmodule patterns
+// enable, on real traffic. Observing a call costs a stack walk, hence the
+// sampling. Turning it on again starts over.
+// This function is safe to use concurrently.
+func SetVStats(every int) {
+	ensureInit()
+	vStats.mu.Lock()
+	vStats.sites = nil
+	if every < 0 {
+		every = 0
+	}
+	atomic.StoreInt64(&vStats.every, int64(every))
+	vStats.mu.Unlock()
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.publishV()
+}
+
+// VStats returns the call sites observed since SetVStats was called, the
+// most checked first.
+func VStats() []VSite {
+	vStats.mu.Lock()
+	sites := make([]VSite, 0, len(vStats.sites))
+	for _, s := range vStats.sites {
+		sites = append(sites, *s)
+	}
+	vStats.mu.Unlock()
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Checks != sites[j].Checks {
+			return sites[i].Checks > sites[j].Checks
+		}
+		if sites[i].File != sites[j].File {
+			return sites[i].File < sites[j].File
+		}
+		return sites[i].Line < sites[j].Line
+	})
+	return sites
+}
+
+// observeV returns v, recording it for the call site depth frames above the
+// caller of observeV if it is sampled.
+func observeV(depth int, v bool) bool {
+	if atomic.LoadInt64(&vStats.every) > 0 {
+		recordV(depth, v)
+	}
+	return v
+}
+
+// recordV records v for the call site, as described for observeV, if it is
+// sampled.
+func recordV(depth int, v bool) {
+	every := atomic.LoadInt64(&vStats.every)
+	if every <= 0 || atomic.AddInt64(&vStats.n, 1)%every != 0 {
+		return
+	}
+	_, file, line, ok := runtime.Caller(3 + depth)
+	if !ok {
+		return
+	}
+	if slash := strings.LastIndex(file, "/"); slash >= 0 {
+		file = file[slash+1:]
+	}
+	atomic.AddInt64(&Stats.VChecks, 1)
+	if v {
+		atomic.AddInt64(&Stats.VPasses, 1)
+	}
+	vStats.mu.Lock()
+	defer vStats.mu.Unlock()
+	if vStats.sites == nil {
+		vStats.sites = make(map[vSiteKey]*VSite)
+	}
+	key := vSiteKey{file, line}
+	s := vStats.sites[key]
+	if s == nil {
+		s = &VSite{File: file, Line: line}
+		vStats.sites[key] = s
+	}
+	s.Checks++
+	if v {
+		s.Passes++
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019-present Facebook Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flog
+
+import (
+	"testing"
+)
+
+func TestVStats(t *testing.T) {
+	logging.newBuffers()
+	defer logging.revertBuffer()
+	SetVStats(1)
+	defer SetVStats(0)
+	logging.vmodule.Set("vstats_test=2")
+	defer logging.vmodule.Set("")
+	for i := 0; i < 3; i++ {
+		V(2).Info("on")
+		V(3).Info("off")
+	}
+	New().V(3).Info("logger")
+
+	sites := VStats()
+	if len(sites) != 3 {
+		t.Fatalf("got %d sites, want 3: %+v", len(sites), sites)
+	}
+	on, off, logger := sites[0], sites[1], sites[2]
+	if on.File != "vstats_test.go" || on.Checks != 3 || on.Passes != 3 {
+		t.Errorf("enabled site %+v", on)
+	}
+	if off.Line != on.Line+1 || off.Checks != 3 || off.Passes != 0 {
+		t.Errorf("disabled site %+v", off)
+	}
+	if logger.Line != on.Line+3 || logger.Checks != 1 || logger.Passes != 0 {
+		t.Errorf("logger site %+v", logger)
+	}
+	if s := GetStats(); len(s.VSites) != 3 {
+		t.Errorf("GetStats has %d sites, want 3", len(s.VSites))
+	}
+}
+
+func TestVStatsSampling(t *testing.T) {
+	SetVStats(4)
+	defer SetVStats(0)
+	for i := 0; i < 8; i++ {
+		V(1)
+	}
+	if sites := VStats(); len(sites) != 1 || sites[0].Checks != 2 {
+		t.Errorf("sampled sites %+v, want 2 checks", sites)
+	}
+}